@@ -0,0 +1,302 @@
+// Package config holds the settings of the API. They are read once at
+// startup from, in increasing order of precedence, built in defaults, a
+// config file, the environment and command line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/exchange"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/mailer"
+	"github.com/PiehTVH/go-ecommerce/payment"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	Port       int
+	APIVersion string
+	// FrontendURL is where links in emails and product links point to.
+	FrontendURL string
+	// JWTSecret signs access and challenge tokens.
+	JWTSecret string
+	// Currency is the ISO 4217 code the store prices and sells in. Stored
+	// amounts are in it, changing it needs a migration of the data.
+	Currency string
+	// PricesIncludeTax is set when catalog prices contain the tax, it is
+	// then taken out of them instead of added at checkout.
+	PricesIncludeTax bool
+
+	Server   Server
+	Database Database
+	Mail     mailer.Config
+	Hashing  helper.PasswordHashing
+	Payment  payment.Config
+	Exchange exchange.Config
+}
+
+// Server holds the timeouts of the HTTP server.
+type Server struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is how long in-flight requests get to finish once a
+	// stop signal arrives.
+	ShutdownTimeout time.Duration
+}
+
+type Database struct {
+	// Driver is "mongo" or "memory". The in-memory store loses everything
+	// on restart and is meant for local runs.
+	Driver string
+	URI    string
+	Name   string
+}
+
+// defaultFile is read when present, a file named with -config or
+// CONFIG_FILE has to exist.
+const defaultFile = ".env"
+
+// flags overriding a setting, by the key they override
+var flags = map[string]struct{ key, usage string }{
+	"port":        {"PORT", "port to listen on"},
+	"api-version": {"API_VERSION", "path prefix of the API"},
+	"db-driver":   {"DB_DRIVER", "database driver, mongo or memory"},
+	"db-host":     {"DB_HOST", "mongo connection string"},
+	"db-name":     {"DB_NAME", "mongo database name"},
+	"mailer":      {"MAILER", "mail driver, smtp, file or log"},
+	"payments":    {"PAYMENT_PROVIDERS", "comma separated payment providers, fake or cod, the first is the default"},
+}
+
+// Load reads the configuration and validates it. args are the command
+// line arguments without the program name.
+func Load(args []string) (Config, error) {
+	set := flag.NewFlagSet("ecommerce", flag.ContinueOnError)
+	file := set.String("config", os.Getenv("CONFIG_FILE"), "file with KEY=value settings, .env by default")
+	values := map[string]*string{}
+	for name, f := range flags {
+		values[name] = set.String(name, "", f.usage)
+	}
+	if err := set.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	src, err := readFile(*file)
+	if err != nil {
+		return Config{}, err
+	}
+	for _, key := range keys {
+		if v, ok := os.LookupEnv(key); ok {
+			src[key] = v
+		}
+	}
+	set.Visit(func(f *flag.Flag) {
+		if override, ok := flags[f.Name]; ok {
+			src[override.key] = *values[f.Name]
+		}
+	})
+
+	cfg, err := src.config()
+	if err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func readFile(path string) (source, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultFile
+	}
+
+	values, err := godotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return source{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: reading %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Validate reports every invalid setting at once.
+func (c Config) Validate() error {
+	var errs []error
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("secretKey, the JWT signing secret, must be set"))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT %d is not a valid port", c.Port))
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 || c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("READ_TIMEOUT, WRITE_TIMEOUT, IDLE_TIMEOUT and SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.APIVersion == "" {
+		errs = append(errs, errors.New("API_VERSION must not be empty"))
+	}
+	if u, err := url.Parse(c.FrontendURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("frontEndUrl %q must be an absolute URL", c.FrontendURL))
+	}
+	if !types.ValidCurrency(c.Currency) {
+		errs = append(errs, fmt.Errorf("STORE_CURRENCY %q is not an ISO 4217 code such as INR", c.Currency))
+	}
+
+	switch c.Database.Driver {
+	case "mongo":
+		if c.Database.URI == "" {
+			errs = append(errs, errors.New("DB_HOST must be set for the mongo driver"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("DB_NAME must not be empty"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER %q is not mongo or memory", c.Database.Driver))
+	}
+
+	if err := c.Mail.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Hashing.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("password hashing: %w", err))
+	}
+	if err := c.Payment.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Exchange.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// keys are the settings read from the environment
+var keys = []string{
+	"PORT", "API_VERSION", "frontEndUrl", "secretKey", "STORE_CURRENCY", "PRICES_INCLUDE_TAX",
+	"READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT", "SHUTDOWN_TIMEOUT",
+	"DB_DRIVER", "DB_HOST", "DB_NAME",
+	"MAILER", "MAIL_FROM", "SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "MAIL_FILE",
+	"PASSWORD_HASH", "BCRYPT_COST", "ARGON2_TIME", "ARGON2_MEMORY", "ARGON2_THREADS",
+	"PAYMENT_PROVIDERS", "PAYMENT_FAKE_SECRET",
+	"EXCHANGE_RATES_FILE", "EXCHANGE_RATES_REFRESH",
+}
+
+type source map[string]string
+
+func (s source) config() (Config, error) {
+	var errs []error
+	number := func(key string, def uint64, bits int) uint64 {
+		v, ok := s[key]
+		if !ok || v == "" {
+			return def
+		}
+		n, err := strconv.ParseUint(v, 10, bits)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not a valid number", key, v))
+		}
+		return n
+	}
+	duration := func(key string, def time.Duration) time.Duration {
+		v, ok := s[key]
+		if !ok || v == "" {
+			return def
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not a valid duration, such as 15s", key, v))
+		}
+		return d
+	}
+	boolean := func(key string, def bool) bool {
+		v, ok := s[key]
+		if !ok || v == "" {
+			return def
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not true or false", key, v))
+		}
+		return b
+	}
+	text := func(key string, def string) string {
+		if v, ok := s[key]; ok && v != "" {
+			return v
+		}
+		return def
+	}
+	list := func(key string, def string) []string {
+		var out []string
+		for _, item := range strings.Split(text(key, def), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+		return out
+	}
+
+	hashing := helper.DefaultPasswordHashing()
+	cfg := Config{
+		Port:             int(number("PORT", 8080, 16)),
+		APIVersion:       text("API_VERSION", constant.APIVersion),
+		FrontendURL:      text("frontEndUrl", "http://localhost:3000"),
+		JWTSecret:        s["secretKey"],
+		Currency:         text("STORE_CURRENCY", constant.DefaultCurrency),
+		PricesIncludeTax: boolean("PRICES_INCLUDE_TAX", false),
+		Server: Server{
+			ReadTimeout:     duration("READ_TIMEOUT", 15*time.Second),
+			WriteTimeout:    duration("WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:     duration("IDLE_TIMEOUT", 60*time.Second),
+			ShutdownTimeout: duration("SHUTDOWN_TIMEOUT", 20*time.Second),
+		},
+		Database: Database{
+			Driver: text("DB_DRIVER", "mongo"),
+			URI:    s["DB_HOST"],
+			Name:   text("DB_NAME", constant.DatabaseName),
+		},
+		Mail: mailer.Config{
+			Driver:       s["MAILER"],
+			From:         s["MAIL_FROM"],
+			SMTPHost:     s["SMTP_HOST"],
+			SMTPPort:     int(number("SMTP_PORT", 0, 16)),
+			SMTPUsername: s["SMTP_USERNAME"],
+			SMTPPassword: s["SMTP_PASSWORD"],
+			File:         s["MAIL_FILE"],
+		},
+		Hashing: helper.PasswordHashing{
+			Algorithm:     text("PASSWORD_HASH", hashing.Algorithm),
+			BcryptCost:    int(number("BCRYPT_COST", uint64(hashing.BcryptCost), 8)),
+			Argon2Time:    uint32(number("ARGON2_TIME", uint64(hashing.Argon2Time), 32)),
+			Argon2Memory:  uint32(number("ARGON2_MEMORY", uint64(hashing.Argon2Memory), 32)),
+			Argon2Threads: uint8(number("ARGON2_THREADS", uint64(hashing.Argon2Threads), 8)),
+		},
+		Payment: payment.Config{
+			Providers:  list("PAYMENT_PROVIDERS", payment.CashOnDelivery),
+			FakeSecret: s["PAYMENT_FAKE_SECRET"],
+		},
+		Exchange: exchange.Config{
+			File:    s["EXCHANGE_RATES_FILE"],
+			Refresh: duration("EXCHANGE_RATES_REFRESH", time.Hour),
+		},
+	}
+
+	if len(errs) > 0 {
+		return Config{}, fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
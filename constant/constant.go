@@ -2,12 +2,18 @@ package constant
 
 const (
 	APIVersion = "v1"
+	// DefaultCurrency is the store currency unless configured otherwise
+	DefaultCurrency = "INR"
 
 	BadRequestMessage = "request not fulfilled"
 
 	//schedular constants
 	HealthCheckRoute = "/health"
+	SwaggerRoute     = "/swagger/*any"
 	Database         = "EthnicElegance"
+	// DatabaseName is the mongo database unless configured otherwise, the
+	// store has always kept its data there
+	DatabaseName = "Elegance"
 
 	// email verification routes
 	VerifyEmailRoute = "/verify-email"
@@ -18,72 +24,249 @@ const (
 	UserRegisterRoute = "/user-register"
 	UserLoginRoute    = "/login"
 	UserLogoutRoute   = "/logout"
+	RefreshTokenRoute = "/refresh-token"
+
+	// two factor authentication routes
+	TwoFactorLoginRoute   = "/login-2fa"
+	TwoFactorSetupRoute   = "/2fa/setup"
+	TwoFactorEnableRoute  = "/2fa/enable"
+	TwoFactorDisableRoute = "/2fa/disable"
+	RecoveryCodesRoute    = "/2fa/recovery-codes"
+
+	// password reset routes
+	ForgotPasswordRoute = "/forgot-password"
+	ResetPasswordRoute  = "/reset-password"
 
 	// product and adminroutes
-	RegisterProductRoute    = "/product-register"
-	ListProductRoute        = "/products"
-	ListProductRouteAdmin   = "/list-products-admin"
-	ListOrders              = "/list-orders"
-	UpdateOrder             = "/update-order"
-	ListCategoryRoute       = "/list-category"
-	ListSingleProductRoute  = "/product/:id"
-	SearchProductRoute      = "/search"
-	UpdateProductRoute      = "/update-product/:id"
-	UpdateStockRoute        = "/update-stock/:id"
-	DeleteProductRoute      = "/delete-product/:id"
-	AddToCartRoute          = "/cart"
-	AddAddressRoute         = "/address"
-	EditAddressRoute        = "/address"
-	EditNameRoute           = "/name"
-	GetSingleUserRoute      = "/user/:id"
-	UpdateUser              = "/update-user"
-	CheckoutRoute           = "/checkout"
-	AddToFavoriteRoute      = "/favorite"
-	RemoveFromFavoriteRoute = "/remove-favorite"
-	ListFavoriteRoute       = "/favorite"
-	GetAllUserRoute         = "/users"
-	BlockUserRoute          = "/block-user"
-	UnblockUserRoute        = "/unblock-user"
-	AddCategoryRoute        = "/category"
-	UpdateCategoryRoute     = "/category/:id"
-	DeleteCategoryRoute     = "/category/:id"
-	AddCouponRoute          = "/coupon"
-	DeleteCouponRoute       = "/coupon/:id"
-	ListCouponRoute         = "/coupon"
-	RemoveFromCartRoute     = "/cart/remove"
-	UpdateCart              = "/cart/update"
-	ListCartRoute           = "/cart"
-	EmptyCartRoute          = "/cart/all"
-	ApplyCouponRoute        = "/cart/coupon"
-	GetProductLinkRoute     = "/product-link/:id"
-	AddOfferRoute           = "/offer"
-	UpdateOfferRoute        = "/offer/:id"
-	GiveRatingRoute         = "/rating/:id"
-	CommentOnProductRoute   = "/comment/:id"
+	RegisterProductRoute      = "/product-register"
+	ListProductRoute          = "/products"
+	ListProductRouteAdmin     = "/list-products-admin"
+	ListOrders                = "/list-orders"
+	UpdateOrder               = "/update-order/:id"
+	MyOrdersRoute             = "/orders"
+	MyOrderRoute              = "/orders/:id"
+	CancelOrderRoute          = "/orders/:id/cancel"
+	ListCategoryRoute         = "/list-category"
+	ListSingleProductRoute    = "/product/:id"
+	SearchProductRoute        = "/search"
+	UpdateProductRoute        = "/update-product/:id"
+	UpdateStockRoute          = "/update-stock/:id"
+	DeleteProductRoute        = "/delete-product/:id"
+	AddToCartRoute            = "/cart"
+	AddAddressRoute           = "/address"
+	UpdateAddressRoute        = "/address/:id"
+	DeleteAddressRoute        = "/address/:id"
+	ListAddressesRoute        = "/address"
+	EditNameRoute             = "/name"
+	GetSingleUserRoute        = "/user/:id"
+	UpdateUser                = "/update-user"
+	CheckoutRoute             = "/checkout"
+	PaymentWebhookRoute       = "/payment/webhook/:provider"
+	ListCurrenciesRoute       = "/currencies"
+	AddToFavoriteRoute        = "/favorite"
+	RemoveFromFavoriteRoute   = "/remove-favorite"
+	ListFavoriteRoute         = "/favorite"
+	GetAllUserRoute           = "/users"
+	BlockUserRoute            = "/block-user"
+	UnblockUserRoute          = "/unblock-user"
+	UpdateUserRoleRoute       = "/user-role"
+	UnlockUserRoute           = "/unlock-user"
+	LoginAttemptsRoute        = "/login-attempts"
+	ListRoutesRoute           = "/routes"
+	AddCategoryRoute          = "/category"
+	UpdateCategoryRoute       = "/category/:id"
+	DeleteCategoryRoute       = "/category/:id"
+	CategoryProductsRoute     = "/category/:id/products"
+	AddCouponRoute            = "/coupon"
+	DeleteCouponRoute         = "/coupon/:id"
+	ListCouponRoute           = "/coupon"
+	RemoveFromCartRoute       = "/cart/remove"
+	UpdateCart                = "/cart/update"
+	ListCartRoute             = "/cart"
+	EmptyCartRoute            = "/cart/all"
+	ApplyCouponRoute          = "/cart/coupon"
+	RemoveCouponRoute         = "/cart/coupon"
+	GetProductLinkRoute       = "/product-link/:id"
+	AddOfferRoute             = "/offer"
+	UpdateOfferRoute          = "/offer/:id"
+	ListOffersRoute           = "/offer"
+	AddTaxRuleRoute           = "/tax-rule"
+	UpdateTaxRuleRoute        = "/tax-rule/:id"
+	DeleteTaxRuleRoute        = "/tax-rule/:id"
+	ListTaxRulesRoute         = "/tax-rule"
+	AddShippingZoneRoute      = "/shipping-zone"
+	UpdateShippingZoneRoute   = "/shipping-zone/:id"
+	DeleteShippingZoneRoute   = "/shipping-zone/:id"
+	ListShippingZonesRoute    = "/shipping-zone"
+	AddShippingMethodRoute    = "/shipping-method"
+	UpdateShippingMethodRoute = "/shipping-method/:id"
+	DeleteShippingMethodRoute = "/shipping-method/:id"
+	ListShippingMethodsRoute  = "/shipping-method"
+	GiveRatingRoute           = "/rating/:id"
+	CommentOnProductRoute     = "/comment/:id"
+)
+
+// roles, NormalUser is the customer role every signup gets
+const (
+	NormalUser         = "user"
+	AdminUser          = "admin"
+	CatalogManagerUser = "catalog-manager"
+	SupportUser        = "support"
+)
+
+// permissions a route can require
+const (
+	PermissionPublic         = ""
+	PermissionAuthenticated  = "authenticated"
+	PermissionManageCatalog  = "catalog:manage"
+	PermissionManageCoupons  = "coupons:manage"
+	PermissionViewOrders     = "orders:view"
+	PermissionManageOrders   = "orders:manage"
+	PermissionViewUsers      = "users:view"
+	PermissionManageUsers    = "users:manage"
+	PermissionUnlockUsers    = "users:unlock"
+	PermissionManageRoles    = "roles:manage"
+	PermissionViewRoutes     = "routes:view"
+	PermissionManageTaxes    = "taxes:manage"
+	PermissionManageShipping = "shipping:manage"
 )
 
+// order states
 const (
-	NormalUser = "user"
-	AdminUser  = "admin"
+	OrderPendingPayment = "pending_payment"
+	OrderPaid           = "paid"
+	OrderPacked         = "packed"
+	OrderShipped        = "shipped"
+	OrderDelivered      = "delivered"
+	OrderCancelled      = "cancelled"
+	OrderReturned       = "returned"
 )
 
+// coupon types
 const (
-	// time slot for otp validation
+	CouponPercentage = "percentage"
+	CouponFixed      = "fixed"
+)
+
+// shipping rate rules
+const (
+	ShippingFlat     = "flat"
+	ShippingWeight   = "weight"
+	ShippingFreeOver = "free_over"
+)
+
+// request and response headers
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// AcceptCurrencyHeader lists the currencies the client wants prices in,
+	// most wanted first, the currency query parameter overrides it
+	AcceptCurrencyHeader = "Accept-Currency"
+)
+
+// gin context keys
+const (
+	PrincipalKey = "principal"
+)
+
+const (
+	// time slot for otp validation, in seconds. A new otp can not be
+	// requested before it is over.
 	OtpValidation = 60
+	// lifetime of an otp in minutes
+	OtpExpiry = 10
+	// guesses and otps sent allowed per window, in minutes
+	OtpWindow      = 60
+	OtpMaxAttempts = 5
+	OtpMaxSends    = 5
+
+	// lifetime of an access token in minutes
+	AccessTokenValidity = 15
+	// lifetime of a session and its refresh token in days
+	RefreshTokenValidity = 30
+	// rotated refresh token hashes a session remembers to spot replays
+	RefreshHashHistory = 2
+	// lifetime of a password reset link in minutes
+	PasswordResetValidity = 30
+
+	ProductNameMaxLength = 200
+
+	// password policy, bcrypt only reads the first 72 bytes
+	PasswordMinLength = 8
+	PasswordMaxLength = 72
+
+	// failed sign ins allowed per account and per client IP before they
+	// get locked out
+	LoginMaxAccountFailures = 5
+	LoginMaxIPFailures      = 20
+	// first lockout in seconds, doubled with every further failure
+	LoginLockoutBase = 30
+	// longest lockout in seconds
+	LoginLockoutMax = 3600
+	// hours after which failures are forgotten
+	LoginFailureWindow = 24
+
+	// authenticator app codes, RFC 6238
+	TotpIssuer = "EthnicElegance"
+	TotpDigits = 6
+	// seconds per code
+	TotpPeriod = 30
+	// codes of neighbouring periods still accepted, for clock drift
+	TotpSkew = 1
+	// lifetime of a sign in challenge in minutes
+	ChallengeTokenValidity = 5
+	// wrong codes allowed per account before the second step is locked out
+	TwoFactorMaxFailures = 5
+	RecoveryCodeCount    = 10
+
+	// longest coupon code
+	CouponNameMaxLength = 32
+	// longest Idempotency-Key header accepted at checkout
+	IdempotencyKeyMaxLength = 255
+	// tries at clearing the ordered items from a cart that keeps changing
+	CartClearAttempts = 3
+	// highest tax rate in hundredths of a percent, 100%
+	TaxRateMax = 10000
+	// range of the ratings customers give products
+	RatingMin = 1
+	RatingMax = 5
+	// most addresses in the address book of a customer, and street lines
+	// in one address
+	AddressBookMax  = 20
+	AddressLinesMax = 3
+)
+
+// reasons recorded when a session is revoked
+const (
+	RevokedLogout         = "logout"
+	RevokedPasswordChange = "password changed"
+	RevokedBlocked        = "user blocked"
+	RevokedTokenReuse     = "refresh token reuse"
+	RevokedRoleChange     = "role changed"
+	RevokedPasswordReset  = "password reset"
 )
 
 // collections
 const (
-	VerificationsCollection = "verifications"
-	UsersCollection         = "users"
-	ProductCollection       = "products"
-	AddressCollection       = "user_addresses"
-	CartCollection          = "user_cart"
-	CategoryCollection      = "categories"
-	CouponCollection        = "coupons"
-	OfferCollection         = "offers"
-	CartItemCollection      = "cart_items"
-	OrderCollection         = "orders"
+	VerificationsCollection  = "verifications"
+	UsersCollection          = "users"
+	ProductCollection        = "products"
+	AddressCollection        = "user_addresses"
+	CartCollection           = "user_cart"
+	CategoryCollection       = "categories"
+	CouponCollection         = "coupons"
+	CouponUsageCollection    = "coupon_usages"
+	OfferCollection          = "offers"
+	TaxRuleCollection        = "tax_rules"
+	ShippingZoneCollection   = "shipping_zones"
+	ShippingMethodCollection = "shipping_methods"
+	CartItemCollection       = "cart_items"
+	OrderCollection          = "orders"
+	SessionCollection        = "sessions"
+	PasswordResetCollection  = "password_resets"
+	LoginAttemptCollection   = "login_attempts"
+	LoginThrottleCollection  = "login_throttles"
+	MigrationCollection      = "migrations"
 )
 
 // messages
@@ -97,9 +280,69 @@ const (
 	AlreadyVerifiedError         = "already verified"
 	OptAlreadySentError          = "otp already sent to email"
 	NotRegisteredUser            = "you are not a register user"
+	NoPendingVerificationError   = "no verification pending for this email"
 	PasswordNotMatchedError      = "email and password do not match"
 	NotAuthorizedUserError       = "you are not authorized"
 	NoProductAvaliable           = "no product avaliable"
 	UserDoesNotExists            = "user not exists"
 	AddressNotExists             = "address not exists. please add one address"
+	AddressNotFoundError         = "address not found"
+	AddressBookFullError         = "address book is full, delete an address first"
+	TokenRequiredError           = "token is required"
+	InvalidTokenError            = "invalid or expired token"
+	PermissionDeniedError        = "you do not have permission to perform this action"
+	InvalidRoleError             = "invalid role"
+	UserBlockedError             = "your account has been blocked"
+	InvalidRefreshTokenError     = "invalid or expired refresh token"
+	OtpAttemptsExceededError     = "too many wrong attempts, please try again later"
+	OtpSendsExceededError        = "too many otps sent, please try again later"
+	OtpSentMessage               = "otp sent to your email"
+	PasswordResetSentMessage     = "if the email is registered, a password reset link has been sent to it"
+	InvalidResetTokenError       = "invalid or expired password reset link"
+	ProductNotFoundError         = "product not found"
+	CategoryNotFoundError        = "category not found"
+	InsufficientStockError       = "not enough stock"
+	RatingRangeError             = "rating must be between 1 and 5"
+	CategorySlugExistsError      = "a category with this slug already exists"
+	CategoryHasChildrenError     = "category still has subcategories, move or delete them first"
+	CategoryHasProductsError     = "category still has products, reassign them first"
+	InvalidCategoryParentError   = "a category can not be placed under itself or its subcategories"
+	TooManyLoginAttemptsError    = "too many failed sign in attempts, please try again later"
+	TwoFactorRequiredMessage     = "enter the code from your authenticator app"
+	TwoFactorEnrollMessage       = "two factor authentication is required for your account, add it to your authenticator app and enter the code"
+	InvalidChallengeTokenError   = "invalid or expired sign in challenge"
+	InvalidTwoFactorCodeError    = "invalid authentication code"
+	TwoFactorAlreadyEnabledError = "two factor authentication is already enabled"
+	TwoFactorNotEnabledError     = "two factor authentication is not enabled"
+	TwoFactorSetupRequiredError  = "start the two factor setup first"
+	TwoFactorMandatoryError      = "two factor authentication can not be turned off for this account"
+	CouponNotFoundError          = "coupon not found"
+	CouponExistsError            = "a coupon with this code already exists"
+	CouponNotStartedError        = "coupon is not active yet"
+	CouponExpiredError           = "coupon has expired"
+	CouponUsedUpError            = "coupon has reached its usage limit"
+	CouponUserLimitError         = "you have already used this coupon"
+	CouponMinCartValueError      = "cart total is below the minimum for this coupon"
+	CouponNotApplicableError     = "coupon does not apply to any product in your cart"
+	CartEmptyError               = "your cart is empty"
+	OfferNotFoundError           = "offer not found"
+	CartItemUnavailableError     = "some products in your cart are no longer available, please review your cart"
+	IdempotencyKeyError          = "the idempotency key is too long"
+	OrderNotFoundError           = "order not found"
+	InvalidOrderStatusError      = "invalid order status"
+	OrderTransitionError         = "the order can not move to this status"
+	OrderPaidByPaymentError      = "only the payment can mark an order paid"
+	OrderChangedError            = "the order was changed meanwhile, please reload it"
+	PaymentMethodError           = "unknown payment method"
+	PaymentDeclinedError         = "the payment was declined"
+	PaymentUnavailableError      = "the payment could not be started, please try again"
+	UnsupportedCurrencyError     = "prices can not be shown in this currency"
+	TaxRuleNotFoundError         = "tax rule not found"
+	ShippingZoneNotFoundError    = "shipping zone not found"
+	ShippingZoneOverlapError     = "another shipping zone already serves these countries"
+	ShippingZoneHasMethodsError  = "shipping zone still has shipping methods, delete them first"
+	ShippingMethodNotFoundError  = "shipping method not found"
+	ShippingMethodRequiredError  = "choose a shipping method"
+	ShippingMethodError          = "this shipping method can not deliver your order"
+	NoShippingMethodError        = "no shipping method delivers your order to your address"
 )
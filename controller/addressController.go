@@ -0,0 +1,272 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultAddress returns the default shipping or billing address of book,
+// its first address should none hold the flag.
+func defaultAddress(book []types.Address, billing bool) (types.Address, bool) {
+	for _, address := range book {
+		if (billing && address.DefaultBilling) || (!billing && address.DefaultShipping) {
+			return address, true
+		}
+	}
+	if len(book) == 0 {
+		return types.Address{}, false
+	}
+	return book[0], true
+}
+
+// ownAddress loads the address id of the address book of email. It writes
+// the error response itself, addresses of other customers are not found.
+func (h *Handler) ownAddress(c *gin.Context, email string, id string) (types.Address, bool) {
+	addressId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.AddressNotFoundError})
+		return types.Address{}, false
+	}
+	address, err := h.db.Addresses().FindByID(c, addressId)
+	if errors.Is(err, database.ErrNotFound) || (err == nil && address.Email != email) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.AddressNotFoundError})
+		return types.Address{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return types.Address{}, false
+	}
+	return address, true
+}
+
+// checkoutAddresses picks the addresses an order ships and bills to, the
+// defaults of the address book unless the checkout names them. It writes
+// the error response itself.
+func (h *Handler) checkoutAddresses(c *gin.Context, email string, req types.Checkout) (shipTo, billTo types.Address, ok bool) {
+	book, err := h.db.Addresses().ListByEmail(c, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return shipTo, billTo, false
+	}
+	if len(book) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.AddressNotExists})
+		return shipTo, billTo, false
+	}
+
+	pick := func(id string, billing bool) (types.Address, bool) {
+		if id == "" {
+			return defaultAddress(book, billing)
+		}
+		for _, address := range book {
+			if address.ID.Hex() == id {
+				return address, true
+			}
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.AddressNotFoundError})
+		return types.Address{}, false
+	}
+	if shipTo, ok = pick(req.AddressId, false); !ok {
+		return shipTo, billTo, false
+	}
+	billTo, ok = pick(req.BillingAddressId, true)
+	return shipTo, billTo, ok
+}
+
+// @Summary Add Address
+// @Description Add an address to the address book of the user. The first address becomes the default shipping and billing address, setting a default flag moves it from the address holding it.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param address body types.AddressData true "Address"
+// @Success 200 {object}  types.Address
+// @Router /v1/ecommerce/address [post]
+func (h *Handler) AddAddress(c *gin.Context) {
+	var data types.AddressData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	postal, err := helper.NormalizeAddress(data.PostalAddress)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	book, err := h.db.Addresses().ListByEmail(c, principal.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if len(book) >= constant.AddressBookMax {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.AddressBookFullError})
+		return
+	}
+
+	now := time.Now().Unix()
+	address := types.Address{
+		// the id is known before the insert so the flags of the other
+		// addresses can be cleared first
+		ID:              primitive.NewObjectID(),
+		Email:           principal.Email,
+		PostalAddress:   postal,
+		DefaultShipping: data.DefaultShipping || len(book) == 0,
+		DefaultBilling:  data.DefaultBilling || len(book) == 0,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	// should the insert fail after the flags were cleared, the book falls
+	// back to its first address until a default is set again
+	if err := h.db.Addresses().ClearDefaults(c, address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if err := h.db.Addresses().Create(c, &address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": address})
+}
+
+// @Summary Update Address
+// @Description Change an address of the address book of the user. A default flag can't be cleared, it moves when another address is made the default.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Address ID"
+// @Param address body types.AddressData true "Address"
+// @Success 200 {object}  types.Address
+// @Router /v1/ecommerce/address/{id} [put]
+func (h *Handler) UpdateAddress(c *gin.Context) {
+	var data types.AddressData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	address, ok := h.ownAddress(c, principal.Email, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	postal, err := helper.NormalizeAddress(data.PostalAddress)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	address.PostalAddress = postal
+	address.DefaultShipping = address.DefaultShipping || data.DefaultShipping
+	address.DefaultBilling = address.DefaultBilling || data.DefaultBilling
+	address.UpdatedAt = time.Now().Unix()
+	if err := h.db.Addresses().ClearDefaults(c, address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if err := h.db.Addresses().Update(c, address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": address})
+}
+
+// @Summary Delete Address
+// @Description Delete an address of the address book of the user. The default flags it held move to the oldest address left. Orders keep the address they were placed with.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Address ID"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/address/{id} [delete]
+func (h *Handler) DeleteAddress(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	address, ok := h.ownAddress(c, principal.Email, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	err := h.db.Addresses().Delete(c, address.ID)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.AddressNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	// the address is gone either way, a book left without a default falls
+	// back to its first address
+	if address.DefaultShipping || address.DefaultBilling {
+		book, err := h.db.Addresses().ListByEmail(c, principal.Email)
+		if err == nil && len(book) > 0 {
+			heir := book[0]
+			heir.DefaultShipping = heir.DefaultShipping || address.DefaultShipping
+			heir.DefaultBilling = heir.DefaultBilling || address.DefaultBilling
+			heir.UpdatedAt = time.Now().Unix()
+			err = h.db.Addresses().Update(c, heir)
+		}
+		if err != nil {
+			log.Printf("passing the defaults of address %s on: %v", address.ID.Hex(), err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary List Addresses
+// @Description List the address book of the user, oldest address first
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  types.Address
+// @Router /v1/ecommerce/address [get]
+func (h *Handler) ListAddresses(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+
+	book, err := h.db.Addresses().ListByEmail(c, principal.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": book})
+}
@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// @Summary List all users
+// @Description List all users from the database by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/users [get]
+func (h *Handler) GetAllUsers(c *gin.Context) {
+	users, err := h.db.Users().List(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.BadRequestMessage})
+		return
+	}
+
+	for i := range users {
+		users[i].Password = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": users})
+}
+
+// @Summary Get single user
+// @Description Get a single user by id
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "User ID"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/user/{id} [get]
+func (h *Handler) GetSingleUser(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.UserDoesNotExists})
+		return
+	}
+
+	dbUser, err := h.db.Users().FindByID(c, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.UserDoesNotExists})
+		return
+	}
+
+	dbUser.Password = ""
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": dbUser})
+}
+
+// @Summary Block user
+// @Description block user by the admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param user_email body string true "User Email"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/block-user [put]
+func (h *Handler) BlockUser(c *gin.Context) {
+	h.setBlocked(c, true)
+}
+
+// @Summary unblock user
+// @Description unblock user by the admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param user_email body string true "User Email"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/unblock-user [put]
+func (h *Handler) UnblockUser(c *gin.Context) {
+	h.setBlocked(c, false)
+}
+
+func (h *Handler) setBlocked(c *gin.Context, blocked bool) {
+	var req struct {
+		Email string `json:"user_email" bson:"user_email"`
+	}
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "Invalid request"})
+		return
+	}
+	req.Email = helper.NormalizeEmail(req.Email)
+
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	if principal.Email == req.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "you can not block yourself"})
+		return
+	}
+
+	dbUser, err := h.db.Users().FindByEmail(c, req.Email)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.UserDoesNotExists})
+		return
+	}
+
+	dbUser.IsBlocked = blocked
+	dbUser.UpdatedAt = time.Now().Unix()
+	if err := h.db.Users().Update(c, dbUser); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if blocked {
+		if err := h.revokeSessions(c, dbUser.Id, constant.RevokedBlocked); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary Update user role
+// @Description Change the role of a user, only admins may do this
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param role body types.UpdateRole true "Role"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/user-role [put]
+func (h *Handler) UpdateUserRole(c *gin.Context) {
+	var req types.UpdateRole
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "Invalid request"})
+		return
+	}
+	req.Email = helper.NormalizeEmail(req.Email)
+
+	if !helper.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.InvalidRoleError})
+		return
+	}
+
+	dbUser, err := h.db.Users().FindByEmail(c, req.Email)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.UserDoesNotExists})
+		return
+	}
+
+	dbUser.UserType = req.Role
+	dbUser.UpdatedAt = time.Now().Unix()
+	if err := h.db.Users().Update(c, dbUser); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	// tokens carry the role, make the user pick up the new one right away
+	if err := h.revokeSessions(c, dbUser.Id, constant.RevokedRoleChange); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
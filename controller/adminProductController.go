@@ -0,0 +1,274 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// validProductData checks the payload and that its category exists. It
+// writes the error response itself and returns false when it is invalid.
+func (h *Handler) validProductData(c *gin.Context, data types.ProductData) bool {
+	if err := helper.CheckProductValidation(data, h.cfg.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+
+	categoryId, _ := primitive.ObjectIDFromHex(data.CategoryId)
+	_, err := h.db.Categories().FindByID(c, categoryId)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CategoryNotFoundError})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+	return true
+}
+
+// adminProductFromParam loads the product addressed by :id, hidden ones
+// included. Deleted products are gone for the back office too.
+func (h *Handler) adminProductFromParam(c *gin.Context) (types.Product, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ProductNotFoundError})
+		return types.Product{}, false
+	}
+
+	product, err := h.db.Products().FindByID(c, id)
+	if errors.Is(err, database.ErrNotFound) || (err == nil && product.DeletedAt > 0) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ProductNotFoundError})
+		return types.Product{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return types.Product{}, false
+	}
+	return product, true
+}
+
+// @Summary Add Product
+// @Description Add product by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param product body types.ProductData true "Product"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/product-register [post]
+func (h *Handler) RegisterProduct(c *gin.Context) {
+	var data types.ProductData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	data.Price = data.Price.DefaultCurrency(h.cfg.Currency)
+	if !h.validProductData(c, data) {
+		return
+	}
+
+	now := time.Now().Unix()
+	product := types.Product{
+		Name:        strings.TrimSpace(data.Name),
+		Price:       data.Price,
+		Description: data.Description,
+		Images:      data.Images,
+		Stock:       data.Stock,
+		Keywords:    data.Keywords,
+		Comments:    []types.Comment{},
+		CategoryId:  data.CategoryId,
+		Weight:      data.Weight,
+		Dimensions:  data.Dimensions,
+		Hidden:      data.Hidden,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := h.db.Products().Create(c, &product); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": product})
+}
+
+// @Summary Update Product
+// @Description Update product by admin. Ratings, comments and stock are left as they are.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Product ID"
+// @Param product body types.ProductData true "Product"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/update-product/{id} [put]
+func (h *Handler) UpdateProduct(c *gin.Context) {
+	var data types.ProductData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	product, ok := h.adminProductFromParam(c)
+	if !ok {
+		return
+	}
+
+	// stock only changes through update-stock and checkouts, it is
+	// checked as it stands
+	data.Stock = product.Stock
+	data.Price = data.Price.DefaultCurrency(h.cfg.Currency)
+	if !h.validProductData(c, data) {
+		return
+	}
+
+	product.Name = strings.TrimSpace(data.Name)
+	product.Price = data.Price
+	product.Description = data.Description
+	product.Images = data.Images
+	product.Keywords = data.Keywords
+	product.CategoryId = data.CategoryId
+	product.Weight = data.Weight
+	product.Dimensions = data.Dimensions
+	product.Hidden = data.Hidden
+	product.UpdatedAt = time.Now().Unix()
+	product, err := h.db.Products().UpdateDetails(c, product)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ProductNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": product})
+}
+
+// @Summary Add Stock
+// @Description Add stock by admin, a negative quantity removes stock
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Product ID"
+// @Param stock body types.UpdateStock true "Stock"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/update-stock/{id} [put]
+func (h *Handler) UpdateStock(c *gin.Context) {
+	var req types.UpdateStock
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quantity == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "quantity must be a non zero number"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ProductNotFoundError})
+		return
+	}
+
+	product, err := h.db.Products().AdjustStock(c, id, req.Quantity)
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ProductNotFoundError})
+		return
+	case errors.Is(err, database.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.InsufficientStockError})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": product})
+}
+
+// @Summary Delete Product
+// @Description Delete product by admin. The product is only marked deleted so existing orders keep it.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Product ID"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/delete-product/{id} [delete]
+func (h *Handler) DeleteProduct(c *gin.Context) {
+	product, ok := h.adminProductFromParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Products().MarkDeleted(c, product.ID, time.Now().Unix())
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ProductNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary List all products
+// @Description List all products from the database by admin, hidden and out of stock ones included
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param search query string false "Search"
+// @Param deleted query bool false "Include deleted products"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/list-products-admin [get]
+func (h *Handler) ListProductsAdmin(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	products, count, err := h.db.Products().Find(c, database.ProductQuery{
+		Search:         c.Query("search"),
+		IncludeHidden:  true,
+		IncludeDeleted: c.Query("deleted") == "true",
+		Skip:           int64((page - 1) * limit),
+		Limit:          int64(limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": products, "total": count})
+}
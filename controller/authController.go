@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// startSession opens a new session for the user and returns its first
+// access and refresh token pair.
+func (h *Handler) startSession(c *gin.Context, user types.User) (types.TokenPair, error) {
+	now := time.Now()
+	session := types.Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     user.Id,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		CreatedAt:  now.Unix(),
+		LastUsedAt: now.Unix(),
+		ExpiresAt:  now.Add(helper.RefreshTokenTTL).Unix(),
+	}
+
+	refreshToken, refreshHash, err := helper.NewRefreshToken(session.ID)
+	if err != nil {
+		return types.TokenPair{}, err
+	}
+	session.RefreshHash = refreshHash
+
+	if err := h.db.Sessions().Create(c, &session); err != nil {
+		return types.TokenPair{}, err
+	}
+
+	return issueTokens(user, session.ID, refreshToken)
+}
+
+func issueTokens(user types.User, sessionId primitive.ObjectID, refreshToken string) (types.TokenPair, error) {
+	accessToken, err := helper.GenerateToken(user.Id.Hex(), user.Email, user.UserType, sessionId.Hex())
+	if err != nil {
+		return types.TokenPair{}, err
+	}
+
+	return types.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(helper.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeSessions signs the user out everywhere.
+func (h *Handler) revokeSessions(c *gin.Context, userId primitive.ObjectID, reason string) error {
+	return h.db.Sessions().RevokeAllForUser(c, userId, reason, time.Now().Unix())
+}
+
+// rejectRefresh revokes the session a refresh was refused for and answers
+// 401, or 500 when the session could not be revoked.
+func (h *Handler) rejectRefresh(c *gin.Context, sessionId primitive.ObjectID, reason string, now int64) {
+	if err := h.db.Sessions().Revoke(c, sessionId, reason, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidRefreshTokenError})
+}
+
+// @Summary Refresh token
+// @Description Exchange a refresh token for a new token pair. Every refresh token works once, presenting a rotated one again revokes the session.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param refresh_token body types.RefreshToken true "Refresh token"
+// @Success 200 {object}  string
+// @Failure 401 {object}  string
+// @Router /v1/ecommerce/refresh-token [post]
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req types.RefreshToken
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	sessionId, err := helper.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidRefreshTokenError})
+		return
+	}
+
+	now := time.Now().Unix()
+	session, err := h.db.Sessions().FindByID(c, sessionId)
+	if err != nil || !session.Active(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidRefreshTokenError})
+		return
+	}
+
+	// a token that was already rotated away is being replayed, so it may
+	// have been stolen: end the session for everybody holding it. Any other
+	// mismatch is just a bad token.
+	presentedHash := helper.HashToken(req.RefreshToken)
+	if presentedHash != session.RefreshHash {
+		if session.Rotated(presentedHash) {
+			h.rejectRefresh(c, session.ID, constant.RevokedTokenReuse, now)
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidRefreshTokenError})
+		return
+	}
+
+	dbUser, err := h.db.Users().FindByID(c, session.UserID)
+	if err != nil || dbUser.IsBlocked {
+		h.rejectRefresh(c, session.ID, constant.RevokedBlocked, now)
+		return
+	}
+
+	refreshToken, refreshHash, err := helper.NewRefreshToken(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": "Failed to generate token"})
+		return
+	}
+
+	// losing this race means a concurrent request rotated the same token
+	if err := h.db.Sessions().Rotate(c, session.ID, presentedHash, refreshHash, now); err != nil {
+		h.rejectRefresh(c, session.ID, constant.RevokedTokenReuse, now)
+		return
+	}
+
+	tokens, err := issueTokens(dbUser, session.ID, refreshToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "token": tokens.AccessToken, "refresh_token": tokens.RefreshToken, "expires_in": tokens.ExpiresIn})
+}
@@ -0,0 +1,227 @@
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+var otpPattern = regexp.MustCompile(`\b\d{6}\b`)
+
+func TestRegisterAndVerify(t *testing.T) {
+	s := newServer(t)
+
+	code, body := s.do(http.MethodPost, constant.UserRegisterRoute, "", map[string]string{"name": "New Customer", "email": "New@Example.com", "phone": "9999999999", "password": "secret-pw1"})
+	if code != http.StatusOK {
+		t.Fatalf("register: %d %v", code, body)
+	}
+	code, body = s.do(http.MethodPost, constant.UserRegisterRoute, "", map[string]string{"name": "New Customer", "email": "new@example.com", "phone": "9999999999", "password": "secret-pw1"})
+	if code != http.StatusBadRequest {
+		t.Errorf("register again: %d %v, want %d", code, body, http.StatusBadRequest)
+	}
+
+	code, body = s.do(http.MethodPost, constant.UserLoginRoute, "", map[string]string{"email": "new@example.com", "password": "secret-pw1"})
+	if code != http.StatusForbidden || body["message"] != constant.EmailIsNotVerified {
+		t.Errorf("login before verifying: %d %v", code, body)
+	}
+
+	if len(s.mail.sent) == 0 {
+		t.Fatal("no verification mail sent")
+	}
+	otp := otpPattern.FindString(s.mail.sent[len(s.mail.sent)-1].Body)
+	code, body = s.do(http.MethodPost, constant.VerifyOtpRoute, "", map[string]string{"email": "new@example.com", "otp": otp})
+	if code != http.StatusOK || body["token"] == nil {
+		t.Fatalf("verify: %d %v", code, body)
+	}
+
+	s.login("new@example.com")
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     func(*types.User)
+		email    string
+		password string
+		wantCode int
+		wantMsg  string
+	}{
+		{name: "signed in", email: "a@example.com", password: "secret-pw1", wantCode: http.StatusOK},
+		{name: "email in another case", email: "A@Example.com", password: "secret-pw1", wantCode: http.StatusOK},
+		{name: "wrong password", email: "a@example.com", password: "wrong-pw", wantCode: http.StatusBadRequest, wantMsg: constant.PasswordNotMatchedError},
+		{name: "unknown email", email: "b@example.com", password: "secret-pw1", wantCode: http.StatusBadRequest, wantMsg: constant.PasswordNotMatchedError},
+		{
+			name:  "blocked",
+			user:  func(u *types.User) { u.IsBlocked = true },
+			email: "a@example.com", password: "secret-pw1",
+			wantCode: http.StatusForbidden, wantMsg: constant.UserBlockedError,
+		},
+		{
+			name:  "not verified",
+			user:  func(u *types.User) { u.Verified = false },
+			email: "a@example.com", password: "secret-pw1",
+			wantCode: http.StatusForbidden, wantMsg: constant.EmailIsNotVerified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			s.user("a@example.com", tt.user)
+
+			code, body := s.do(http.MethodPost, constant.UserLoginRoute, "", map[string]string{"email": tt.email, "password": tt.password})
+			if code != tt.wantCode {
+				t.Fatalf("login: %d %v, want %d", code, body, tt.wantCode)
+			}
+			if tt.wantMsg != "" && body["message"] != tt.wantMsg {
+				t.Errorf("message = %v, want %q", body["message"], tt.wantMsg)
+			}
+			if tt.wantCode == http.StatusOK && (body["token"] == nil || body["refresh_token"] == nil) {
+				t.Errorf("no tokens in %v", body)
+			}
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	s := newServer(t)
+	s.user("a@example.com", nil)
+	_, refresh := s.login("a@example.com")
+
+	code, body := s.do(http.MethodPost, constant.RefreshTokenRoute, "", map[string]string{"refresh_token": refresh})
+	if code != http.StatusOK {
+		t.Fatalf("refresh: %d %v", code, body)
+	}
+	rotated := body["refresh_token"].(string)
+	if rotated == refresh {
+		t.Fatal("refresh token not rotated")
+	}
+
+	// a stolen token played back ends the session for both holders
+	code, body = s.do(http.MethodPost, constant.RefreshTokenRoute, "", map[string]string{"refresh_token": refresh})
+	if code != http.StatusUnauthorized {
+		t.Fatalf("replay: %d %v, want %d", code, body, http.StatusUnauthorized)
+	}
+	code, body = s.do(http.MethodPost, constant.RefreshTokenRoute, "", map[string]string{"refresh_token": rotated})
+	if code != http.StatusUnauthorized {
+		t.Errorf("refresh after replay: %d %v, want %d", code, body, http.StatusUnauthorized)
+	}
+}
+
+func TestSignOut(t *testing.T) {
+	s := newServer(t)
+	s.user("a@example.com", nil)
+	token, refresh := s.login("a@example.com")
+
+	if code, body := s.do(http.MethodGet, constant.ListCartRoute, "", nil); code != http.StatusUnauthorized {
+		t.Errorf("cart without a token: %d %v, want %d", code, body, http.StatusUnauthorized)
+	}
+	if code, body := s.do(http.MethodPost, constant.UserLogoutRoute, token, nil); code != http.StatusOK {
+		t.Fatalf("logout: %d %v", code, body)
+	}
+	if code, body := s.do(http.MethodGet, constant.ListCartRoute, token, nil); code != http.StatusUnauthorized {
+		t.Errorf("cart after logout: %d %v, want %d", code, body, http.StatusUnauthorized)
+	}
+	if code, body := s.do(http.MethodPost, constant.RefreshTokenRoute, "", map[string]string{"refresh_token": refresh}); code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: %d %v, want %d", code, body, http.StatusUnauthorized)
+	}
+}
+
+func TestVerifyOtpBlocked(t *testing.T) {
+	s := newServer(t)
+	s.user("a@example.com", func(u *types.User) { u.Verified, u.IsBlocked = false, true })
+
+	if code, body := s.do(http.MethodPost, constant.VerifyEmailRoute, "", map[string]string{"email": "a@example.com"}); code != http.StatusOK {
+		t.Fatalf("verify email: %d %v", code, body)
+	}
+	otp := otpPattern.FindString(s.mail.sent[len(s.mail.sent)-1].Body)
+	code, body := s.do(http.MethodPost, constant.VerifyOtpRoute, "", map[string]string{"email": "a@example.com", "otp": otp})
+	if code != http.StatusForbidden || body["message"] != constant.UserBlockedError || body["token"] != nil {
+		t.Errorf("verify otp: %d %v, want %d without a token", code, body, http.StatusForbidden)
+	}
+}
+
+func TestVerifyEmailNothingPending(t *testing.T) {
+	// an unknown and a verified address look the same
+	for _, email := range []string{"verified@example.com", "unknown@example.com"} {
+		t.Run(email, func(t *testing.T) {
+			s := newServer(t)
+			s.user("verified@example.com", nil)
+
+			for _, route := range []string{constant.VerifyEmailRoute, constant.ResendEmailRoute} {
+				code, body := s.do(http.MethodPost, route, "", map[string]string{"email": email})
+				if code != http.StatusBadRequest || body["message"] != constant.NoPendingVerificationError {
+					t.Errorf("%s: %d %v", route, code, body)
+				}
+			}
+			code, body := s.do(http.MethodPost, constant.VerifyOtpRoute, "", map[string]string{"email": email, "otp": "123456"})
+			if code != http.StatusBadRequest || body["message"] != constant.NoPendingVerificationError {
+				t.Errorf("verify otp: %d %v", code, body)
+			}
+			if len(s.mail.sent) != 0 {
+				t.Errorf("%d mails sent, want none", len(s.mail.sent))
+			}
+		})
+	}
+}
+
+func TestTwoFactorLoginReplay(t *testing.T) {
+	s := newServer(t)
+	secret, err := helper.NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.user("a@example.com", func(u *types.User) {
+		u.TotpEnabled, u.TotpSecret = true, secret
+		u.RecoveryCodes = []string{helper.HashRecoveryCode("recovery-1")}
+	})
+	challenge := func() string {
+		code, body := s.do(http.MethodPost, constant.UserLoginRoute, "", map[string]string{"email": "a@example.com", "password": "secret-pw1"})
+		if code != http.StatusOK || body["challenge_token"] == nil {
+			t.Fatalf("login: %d %v", code, body)
+		}
+		return body["challenge_token"].(string)
+	}
+	code, err := helper.TOTPCode(secret, time.Now().Unix()/30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every code works once, also from a second challenge
+	for _, second := range []string{code, "recovery-1"} {
+		status, body := s.do(http.MethodPost, constant.TwoFactorLoginRoute, "", map[string]string{"challenge_token": challenge(), "code": second})
+		if status != http.StatusOK || body["token"] == nil {
+			t.Fatalf("first use of %s: %d %v", second, status, body)
+		}
+		status, body = s.do(http.MethodPost, constant.TwoFactorLoginRoute, "", map[string]string{"challenge_token": challenge(), "code": second})
+		if status != http.StatusUnauthorized || body["message"] != constant.InvalidTwoFactorCodeError {
+			t.Errorf("second use of %s: %d %v, want %d", second, status, body, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestLoginRehash(t *testing.T) {
+	s := newServer(t)
+	helper.SetPasswordHashing(helper.PasswordHashing{Algorithm: helper.HashArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1})
+	legacy, err := helper.EncryptPassword("secret-pw1")
+	helper.SetPasswordHashing(helper.PasswordHashing{Algorithm: helper.HashBcrypt, BcryptCost: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := s.user("a@example.com", func(u *types.User) { u.Password = legacy })
+
+	s.login("a@example.com")
+	stored, err := s.db.Users().FindByID(context.Background(), user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password == legacy || helper.NeedsRehash(stored.Password) {
+		t.Errorf("hash not upgraded at login")
+	}
+	s.login("a@example.com")
+}
@@ -0,0 +1,447 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/exchange"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/pricing"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// cartLines prices the cart at the current product prices and offers.
+// Products that are no longer sold are left out.
+func (h *Handler) cartLines(c *gin.Context, cart types.CartItem) ([]pricing.Line, error) {
+	prices, err := h.newPricer(c)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []pricing.Line{}
+	for _, item := range cart.Products {
+		id, err := primitive.ObjectIDFromHex(item.ProductID)
+		if err != nil {
+			continue
+		}
+		product, err := h.db.Products().FindByID(c, id)
+		if errors.Is(err, database.ErrNotFound) || (err == nil && !product.Visible()) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		price, err := prices.price(product)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, pricing.Line{
+			ProductID:   item.ProductID,
+			CategoryIDs: helper.CategoryAncestors(prices.categories, product.CategoryId),
+			Weight:      product.Weight,
+			Volume:      product.Dimensions.Volume(),
+			Name:        product.Name,
+			ListPrice:   price.ListPrice,
+			UnitPrice:   price.Price,
+			Quantity:    item.Quantity,
+			Available:   product.Stock >= item.Quantity,
+		})
+	}
+	return lines, nil
+}
+
+// loadCart returns the open cart of email. It is empty when there is none
+// yet or the last one was checked out.
+func (h *Handler) loadCart(c *gin.Context, email string) (types.CartItem, error) {
+	cart, err := h.db.Carts().FindByEmail(c, email)
+	if errors.Is(err, database.ErrNotFound) || (err == nil && cart.ChekedOut) {
+		return types.CartItem{Email: email, Products: []types.ProductInCart{}}, nil
+	}
+	if err != nil {
+		return types.CartItem{}, err
+	}
+	helper.MergeCartLines(&cart)
+	return cart, nil
+}
+
+// pricedCart works out the totals of the cart from the current prices and
+// offers, with its coupon when that still applies, the tax of region and
+// the shipping methods able to ship it there.
+func (h *Handler) pricedCart(c *gin.Context, email string, cart types.CartItem, region types.TaxRegion) (pricing.Cart, error) {
+	lines, err := h.cartLines(c, cart)
+	if err != nil {
+		return pricing.Cart{}, err
+	}
+	out := pricing.Cart{Lines: lines}
+	for _, line := range lines {
+		out.NumItems += line.Quantity
+	}
+
+	var coupon *types.Coupon
+	used := 0
+	if cart.Coupon != "" {
+		found, err := h.db.Coupons().FindByName(c, cart.Coupon)
+		switch {
+		case errors.Is(err, database.ErrNotFound):
+			out.CouponError = constant.CouponNotFoundError
+		case err != nil:
+			return pricing.Cart{}, err
+		default:
+			coupon = &found
+			if used, err = h.db.Coupons().UsedBy(c, found.ID, email); err != nil {
+				return pricing.Cart{}, err
+			}
+		}
+	}
+
+	// when Quote turns the coupon down the cart is priced without it
+	out.Breakdown, err = pricing.Quote(lines, h.cfg.Currency, coupon, used, time.Now().Unix())
+	switch {
+	case errors.Is(err, types.ErrCurrencyMismatch):
+		return pricing.Cart{}, err
+	case err != nil:
+		out.CouponError = err.Error()
+	}
+	if err := h.applyTax(c, &out, region); err != nil {
+		return pricing.Cart{}, err
+	}
+	if err := h.shippingOptions(c, &out, region.Country); err != nil {
+		return pricing.Cart{}, err
+	}
+	return out, nil
+}
+
+// respondCart answers with the priced cart in the currency of conv, taxed
+// where the caller lives.
+func (h *Handler) respondCart(c *gin.Context, conv exchange.Converter, email string, cart types.CartItem) {
+	region, err := h.taxRegion(c, email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	priced, err := h.pricedCart(c, email, cart, region)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	shown, ok := convertCart(c, conv, priced)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": shown})
+}
+
+// convertCart shows cart in the currency of conv. It writes the error
+// response itself.
+func convertCart(c *gin.Context, conv exchange.Converter, cart pricing.Cart) (pricing.Cart, bool) {
+	shown, err := pricing.Convert(cart, conv.Convert)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return pricing.Cart{}, false
+	}
+	return shown, true
+}
+
+// setCartLine changes the quantity of one product in the cart of the
+// caller, quantity gets the current one and returns the new one. More
+// units than in stock are refused.
+func (h *Handler) setCartLine(c *gin.Context, productID string, quantity func(current int) int) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.loadCart(c, principal.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	want := quantity(helper.CartQuantity(cart, productID))
+	if want > 0 {
+		id, err := primitive.ObjectIDFromHex(productID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ProductNotFoundError})
+			return
+		}
+		product, err := h.db.Products().FindByID(c, id)
+		if errors.Is(err, database.ErrNotFound) || (err == nil && !product.Visible()) {
+			c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ProductNotFoundError})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+		if want > product.Stock {
+			c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.InsufficientStockError, "available": product.Stock})
+			return
+		}
+	}
+
+	helper.SetCartQuantity(&cart, productID, want)
+	if err := h.db.Carts().Save(c, cart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	h.respondCart(c, conv, principal.Email, cart)
+}
+
+// @Summary Add to cart
+// @Description Add units of a product to the cart, a product already in it gets its quantity raised
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param item body types.AddToCart true "Item"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
+// @Success 200 {object}  pricing.Cart
+// @Router /v1/ecommerce/cart [post]
+func (h *Handler) AddToCart(c *gin.Context) {
+	var req types.AddToCart
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quantity < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "quantity must be at least 1"})
+		return
+	}
+
+	h.setCartLine(c, req.ProductID, func(current int) int { return current + req.Quantity })
+}
+
+// @Summary Update cart
+// @Description Set the quantity of a product in the cart, 0 removes it
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param item body types.AddToCart true "Item"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
+// @Success 200 {object}  pricing.Cart
+// @Router /v1/ecommerce/cart/update [put]
+func (h *Handler) UpdateCart(c *gin.Context) {
+	var req types.AddToCart
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quantity < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "quantity can't be negative"})
+		return
+	}
+
+	h.setCartLine(c, req.ProductID, func(int) int { return req.Quantity })
+}
+
+// @Summary Remove from cart
+// @Description Remove a product from the cart
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param productId body string true "Product ID"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
+// @Success 200 {object}  pricing.Cart
+// @Router /v1/ecommerce/cart/remove [post]
+func (h *Handler) RemoveFromCart(c *gin.Context) {
+	var req struct {
+		ProductId string `json:"productId" bson:"productId"`
+	}
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	h.setCartLine(c, req.ProductId, func(int) int { return 0 })
+}
+
+// @Summary List cart
+// @Description List the cart with its totals worked out from the current prices
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
+// @Success 200 {object}  pricing.Cart
+// @Router /v1/ecommerce/cart [get]
+func (h *Handler) ListCart(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.loadCart(c, principal.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	h.respondCart(c, conv, principal.Email, cart)
+}
+
+// @Summary Empty cart
+// @Description Remove every product and the coupon from the cart
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/cart/all [delete]
+func (h *Handler) EmptyCart(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Carts().Delete(c, principal.Email)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary Apply coupon
+// @Description Apply a coupon to the cart and get the discounted price
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param coupon body types.ApplyCoupon true "Coupon"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
+// @Success 200 {object}  pricing.Cart
+// @Router /v1/ecommerce/cart/coupon [post]
+func (h *Handler) ApplyCoupon(c *gin.Context) {
+	var req types.ApplyCoupon
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+
+	coupon, err := h.db.Coupons().FindByName(c, strings.ToUpper(strings.TrimSpace(req.Coupon)))
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.CouponNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	cart, err := h.loadCart(c, principal.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	cart.Coupon = coupon.Name
+
+	region, err := h.taxRegion(c, principal.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	priced, err := h.pricedCart(c, principal.Email, cart, region)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if len(priced.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CartEmptyError})
+		return
+	}
+	shown, ok := convertCart(c, conv, priced)
+	if !ok {
+		return
+	}
+	if priced.CouponError != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": priced.CouponError, "data": shown})
+		return
+	}
+
+	if err := h.db.Carts().Save(c, cart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": shown})
+}
+
+// @Summary Remove coupon
+// @Description Remove the coupon applied to the cart
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
+// @Success 200 {object}  pricing.Cart
+// @Router /v1/ecommerce/cart/coupon [delete]
+func (h *Handler) RemoveCoupon(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.loadCart(c, principal.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if cart.Coupon != "" {
+		cart.Coupon = ""
+		if err := h.db.Carts().Save(c, cart); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+	}
+
+	h.respondCart(c, conv, principal.Email, cart)
+}
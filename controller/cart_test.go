@@ -0,0 +1,89 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+)
+
+func TestAddToCart(t *testing.T) {
+	s := newServer(t)
+	s.user("a@example.com", nil)
+	token, _ := s.login("a@example.com")
+	tea := s.product("Tea", 100, 5)
+	mug := s.product("Mug", 250, 1)
+
+	tests := []struct {
+		name      string
+		productID string
+		quantity  int
+		wantCode  int
+		wantItems int
+		wantUnits float64
+		wantTotal float64
+	}{
+		{name: "first line", productID: tea.ID.Hex(), quantity: 2, wantCode: http.StatusOK, wantItems: 1, wantUnits: 2, wantTotal: 20000},
+		{name: "same product raises the quantity", productID: tea.ID.Hex(), quantity: 1, wantCode: http.StatusOK, wantItems: 1, wantUnits: 3, wantTotal: 30000},
+		{name: "second line", productID: mug.ID.Hex(), quantity: 1, wantCode: http.StatusOK, wantItems: 2, wantUnits: 4, wantTotal: 55000},
+		{name: "more than in stock", productID: tea.ID.Hex(), quantity: 3, wantCode: http.StatusConflict},
+		{name: "no quantity", productID: tea.ID.Hex(), quantity: 0, wantCode: http.StatusBadRequest},
+		{name: "unknown product", productID: "000000000000000000000000", quantity: 1, wantCode: http.StatusNotFound},
+		{name: "malformed product id", productID: "tea", quantity: 1, wantCode: http.StatusNotFound},
+	}
+	// the cases build on each other, one cart goes through all of them
+	for _, tt := range tests {
+		code, body := s.do(http.MethodPost, constant.AddToCartRoute, token, map[string]interface{}{"product_id": tt.productID, "quantity": tt.quantity})
+		if code != tt.wantCode {
+			t.Fatalf("%s: %d %v, want %d", tt.name, code, body, tt.wantCode)
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		if items, _ := field(body, "data", "items").([]interface{}); len(items) != tt.wantItems {
+			t.Errorf("%s: %d lines, want %d", tt.name, len(items), tt.wantItems)
+		}
+		if got := field(body, "data", "num_items"); got != tt.wantUnits {
+			t.Errorf("%s: num_items = %v, want %v", tt.name, got, tt.wantUnits)
+		}
+		if got := field(body, "data", "total", "amount"); got != tt.wantTotal {
+			t.Errorf("%s: total = %v, want %v", tt.name, got, tt.wantTotal)
+		}
+	}
+
+	code, body := s.do(http.MethodGet, constant.ListCartRoute, token, nil)
+	if code != http.StatusOK || field(body, "data", "num_items") != float64(4) {
+		t.Errorf("list cart: %d %v", code, body)
+	}
+}
+
+func TestRemoveFromCart(t *testing.T) {
+	s := newServer(t)
+	s.user("a@example.com", nil)
+	token, _ := s.login("a@example.com")
+	tea := s.product("Tea", 100, 5)
+	mug := s.product("Mug", 250, 5)
+
+	for _, id := range []string{tea.ID.Hex(), mug.ID.Hex()} {
+		if code, body := s.do(http.MethodPost, constant.AddToCartRoute, token, map[string]interface{}{"product_id": id, "quantity": 1}); code != http.StatusOK {
+			t.Fatalf("add: %d %v", code, body)
+		}
+	}
+
+	code, body := s.do(http.MethodPost, constant.RemoveFromCartRoute, token, map[string]string{"productId": tea.ID.Hex()})
+	if code != http.StatusOK {
+		t.Fatalf("remove: %d %v", code, body)
+	}
+	items, _ := field(body, "data", "items").([]interface{})
+	if len(items) != 1 || field(items[0].(map[string]interface{}), "product_id") != mug.ID.Hex() {
+		t.Errorf("items after removing tea = %v", items)
+	}
+
+	// another customer has a cart of their own
+	s.user("b@example.com", nil)
+	other, _ := s.login("b@example.com")
+	code, body = s.do(http.MethodGet, constant.ListCartRoute, other, nil)
+	if code != http.StatusOK || field(body, "data", "num_items") != float64(0) {
+		t.Errorf("cart of another customer: %d %v", code, body)
+	}
+}
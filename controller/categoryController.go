@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// categoryFromParam loads the category addressed by :id, which may also
+// be its slug. It writes the error response itself.
+func (h *Handler) categoryFromParam(c *gin.Context) (types.Category, bool) {
+	param := c.Param("id")
+
+	var category types.Category
+	var err error
+	if id, idErr := primitive.ObjectIDFromHex(param); idErr == nil {
+		category, err = h.db.Categories().FindByID(c, id)
+	} else {
+		category, err = h.db.Categories().FindBySlug(c, param)
+	}
+
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.CategoryNotFoundError})
+		return types.Category{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return types.Category{}, false
+	}
+	return category, true
+}
+
+// validCategoryData checks the payload, fills in a missing slug and makes
+// sure the parent exists and is not the category itself or one of its
+// subcategories. self is empty for a new category.
+func (h *Handler) validCategoryData(c *gin.Context, data *types.CategoryData, self string) bool {
+	if data.Slug == "" {
+		data.Slug = helper.Slugify(data.Category)
+	}
+	if err := helper.CheckCategoryValidation(*data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+	if data.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "slug can't be empty"})
+		return false
+	}
+
+	if data.ParentId == "" {
+		return true
+	}
+
+	categories, err := h.db.Categories().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+
+	found := false
+	for _, category := range categories {
+		found = found || category.ID.Hex() == data.ParentId
+	}
+	if !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CategoryNotFoundError})
+		return false
+	}
+
+	if self != "" {
+		for _, id := range helper.CategorySubtree(categories, self) {
+			if id == data.ParentId {
+				c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.InvalidCategoryParentError})
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// @Summary Add Category
+// @Description Add category by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param category body types.CategoryData true "Category"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/category [post]
+func (h *Handler) AddCategory(c *gin.Context) {
+	var data types.CategoryData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validCategoryData(c, &data, "") {
+		return
+	}
+
+	now := time.Now().Unix()
+	category := types.Category{
+		Category:  strings.TrimSpace(data.Category),
+		Slug:      data.Slug,
+		ParentId:  data.ParentId,
+		Position:  data.Position,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err := h.db.Categories().Create(c, &category)
+	if errors.Is(err, database.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.CategorySlugExistsError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": category})
+}
+
+// @Summary Update Category
+// @Description Rename, move or reorder a category
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Category ID"
+// @Param category body types.CategoryData true "Category"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/category/{id} [put]
+func (h *Handler) UpdateCategory(c *gin.Context) {
+	var data types.CategoryData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	category, ok := h.categoryFromParam(c)
+	if !ok {
+		return
+	}
+
+	// the slug is part of the storefront urls, it only changes on request
+	if data.Slug == "" {
+		data.Slug = category.Slug
+	}
+	if !h.validCategoryData(c, &data, category.ID.Hex()) {
+		return
+	}
+
+	category.Category = strings.TrimSpace(data.Category)
+	category.Slug = data.Slug
+	category.ParentId = data.ParentId
+	category.Position = data.Position
+	category.UpdatedAt = time.Now().Unix()
+	err := h.db.Categories().Update(c, category)
+	if errors.Is(err, database.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.CategorySlugExistsError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": category})
+}
+
+// @Summary Delete Category
+// @Description Delete a category without subcategories. Its products have to be moved first, or with reassign_to in the same request.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Category ID"
+// @Param reassign_to query string false "Category ID receiving the products"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/category/{id} [delete]
+func (h *Handler) DeleteCategory(c *gin.Context) {
+	category, ok := h.categoryFromParam(c)
+	if !ok {
+		return
+	}
+	id := category.ID.Hex()
+
+	// the checks and the delete are separate writes, a subcategory or
+	// product added in between keeps pointing at the deleted category.
+	// The tree shows such a subcategory at the top level, and an admin
+	// can still move it or the product.
+	categories, err := h.db.Categories().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	for _, other := range categories {
+		if other.ParentId == id {
+			c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.CategoryHasChildrenError})
+			return
+		}
+	}
+
+	if target := c.Query("reassign_to"); target != "" {
+		targetId, err := primitive.ObjectIDFromHex(target)
+		if err != nil || target == id {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CategoryNotFoundError})
+			return
+		}
+		if _, err := h.db.Categories().FindByID(c, targetId); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CategoryNotFoundError})
+			return
+		}
+		if _, err := h.db.Products().ReassignCategory(c, id, target); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+	}
+
+	_, count, err := h.db.Products().Find(c, database.ProductQuery{CategoryIDs: []string{id}, IncludeHidden: true, Limit: 1})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.CategoryHasProductsError, "products": count})
+		return
+	}
+
+	if err := h.db.Categories().Delete(c, category.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary List category products
+// @Description List the products of a category and of all its subcategories
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID or slug"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/category/{id}/products [get]
+func (h *Handler) ListCategoryProducts(c *gin.Context) {
+	category, ok := h.categoryFromParam(c)
+	if !ok {
+		return
+	}
+
+	categories, err := h.db.Categories().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	products, count, err := h.db.Products().Find(c, database.ProductQuery{
+		CategoryIDs: helper.CategorySubtree(categories, category.ID.Hex()),
+		InStock:     true,
+		Skip:        int64((page - 1) * limit),
+		Limit:       int64(limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+	prices, err := h.newPricer(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	priced, err := prices.priced(products)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "category": category, "products": convertProducts(conv, priced), "total": count})
+}
@@ -0,0 +1,115 @@
+package controller_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/payment"
+)
+
+func TestCheckout(t *testing.T) {
+	tests := []struct {
+		name       string
+		noAddress  bool
+		emptyCart  bool
+		method     string
+		wantCode   int
+		wantMsg    string
+		wantStatus string
+	}{
+		{name: "cash on delivery", method: payment.CashOnDelivery, wantCode: http.StatusOK, wantStatus: constant.OrderPendingPayment},
+		{name: "card", method: payment.FakeProvider, wantCode: http.StatusOK, wantStatus: constant.OrderPendingPayment},
+		{name: "unknown payment method", method: "cheque", wantCode: http.StatusBadRequest, wantMsg: constant.PaymentMethodError},
+		{name: "no address", noAddress: true, method: payment.CashOnDelivery, wantCode: http.StatusBadRequest, wantMsg: constant.AddressNotExists},
+		{name: "empty cart", emptyCart: true, method: payment.CashOnDelivery, wantCode: http.StatusBadRequest, wantMsg: constant.CartEmptyError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			s.user("a@example.com", nil)
+			token, _ := s.login("a@example.com")
+			if !tt.noAddress {
+				s.address("a@example.com")
+			}
+			tea := s.product("Tea", 100, 5)
+			if !tt.emptyCart {
+				if code, body := s.do(http.MethodPost, constant.AddToCartRoute, token, map[string]interface{}{"product_id": tea.ID.Hex(), "quantity": 2}); code != http.StatusOK {
+					t.Fatalf("add: %d %v", code, body)
+				}
+			}
+
+			code, body := s.do(http.MethodPost, constant.CheckoutRoute, token, map[string]string{"payment_method": tt.method})
+			if code != tt.wantCode {
+				t.Fatalf("checkout: %d %v, want %d", code, body, tt.wantCode)
+			}
+			if tt.wantMsg != "" && body["message"] != tt.wantMsg {
+				t.Errorf("message = %v, want %q", body["message"], tt.wantMsg)
+			}
+
+			wantStock := 5
+			if code == http.StatusOK {
+				wantStock = 3
+				if got := field(body, "data", "status"); got != tt.wantStatus {
+					t.Errorf("status = %v, want %q", got, tt.wantStatus)
+				}
+				if got := field(body, "data", "total", "amount"); got != float64(20000) {
+					t.Errorf("total = %v, want 20000", got)
+				}
+				if _, body := s.do(http.MethodGet, constant.ListCartRoute, token, nil); field(body, "data", "num_items") != float64(0) {
+					t.Errorf("cart after checkout = %v, want it empty", field(body, "data"))
+				}
+			}
+			product, err := s.db.Products().FindByID(context.Background(), tea.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if product.Stock != wantStock {
+				t.Errorf("stock = %d, want %d", product.Stock, wantStock)
+			}
+		})
+	}
+}
+
+func TestCheckoutIdempotency(t *testing.T) {
+	s := newServer(t)
+	s.user("a@example.com", nil)
+	token, _ := s.login("a@example.com")
+	s.address("a@example.com")
+	tea := s.product("Tea", 100, 5)
+	if code, body := s.do(http.MethodPost, constant.AddToCartRoute, token, map[string]interface{}{"product_id": tea.ID.Hex(), "quantity": 2}); code != http.StatusOK {
+		t.Fatalf("add: %d %v", code, body)
+	}
+
+	checkout := map[string]string{"payment_method": payment.CashOnDelivery}
+	code, first := s.do(http.MethodPost, constant.CheckoutRoute, token, checkout, constant.IdempotencyKeyHeader, "order-1")
+	if code != http.StatusOK {
+		t.Fatalf("checkout: %d %v", code, first)
+	}
+	// the retry gets the same order back, even with the cart now empty
+	code, retry := s.do(http.MethodPost, constant.CheckoutRoute, token, checkout, constant.IdempotencyKeyHeader, "order-1")
+	if code != http.StatusOK || field(retry, "data", "id") != field(first, "data", "id") {
+		t.Fatalf("retry: %d %v, want order %v", code, retry, field(first, "data", "id"))
+	}
+	// a new key is a new checkout
+	code, body := s.do(http.MethodPost, constant.CheckoutRoute, token, checkout, constant.IdempotencyKeyHeader, "order-2")
+	if code != http.StatusBadRequest || body["message"] != constant.CartEmptyError {
+		t.Errorf("checkout with another key: %d %v", code, body)
+	}
+
+	orders, err := s.db.Orders().ListByEmail(context.Background(), "a@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 {
+		t.Errorf("%d orders placed, want 1", len(orders))
+	}
+	product, err := s.db.Products().FindByID(context.Background(), tea.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Stock != 3 {
+		t.Errorf("stock = %d, want 3", product.Stock)
+	}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/PiehTVH/go-ecommerce/config"
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/exchange"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/mailer"
+	"github.com/PiehTVH/go-ecommerce/payment"
+	"github.com/PiehTVH/go-ecommerce/pricing"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler groups the HTTP handlers of the API together with the
+// dependencies they share.
+type Handler struct {
+	cfg   config.Config
+	db    database.Manager
+	mail  mailer.Mailer
+	pay   *payment.Gateway
+	rates exchange.RateProvider
+	taxes pricing.TaxCalculator
+}
+
+func NewHandler(cfg config.Config, db database.Manager, mail mailer.Mailer, pay *payment.Gateway, rates exchange.RateProvider, taxes pricing.TaxCalculator) *Handler {
+	return &Handler{cfg: cfg, db: db, mail: mail, pay: pay, rates: rates, taxes: taxes}
+}
+
+// principal returns the caller verified by the auth middleware. It writes
+// the error response itself and returns false when there is none.
+func (h *Handler) principal(c *gin.Context) (types.Principal, bool) {
+	principal, ok := helper.CurrentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.NotAuthorizedUserError})
+		return types.Principal{}, false
+	}
+	return principal, true
+}
+
+// currentUser loads the account of the authenticated caller.
+func (h *Handler) currentUser(c *gin.Context) (types.User, bool) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return types.User{}, false
+	}
+
+	dbUser, err := h.db.Users().FindByID(c, principal.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.UserDoesNotExists})
+		return types.User{}, false
+	}
+	return dbUser, true
+}
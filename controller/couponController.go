@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// validCouponData checks the payload and that the categories and products
+// it is scoped to exist. It writes the error response itself.
+func (h *Handler) validCouponData(c *gin.Context, data types.CouponData) bool {
+	if err := helper.CheckCouponValidation(data, h.cfg.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+
+	for _, id := range data.CategoryIds {
+		categoryId, _ := primitive.ObjectIDFromHex(id)
+		if _, err := h.db.Categories().FindByID(c, categoryId); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CategoryNotFoundError, "id": id})
+			return false
+		}
+	}
+	for _, id := range data.ProductIds {
+		productId, _ := primitive.ObjectIDFromHex(id)
+		product, err := h.db.Products().FindByID(c, productId)
+		if err != nil || product.DeletedAt > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.ProductNotFoundError, "id": id})
+			return false
+		}
+	}
+	return true
+}
+
+// @Summary Add Coupon
+// @Description Add a percentage or fixed amount coupon by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param coupon body types.CouponData true "Coupon"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/coupon [post]
+func (h *Handler) AddCoupon(c *gin.Context) {
+	var data types.CouponData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	data.Name = strings.ToUpper(strings.TrimSpace(data.Name))
+	data.AmountOff = data.AmountOff.DefaultCurrency(h.cfg.Currency)
+	data.MinCartValue = data.MinCartValue.DefaultCurrency(h.cfg.Currency)
+	data.MaxDiscount = data.MaxDiscount.DefaultCurrency(h.cfg.Currency)
+	if !h.validCouponData(c, data) {
+		return
+	}
+
+	now := time.Now().Unix()
+	coupon := types.Coupon{
+		Name:         data.Name,
+		Type:         data.Type,
+		Discount:     data.Discount,
+		AmountOff:    data.AmountOff,
+		MinCartValue: data.MinCartValue,
+		MaxDiscount:  data.MaxDiscount,
+		StartsAt:     data.StartsAt,
+		EndsAt:       data.EndsAt,
+		UsageLimit:   data.UsageLimit,
+		PerUserLimit: data.PerUserLimit,
+		CategoryIds:  data.CategoryIds,
+		ProductIds:   data.ProductIds,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	err := h.db.Coupons().Create(c, &coupon)
+	if errors.Is(err, database.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.CouponExistsError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": coupon})
+}
+
+// @Summary Delete Coupon
+// @Description Delete coupon by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Coupon ID"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/coupon/{id} [delete]
+func (h *Handler) DeleteCoupon(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.CouponNotFoundError})
+		return
+	}
+
+	err = h.db.Coupons().Delete(c, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.CouponNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary List Coupons
+// @Description List every coupon with its usage by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/coupon [get]
+func (h *Handler) ListCoupons(c *gin.Context) {
+	coupons, err := h.db.Coupons().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": coupons})
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/exchange"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+)
+
+// converter returns the conversion from the store currency to the one the
+// caller wants prices in. The currency query parameter has to name a
+// supported currency, from the Accept-Currency header the first supported
+// one is taken and the store currency when there is none. It writes the
+// error response itself.
+func (h *Handler) converter(c *gin.Context) (exchange.Converter, bool) {
+	store := exchange.Identity(h.cfg.Currency)
+
+	query := strings.ToUpper(strings.TrimSpace(c.Query("currency")))
+	header := c.GetHeader(constant.AcceptCurrencyHeader)
+	if (query == "" && header == "") || query == h.cfg.Currency {
+		return store, true
+	}
+
+	table, err := h.rates.Rates(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return exchange.Converter{}, false
+	}
+
+	if query != "" {
+		conv, err := table.Converter(h.cfg.Currency, query)
+		if errors.Is(err, exchange.ErrUnsupportedCurrency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.UnsupportedCurrencyError, "currencies": table.Currencies()})
+			return exchange.Converter{}, false
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return exchange.Converter{}, false
+		}
+		return conv, true
+	}
+
+	// quality values are ignored, the list is taken in order
+	for _, item := range strings.Split(header, ",") {
+		currency, _, _ := strings.Cut(item, ";")
+		currency = strings.ToUpper(strings.TrimSpace(currency))
+		if conv, err := table.Converter(h.cfg.Currency, currency); err == nil {
+			return conv, true
+		}
+	}
+	return store, true
+}
+
+// convertProducts shows products in the currency of conv.
+func convertProducts(conv exchange.Converter, products []types.PricedProduct) []types.PricedProduct {
+	for i := range products {
+		products[i].Price = conv.Convert(products[i].Price)
+		products[i].EffectivePrice = conv.Convert(products[i].EffectivePrice)
+	}
+	return products
+}
+
+// @Summary List currencies
+// @Description List the currencies prices can be shown in, with the rate from the store currency
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/currencies [get]
+func (h *Handler) ListCurrencies(c *gin.Context) {
+	table, err := h.rates.Rates(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	rates := gin.H{}
+	for _, currency := range table.Currencies() {
+		if conv, err := table.Converter(h.cfg.Currency, currency); err == nil {
+			rates[currency] = conv.RateString()
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": gin.H{
+		"store_currency": h.cfg.Currency,
+		"rates":          rates,
+		"as_of":          table.AsOf,
+	}})
+}
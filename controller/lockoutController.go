@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+)
+
+// loginLockedUntil returns when the account or the client IP may sign in
+// again, 0 when neither is locked out.
+func (h *Handler) loginLockedUntil(c *gin.Context, email string) (int64, error) {
+	now := time.Now().Unix()
+	limits := map[string]int{
+		helper.AccountThrottleKey(email):   constant.LoginMaxAccountFailures,
+		helper.IPThrottleKey(c.ClientIP()): constant.LoginMaxIPFailures,
+	}
+
+	var until int64
+	for key, max := range limits {
+		throttle, err := h.db.LoginThrottles().Get(c, key)
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if lockedUntil := helper.LockedUntil(throttle, max); lockedUntil > now && lockedUntil > until {
+			until = lockedUntil
+		}
+	}
+	return until, nil
+}
+
+// loginFailed counts a failed sign in against the account and the client
+// IP and keeps a record of it for the back office.
+func (h *Handler) loginFailed(c *gin.Context, email string, reason string) error {
+	now := time.Now().Unix()
+
+	for _, key := range []string{helper.AccountThrottleKey(email), helper.IPThrottleKey(c.ClientIP())} {
+		throttle, err := h.db.LoginThrottles().Get(c, key)
+		if err == nil && helper.ThrottleExpired(throttle, now) {
+			if err := h.db.LoginThrottles().Reset(c, key); err != nil {
+				return err
+			}
+		}
+		if _, err := h.db.LoginThrottles().Fail(c, key, now); err != nil {
+			return err
+		}
+	}
+
+	return h.db.LoginAttempts().Create(c, &types.LoginAttempt{
+		Email:     helper.NormalizeEmail(email),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Reason:    reason,
+		CreatedAt: now,
+	})
+}
+
+// @Summary Unlock user
+// @Description Clear the failed sign in counter of an account
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param user_email body string true "User Email"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/unlock-user [put]
+func (h *Handler) UnlockUser(c *gin.Context) {
+	var req struct {
+		Email string `json:"user_email" bson:"user_email"`
+	}
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "Invalid request"})
+		return
+	}
+
+	if err := h.db.LoginThrottles().Reset(c, helper.AccountThrottleKey(req.Email)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary List login attempts
+// @Description Show the lockout state and the recent failed sign ins of an account
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param email query string true "User Email"
+// @Param limit query int false "Limit"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/login-attempts [get]
+func (h *Handler) ListLoginAttempts(c *gin.Context) {
+	email := helper.NormalizeEmail(c.Query("email"))
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email is required"})
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	attempts, err := h.db.LoginAttempts().Recent(c, email, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	throttle, err := h.db.LoginThrottles().Get(c, helper.AccountThrottleKey(email))
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"error":        false,
+		"message":      "success",
+		"failures":     throttle.Failures,
+		"locked_until": helper.LockedUntil(throttle, constant.LoginMaxAccountFailures),
+		"data":         attempts,
+	})
+}
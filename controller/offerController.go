@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/pricing"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pricer resolves product prices against the offers running when it was
+// made, so a listing is priced consistently.
+type pricer struct {
+	categories []types.Category
+	offers     []types.Offer
+	now        int64
+}
+
+func (h *Handler) newPricer(c *gin.Context) (pricer, error) {
+	now := time.Now().Unix()
+	categories, err := h.db.Categories().List(c)
+	if err != nil {
+		return pricer{}, err
+	}
+	offers, err := h.db.Offers().Running(c, now)
+	if err != nil {
+		return pricer{}, err
+	}
+	return pricer{categories: categories, offers: offers, now: now}, nil
+}
+
+func (p pricer) price(product types.Product) (pricing.Price, error) {
+	return pricing.Resolve(product, helper.CategoryAncestors(p.categories, product.CategoryId), p.offers, p.now)
+}
+
+// priced pairs each product with the price customers pay for it.
+func (p pricer) priced(products []types.Product) ([]types.PricedProduct, error) {
+	out := make([]types.PricedProduct, 0, len(products))
+	for _, product := range products {
+		price, err := p.price(product)
+		if err != nil {
+			return nil, err
+		}
+		item := types.PricedProduct{Product: product, EffectivePrice: price.Price}
+		if price.Offer != nil {
+			item.OfferId = price.Offer.ID.Hex()
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// validOfferData checks the payload and that the category or product the
+// offer targets exists. It writes the error response itself.
+func (h *Handler) validOfferData(c *gin.Context, data types.OfferData) bool {
+	if err := helper.CheckOfferValidation(data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+
+	if data.CategoryId != "" {
+		categoryId, _ := primitive.ObjectIDFromHex(data.CategoryId)
+		if _, err := h.db.Categories().FindByID(c, categoryId); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CategoryNotFoundError})
+			return false
+		}
+		return true
+	}
+
+	productId, _ := primitive.ObjectIDFromHex(data.ProductId)
+	product, err := h.db.Products().FindByID(c, productId)
+	if err != nil || product.DeletedAt > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.ProductNotFoundError})
+		return false
+	}
+	return true
+}
+
+// @Summary Add Offer
+// @Description Add a time limited percentage offer on a category or a product by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param offer body types.OfferData true "Offer"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/offer [post]
+func (h *Handler) AddOffer(c *gin.Context) {
+	var data types.OfferData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validOfferData(c, data) {
+		return
+	}
+
+	now := time.Now().Unix()
+	offer := types.Offer{
+		Name:       strings.TrimSpace(data.Name),
+		CategoryId: data.CategoryId,
+		ProductId:  data.ProductId,
+		Discount:   data.Discount,
+		StartsAt:   data.StartsAt,
+		EndsAt:     data.EndsAt,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := h.db.Offers().Create(c, &offer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": offer})
+}
+
+// @Summary Update Offer
+// @Description Change an offer by admin, setting ends_at to now ends it
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Offer ID"
+// @Param offer body types.OfferData true "Offer"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/offer/{id} [put]
+func (h *Handler) UpdateOffer(c *gin.Context) {
+	var data types.OfferData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.OfferNotFoundError})
+		return
+	}
+	offer, err := h.db.Offers().FindByID(c, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.OfferNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validOfferData(c, data) {
+		return
+	}
+
+	offer.Name = strings.TrimSpace(data.Name)
+	offer.CategoryId = data.CategoryId
+	offer.ProductId = data.ProductId
+	offer.Discount = data.Discount
+	offer.StartsAt = data.StartsAt
+	offer.EndsAt = data.EndsAt
+	offer.UpdatedAt = time.Now().Unix()
+	if err := h.db.Offers().Update(c, offer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": offer})
+}
+
+// @Summary List Offers
+// @Description List every offer by admin, past and upcoming ones included
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/offer [get]
+func (h *Handler) ListOffers(c *gin.Context) {
+	offers, err := h.db.Offers().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	now := time.Now().Unix()
+	running := []string{}
+	for _, offer := range offers {
+		if offer.Running(now) {
+			running = append(running, offer.ID.Hex())
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": offers, "running": running})
+}
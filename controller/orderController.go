@@ -0,0 +1,584 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/payment"
+	"github.com/PiehTVH/go-ecommerce/pricing"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// reserveStock takes the units of every line out of stock. Each decrement
+// only goes through while enough stock is left, so concurrent checkouts
+// can not oversell. When a line fails the lines already taken are put
+// back and the error response is written.
+func (h *Handler) reserveStock(c *gin.Context, lines []pricing.Line) bool {
+	for i, line := range lines {
+		id, _ := primitive.ObjectIDFromHex(line.ProductID)
+		_, err := h.db.Products().AdjustStock(c, id, -line.Quantity)
+		if err == nil {
+			continue
+		}
+
+		h.releaseStock(c, lines[:i])
+		switch {
+		case errors.Is(err, database.ErrInsufficientStock):
+			c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.InsufficientStockError, "product_id": line.ProductID})
+		case errors.Is(err, database.ErrNotFound):
+			c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.CartItemUnavailableError, "product_id": line.ProductID})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		}
+		return false
+	}
+	return true
+}
+
+// releaseStock puts the units of lines back in stock.
+func (h *Handler) releaseStock(c *gin.Context, lines []pricing.Line) {
+	for _, line := range lines {
+		id, _ := primitive.ObjectIDFromHex(line.ProductID)
+		_, _ = h.db.Products().AdjustStock(c, id, line.Quantity)
+	}
+}
+
+// undoCheckout puts back the stock and the coupon use taken by a checkout
+// that did not place its order.
+func (h *Handler) undoCheckout(c *gin.Context, lines []pricing.Line, coupon *types.Coupon, email string) {
+	h.releaseStock(c, lines)
+	if coupon != nil {
+		_ = h.db.Coupons().Release(c, *coupon, email)
+	}
+}
+
+// replayOrder answers a checkout retried with an idempotency key that
+// already placed order.
+func replayOrder(c *gin.Context, order types.Order) {
+	c.Header(constant.IdempotentReplayedHeader, "true")
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": order})
+}
+
+// @Summary Checkout
+// @Description Turn the cart into an order. Stock is reserved, prices, tax, shipping and the addresses are copied into the order and a payment intent is opened with the chosen provider. A request retried with the same Idempotency-Key returns the order it placed.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @param Idempotency-Key header string false "Key identifying this checkout"
+// @Param checkout body types.Checkout false "Payment and shipping method, addresses from the address book"
+// @Param currency query string false "Currency the customer sees the order in"
+// @Param Accept-Currency header string false "Currencies the customer sees the order in, most wanted first"
+// @Success 200 {object}  types.Order
+// @Router /v1/ecommerce/checkout [post]
+func (h *Handler) Checkout(c *gin.Context) {
+	var req types.Checkout
+
+	defer c.Request.Body.Close()
+
+	// the body is optional
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	key := strings.TrimSpace(c.GetHeader(constant.IdempotencyKeyHeader))
+	if len(key) > constant.IdempotencyKeyMaxLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.IdempotencyKeyError})
+		return
+	}
+	if key != "" {
+		order, err := h.db.Orders().FindByIdempotencyKey(c, user.Email, key)
+		if err == nil {
+			replayOrder(c, order)
+			return
+		}
+		if !errors.Is(err, database.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+	}
+
+	provider, ok := h.pay.Provider(req.PaymentMethod)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.PaymentMethodError, "methods": h.pay.Names()})
+		return
+	}
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+
+	shipTo, billTo, ok := h.checkoutAddresses(c, user.Email, req)
+	if !ok {
+		return
+	}
+
+	cart, err := h.loadCart(c, user.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	priced, err := h.pricedCart(c, user.Email, cart, shipTo.TaxRegion())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if len(cart.Products) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CartEmptyError})
+		return
+	}
+	if len(priced.Lines) != len(cart.Products) {
+		if shown, ok := convertCart(c, conv, priced); ok {
+			c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.CartItemUnavailableError, "data": shown})
+		}
+		return
+	}
+	if priced.CouponError != "" {
+		if shown, ok := convertCart(c, conv, priced); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": priced.CouponError, "data": shown})
+		}
+		return
+	}
+	shipped, ok := chooseShipping(c, conv, &priced, req.ShippingMethod)
+	if !ok {
+		return
+	}
+	shown, ok := convertCart(c, conv, priced)
+	if !ok {
+		return
+	}
+
+	if !h.reserveStock(c, priced.Lines) {
+		return
+	}
+
+	var coupon *types.Coupon
+	if priced.Coupon != "" {
+		found, err := h.db.Coupons().FindByName(c, priced.Coupon)
+		if err == nil {
+			err = h.db.Coupons().Redeem(c, found, user.Email)
+		}
+		if err != nil {
+			h.releaseStock(c, priced.Lines)
+			if errors.Is(err, database.ErrLimitReached) || errors.Is(err, database.ErrNotFound) {
+				c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.CouponUsedUpError})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+		coupon = &found
+	}
+
+	now := time.Now().Unix()
+	order := types.Order{
+		// the payment intent refers to the order before it is stored
+		ID:              primitive.NewObjectID(),
+		Email:           user.Email,
+		IdempotencyKey:  key,
+		Items:           make([]types.OrderItem, 0, len(priced.Lines)),
+		NumItems:        priced.NumItems,
+		Subtotal:        priced.Subtotal,
+		Discount:        priced.Discount,
+		Tax:             priced.Tax,
+		TaxInclusive:    priced.TaxInclusive,
+		TaxRegion:       priced.TaxRegion,
+		Total:           priced.Total,
+		Coupon:          priced.Coupon,
+		ShippingAddress: shipTo.PostalAddress,
+		BillingAddress:  billTo.PostalAddress,
+		Conversion: types.Conversion{
+			Currency:           conv.To,
+			SettlementCurrency: conv.From,
+			Rate:               conv.RateString(),
+			RatesAsOf:          conv.AsOf,
+			Total:              shown.Total,
+		},
+		Status:    constant.OrderPendingPayment,
+		History:   []types.OrderEvent{{Status: constant.OrderPendingPayment, At: now, By: user.Email}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if shipped.MethodID != "" {
+		weight, _ := priced.Parcel()
+		order.Shipping = types.OrderShipping{ShippingOption: shipped, Weight: weight}
+	}
+	for _, line := range priced.Lines {
+		order.Items = append(order.Items, types.OrderItem{
+			ProductID: line.ProductID,
+			Name:      line.Name,
+			ListPrice: line.ListPrice,
+			UnitPrice: line.UnitPrice,
+			Quantity:  line.Quantity,
+			Discount:  line.Discount,
+			TaxRate:   line.TaxRate,
+			Tax:       line.Tax,
+		})
+	}
+
+	if !order.Total.IsZero() {
+		intent, err := provider.Authorize(c, payment.AuthorizeRequest{OrderID: order.ID.Hex(), Email: user.Email, Amount: order.Total})
+		if err != nil {
+			h.undoCheckout(c, priced.Lines, coupon, user.Email)
+			c.JSON(http.StatusBadGateway, gin.H{"error": true, "message": constant.PaymentUnavailableError})
+			return
+		}
+		if intent.Status == payment.StatusFailed {
+			h.undoCheckout(c, priced.Lines, coupon, user.Email)
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": true, "message": constant.PaymentDeclinedError})
+			return
+		}
+		order.Payment = types.Payment{Provider: provider.Name(), IntentID: intent.ID, Status: intent.Status, Amount: intent.Amount}
+	}
+	// nothing to wait for when the provider authorizes on the spot or the
+	// coupon covers everything. Cash on delivery stays pending until the
+	// courier collects it, the order can be packed meanwhile.
+	switch {
+	case order.Total.IsZero():
+		order.Status = constant.OrderPaid
+		order.History = append(order.History, types.OrderEvent{Status: constant.OrderPaid, At: now, Note: "nothing to pay"})
+	case payment.CollectsOnDelivery(provider):
+	case order.Payment.Status == payment.StatusAuthorized:
+		order.Status = constant.OrderPaid
+		order.History = append(order.History, types.OrderEvent{Status: constant.OrderPaid, At: now, Note: "payment authorized by " + provider.Name()})
+	}
+
+	err = h.db.Orders().Create(c, &order)
+	if err != nil {
+		// undo the reservation, a concurrent request with the same key
+		// placed the order already
+		h.undoCheckout(c, priced.Lines, coupon, user.Email)
+		// a card authorization is released, cash on delivery took nothing
+		if order.Payment.Status == payment.StatusAuthorized && !payment.CollectsOnDelivery(provider) {
+			_, _ = provider.Refund(c, order.Payment.IntentID, order.Payment.Amount)
+		}
+		if errors.Is(err, database.ErrDuplicate) {
+			if placed, err := h.db.Orders().FindByIdempotencyKey(c, user.Email, key); err == nil {
+				replayOrder(c, placed)
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	// the order is placed, a cart left behind must not read as a failed
+	// checkout
+	if err := h.clearOrderedItems(c, order); err != nil {
+		log.Printf("clearing the cart of %s after order %s: %v", user.Email, order.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": order})
+}
+
+// clearOrderedItems takes the items of order out of the cart of its
+// customer. Units added while the order was placed stay in the cart, the
+// cart is only written back while it is still as it was read.
+func (h *Handler) clearOrderedItems(c *gin.Context, order types.Order) error {
+	for attempt := 0; attempt < constant.CartClearAttempts; attempt++ {
+		stored, err := h.db.Carts().FindByEmail(c, order.Email)
+		if errors.Is(err, database.ErrNotFound) || (err == nil && stored.ChekedOut) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		cart := stored
+		helper.MergeCartLines(&cart)
+		for _, item := range order.Items {
+			helper.SetCartQuantity(&cart, item.ProductID, helper.CartQuantity(cart, item.ProductID)-item.Quantity)
+		}
+		// the coupon went into the order
+		if cart.Coupon == order.Coupon {
+			cart.Coupon = ""
+		}
+		cart.ChekedOut = len(cart.Products) == 0
+
+		err = h.db.Carts().Replace(c, stored, cart)
+		if !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+	}
+	return errors.New("the cart kept changing")
+}
+
+// transitionOrder moves the order to status and applies what the move
+// implies: the items of a cancelled or returned order go back in stock, a
+// cancelled order gives its coupon use back and the payment is captured or
+// refunded. by is the email of who asked for it. It writes the error
+// response itself.
+func (h *Handler) transitionOrder(c *gin.Context, order types.Order, status, by, note string) (types.Order, bool) {
+	unpaidPacking := order.Status == constant.OrderPendingPayment && status == constant.OrderPacked
+	if !helper.CanTransition(order.Status, status) || (unpaidPacking && !h.paidOnDelivery(order)) {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.OrderTransitionError, "status": order.Status})
+		return types.Order{}, false
+	}
+
+	event := types.OrderEvent{Status: status, At: time.Now().Unix(), By: by, Note: strings.TrimSpace(note)}
+	updated, err := h.db.Orders().Transition(c, order.ID, order.Status, event)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.OrderChangedError})
+		return types.Order{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return types.Order{}, false
+	}
+
+	if status == constant.OrderCancelled || status == constant.OrderReturned {
+		// deleted products are not restocked
+		for _, item := range updated.Items {
+			id, _ := primitive.ObjectIDFromHex(item.ProductID)
+			_, _ = h.db.Products().AdjustStock(c, id, item.Quantity)
+		}
+	}
+	if status == constant.OrderCancelled && updated.Coupon != "" {
+		if coupon, err := h.db.Coupons().FindByName(c, updated.Coupon); err == nil {
+			_ = h.db.Coupons().Release(c, coupon, updated.Email)
+		}
+	}
+	return h.settlePayment(c, updated), true
+}
+
+// paidOnDelivery reports whether the payment of order is collected by the
+// courier, the order is fulfilled before it is paid.
+func (h *Handler) paidOnDelivery(order types.Order) bool {
+	provider, ok := h.pay.Provider(order.Payment.Provider)
+	return ok && payment.CollectsOnDelivery(provider) && order.Payment.Status == payment.StatusAuthorized
+}
+
+// orderFromParam loads the order addressed by :id. Unless admin is set
+// the order has to belong to email. It writes the error response itself.
+func (h *Handler) orderFromParam(c *gin.Context, email string, admin bool) (types.Order, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.OrderNotFoundError})
+		return types.Order{}, false
+	}
+
+	order, err := h.db.Orders().FindByID(c, id)
+	if errors.Is(err, database.ErrNotFound) || (err == nil && !admin && order.Email != email) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.OrderNotFoundError})
+		return types.Order{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return types.Order{}, false
+	}
+	return order, true
+}
+
+// pageQuery reads the page and limit query parameters.
+func pageQuery(c *gin.Context, defaultLimit, maxLimit int) (skip, limit int64) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || size < 1 || size > maxLimit {
+		size = defaultLimit
+	}
+	return int64((page - 1) * size), int64(size)
+}
+
+// @Summary List my orders
+// @Description List the orders of the caller, newest first
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/orders [get]
+func (h *Handler) ListMyOrders(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+
+	skip, limit := pageQuery(c, 20, 100)
+	orders, count, err := h.db.Orders().Find(c, database.OrderQuery{Email: principal.Email, Skip: skip, Limit: limit})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": orders, "total": count})
+}
+
+// @Summary Get my order
+// @Description Get one order of the caller with its status history
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Order ID"
+// @Success 200 {object}  types.Order
+// @Router /v1/ecommerce/orders/{id} [get]
+func (h *Handler) GetMyOrder(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+
+	order, ok := h.orderFromParam(c, principal.Email, false)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": order})
+}
+
+// @Summary Cancel my order
+// @Description Cancel an order of the caller that has not been packed yet
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Order ID"
+// @Success 200 {object}  types.Order
+// @Router /v1/ecommerce/orders/{id}/cancel [post]
+func (h *Handler) CancelOrder(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+
+	order, ok := h.orderFromParam(c, principal.Email, false)
+	if !ok {
+		return
+	}
+	if !helper.Cancellable(order.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.OrderTransitionError, "status": order.Status})
+		return
+	}
+
+	order, ok = h.transitionOrder(c, order, constant.OrderCancelled, principal.Email, "cancelled by the customer")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": order})
+}
+
+// @Summary List orders
+// @Description List every order by admin, newest first
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param status query string false "Status"
+// @Param email query string false "Customer email"
+// @Param from query string false "Placed on or after this day, YYYY-MM-DD"
+// @Param to query string false "Placed on or before this day, YYYY-MM-DD"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/list-orders [get]
+func (h *Handler) ListOrders(c *gin.Context) {
+	query := database.OrderQuery{
+		Email:  helper.NormalizeEmail(c.Query("email")),
+		Status: c.Query("status"),
+	}
+	if query.Status != "" && !helper.IsOrderStatus(query.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.InvalidOrderStatusError})
+		return
+	}
+
+	if from := c.Query("from"); from != "" {
+		day, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "from must be a YYYY-MM-DD date"})
+			return
+		}
+		query.From = day.Unix()
+	}
+	if to := c.Query("to"); to != "" {
+		day, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "to must be a YYYY-MM-DD date"})
+			return
+		}
+		query.To = day.AddDate(0, 0, 1).Unix()
+	}
+
+	query.Skip, query.Limit = pageQuery(c, 50, 200)
+	orders, count, err := h.db.Orders().Find(c, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": orders, "total": count})
+}
+
+// @Summary Update order status
+// @Description Move an order to its next status by admin. Only the payment marks an order paid, an order paid on delivery is packed while its payment is pending.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Order ID"
+// @Param status body types.UpdateOrderStatus true "Status"
+// @Success 200 {object}  types.Order
+// @Router /v1/ecommerce/update-order/{id} [put]
+func (h *Handler) UpdateOrderStatus(c *gin.Context) {
+	var req types.UpdateOrderStatus
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if !helper.IsOrderStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.InvalidOrderStatusError})
+		return
+	}
+	// the payment webhook marks orders paid, an admin can't vouch for it
+	if req.Status == constant.OrderPaid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.OrderPaidByPaymentError})
+		return
+	}
+
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+
+	order, ok := h.orderFromParam(c, "", true)
+	if !ok {
+		return
+	}
+
+	order, ok = h.transitionOrder(c, order, req.Status, principal.Email, req.Note)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": order})
+}
@@ -0,0 +1,94 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/payment"
+)
+
+func TestCancelOrder(t *testing.T) {
+	s := newServer(t)
+	s.user("a@example.com", nil)
+	token, _ := s.login("a@example.com")
+	s.address("a@example.com")
+	tea := s.product("Tea", 100, 5)
+	if code, body := s.do(http.MethodPost, constant.AddToCartRoute, token, map[string]interface{}{"product_id": tea.ID.Hex(), "quantity": 2}); code != http.StatusOK {
+		t.Fatalf("add: %d %v", code, body)
+	}
+	code, body := s.do(http.MethodPost, constant.CheckoutRoute, token, map[string]string{"payment_method": payment.CashOnDelivery})
+	if code != http.StatusOK {
+		t.Fatalf("checkout: %d %v", code, body)
+	}
+	id := field(body, "data", "id").(string)
+
+	code, body = s.do(http.MethodPost, "/orders/"+id+"/cancel", token, nil)
+	if code != http.StatusOK {
+		t.Fatalf("cancel: %d %v", code, body)
+	}
+	if got := field(body, "data", "status"); got != constant.OrderCancelled {
+		t.Errorf("status = %v, want %q", got, constant.OrderCancelled)
+	}
+	// the courier never collected the cash, there is nothing to refund
+	if got := field(body, "data", "payment", "status"); got != payment.StatusAuthorized {
+		t.Errorf("payment status = %v, want %q", got, payment.StatusAuthorized)
+	}
+
+	code, body = s.do(http.MethodPost, "/orders/"+id+"/cancel", token, nil)
+	if code != http.StatusConflict {
+		t.Errorf("cancel again: %d %v, want %d", code, body, http.StatusConflict)
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		steps    []string
+		wantCode int
+		wantMsg  string
+	}{
+		{name: "cash on delivery is packed unpaid", method: payment.CashOnDelivery, steps: []string{constant.OrderPacked, constant.OrderShipped, constant.OrderDelivered}, wantCode: http.StatusOK},
+		{name: "card waits for the payment", method: payment.FakeProvider, steps: []string{constant.OrderPacked}, wantCode: http.StatusConflict, wantMsg: constant.OrderTransitionError},
+		{name: "admin marks cash on delivery paid", method: payment.CashOnDelivery, steps: []string{constant.OrderPaid}, wantCode: http.StatusBadRequest, wantMsg: constant.OrderPaidByPaymentError},
+		{name: "admin marks card paid", method: payment.FakeProvider, steps: []string{constant.OrderPaid}, wantCode: http.StatusBadRequest, wantMsg: constant.OrderPaidByPaymentError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			admin := s.admin("admin@example.com")
+			s.user("a@example.com", nil)
+			token, _ := s.login("a@example.com")
+			s.address("a@example.com")
+			tea := s.product("Tea", 100, 5)
+			if code, body := s.do(http.MethodPost, constant.AddToCartRoute, token, map[string]interface{}{"product_id": tea.ID.Hex(), "quantity": 1}); code != http.StatusOK {
+				t.Fatalf("add: %d %v", code, body)
+			}
+			code, body := s.do(http.MethodPost, constant.CheckoutRoute, token, map[string]string{"payment_method": tt.method})
+			if code != http.StatusOK {
+				t.Fatalf("checkout: %d %v", code, body)
+			}
+			id := field(body, "data", "id").(string)
+
+			for _, status := range tt.steps {
+				code, body = s.do(http.MethodPut, "/update-order/"+id, admin, map[string]string{"status": status})
+				if code != http.StatusOK {
+					break
+				}
+			}
+			if code != tt.wantCode {
+				t.Fatalf("update: %d %v, want %d", code, body, tt.wantCode)
+			}
+			if tt.wantMsg != "" && body["message"] != tt.wantMsg {
+				t.Errorf("message = %v, want %q", body["message"], tt.wantMsg)
+			}
+			if code == http.StatusOK && tt.method == payment.CashOnDelivery {
+				// the courier collected the cash on delivery
+				if got := field(body, "data", "payment", "status"); got != payment.StatusCaptured {
+					t.Errorf("payment status = %v, want %q", got, payment.StatusCaptured)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/mailer"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Forgot password
+// @Description Email a password reset link. The answer is the same whether or not the email is registered.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param email body types.EmailData true "Email"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/forgot-password [post]
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req types.EmailData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	dbUser, err := h.db.Users().FindByEmail(c, helper.NormalizeEmail(req.Email))
+	if err == nil {
+		if err := h.sendResetLink(c, dbUser); err != nil {
+			log.Printf("password reset for %s: %v", dbUser.Email, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": constant.PasswordResetSentMessage})
+}
+
+// sendResetLink stores a new reset token for the user and mails the link
+// in the background, so the response time does not tell whether the
+// address is registered.
+func (h *Handler) sendResetLink(c *gin.Context, user types.User) error {
+	token, err := helper.RandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reset := types.PasswordReset{
+		UserID:    user.Id,
+		TokenHash: helper.HashToken(token),
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(constant.PasswordResetValidity * time.Minute).Unix(),
+	}
+	if err := h.db.PasswordResets().Create(c, &reset); err != nil {
+		return err
+	}
+
+	link := h.cfg.FrontendURL + "/reset-password?token=" + url.QueryEscape(token)
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Use the link below to choose a new password. It expires in %d minutes and works once.\n\n%s\n\nIf you did not ask for this, you can ignore this email.", constant.PasswordResetValidity, link),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := h.mail.Send(ctx, msg); err != nil {
+			log.Printf("password reset mail to %s: %v", msg.To, err)
+		}
+	}()
+	return nil
+}
+
+// @Summary Reset password
+// @Description Set a new password with the token of a reset link. Signs the user out everywhere.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param reset body types.ResetPassword true "Reset"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/reset-password [post]
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req types.ResetPassword
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if err := helper.ValidatePassword(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	now := time.Now().Unix()
+	reset, err := h.db.PasswordResets().FindByHash(c, helper.HashToken(req.Token))
+	if err != nil || reset.UsedAt != 0 || now >= reset.ExpiresAt {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.InvalidResetTokenError})
+		return
+	}
+
+	dbUser, err := h.db.Users().FindByID(c, reset.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.InvalidResetTokenError})
+		return
+	}
+
+	password, err := helper.EncryptPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	// consuming the token first makes a concurrent second use fail
+	if err := h.db.PasswordResets().MarkUsed(c, reset.ID, now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.InvalidResetTokenError})
+		return
+	}
+
+	// following the link proves the mailbox belongs to the user
+	dbUser.Password = password
+	dbUser.Verified = true
+	dbUser.UpdatedAt = now
+	if err := h.db.Users().Update(c, dbUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if err := h.db.PasswordResets().InvalidateForUser(c, dbUser.Id, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if err := h.revokeSessions(c, dbUser.Id, constant.RevokedPasswordReset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
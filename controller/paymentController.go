@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/payment"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// webhookMaxBytes caps the body of a payment webhook
+const webhookMaxBytes = 1 << 20
+
+// settlePayment captures the payment of a delivered order and refunds the
+// one of a cancelled or returned order once it was captured. Nothing was
+// taken for an authorization that was never captured, such as cash on
+// delivery the courier did not collect, so there is nothing to refund. A
+// provider failure is recorded on the payment, the order keeps its status
+// either way.
+func (h *Handler) settlePayment(c *gin.Context, order types.Order) types.Order {
+	p := order.Payment
+	if p.Provider == "" {
+		return order
+	}
+
+	provider, ok := h.pay.Provider(p.Provider)
+	var err error
+	var intent payment.Intent
+	switch {
+	case order.Status == constant.OrderDelivered && p.Status == payment.StatusAuthorized:
+		if ok {
+			intent, err = provider.Capture(c, p.IntentID, p.Amount)
+		}
+	case (order.Status == constant.OrderCancelled || order.Status == constant.OrderReturned) &&
+		p.Status == payment.StatusCaptured:
+		if ok {
+			intent, err = provider.Refund(c, p.IntentID, p.Amount)
+		}
+	default:
+		return order
+	}
+
+	switch {
+	case !ok:
+		p.Error = constant.PaymentMethodError + " " + p.Provider
+	case err != nil:
+		p.Error = err.Error()
+	default:
+		p.Status = intent.Status
+		p.Error = ""
+	}
+	updated, err := h.db.Orders().SetPayment(c, order.ID, p)
+	if err != nil {
+		order.Payment = p
+		return order
+	}
+	return updated
+}
+
+// @Summary Payment webhook
+// @Description Receive a signed payment notification from a provider. An authorized payment moves its order from pending_payment to paid, a failed one cancels it.
+// @Tags Payment
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider"
+// @Success 200 {object}  types.Order
+// @Router /v1/ecommerce/payment/webhook/{provider} [post]
+func (h *Handler) PaymentWebhook(c *gin.Context) {
+	provider, ok := h.pay.Provider(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.PaymentMethodError})
+		return
+	}
+
+	defer c.Request.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, webhookMaxBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	event, err := provider.VerifyWebhook(payload, c.Request.Header)
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(event.OrderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.OrderNotFoundError})
+		return
+	}
+	order, err := h.db.Orders().FindByID(c, id)
+	if errors.Is(err, database.ErrNotFound) || (err == nil && (order.Payment.Provider != provider.Name() || order.Payment.IntentID != event.IntentID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.OrderNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if event.Amount != order.Payment.Amount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "amount does not match the order"})
+		return
+	}
+
+	// providers deliver webhooks at least once, an event already applied
+	// only settles what is still owed
+	if order.Payment.Status != payment.StatusRequiresAction {
+		order = h.settlePayment(c, order)
+		c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": order})
+		return
+	}
+
+	p := order.Payment
+	switch event.Type {
+	case payment.EventAuthorized:
+		p.Status = payment.StatusAuthorized
+	case payment.EventFailed:
+		p.Status = payment.StatusFailed
+	default:
+		c.JSON(http.StatusOK, gin.H{"error": false, "message": "ignored"})
+		return
+	}
+	order, err = h.db.Orders().SetPayment(c, order.ID, p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	switch {
+	case p.Status == payment.StatusAuthorized && order.Status == constant.OrderPendingPayment:
+		order, ok = h.transitionOrder(c, order, constant.OrderPaid, "", "payment authorized by "+provider.Name())
+		if !ok {
+			return
+		}
+	case p.Status == payment.StatusAuthorized:
+		// the customer cancelled before paying
+		order = h.settlePayment(c, order)
+	case order.Status == constant.OrderPendingPayment:
+		// the stock and the coupon go back as for a customer cancel
+		order, ok = h.transitionOrder(c, order, constant.OrderCancelled, "", "payment failed at "+provider.Name())
+		if !ok {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": order})
+}
@@ -1,33 +1,25 @@
 package controller
 
 import (
-	"context"
-	"os"
-
 	"github.com/PiehTVH/go-ecommerce/constant"
 	"github.com/PiehTVH/go-ecommerce/database"
 	"github.com/PiehTVH/go-ecommerce/helper"
 	"github.com/PiehTVH/go-ecommerce/types"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // @Summary List all products
-// @Description List all products
+// @Description List all products with the price after offers
 // @Tags User
 // @Accept json
 // @Produce json
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
 // @Success 200 {object}  string
 // @Router /v1/ecommerce/products [get]
-func ListProductsController(c *gin.Context) {
-
-	var productCollection *mongo.Collection = database.GetCollection(database.DB, constant.ProductCollection)
-
-	var products []types.Product
-
-	cursor, err := productCollection.Find(context.Background(), bson.D{})
+func (h *Handler) ListProductsController(c *gin.Context) {
+	products, _, err := h.db.Products().Find(c, database.ProductQuery{InStock: true})
 
 	if err != nil {
 		c.JSON(400, gin.H{
@@ -36,34 +28,38 @@ func ListProductsController(c *gin.Context) {
 		return
 	}
 
-	defer cursor.Close(context.Background())
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+	prices, err := h.newPricer(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"message": constant.BadRequestMessage,
+		})
+		return
+	}
 
-	for cursor.Next(context.Background()) {
-		var product types.Product
-		cursor.Decode(&product)
-		if product.Stock > 0 {
-			products = append(products, product)
-		}
+	priced, err := prices.priced(products)
+	if err != nil {
+		c.JSON(500, gin.H{"error": true, "message": err.Error()})
+		return
 	}
 
 	c.JSON(200, gin.H{
-		"products": products,
+		"products": convertProducts(conv, priced),
 	})
 }
 
 // @Summary List all categories
-// @Description List all categories
+// @Description List all categories, flat and nested under their parents
 // @Tags User
 // @Accept json
 // @Produce json
 // @Success 200 {object}  string
 // @Router /v1/ecommerce/list-category [get]
-func ListCategoryController(c *gin.Context) {
-	var categoryCollection *mongo.Collection = database.GetCollection(database.DB, constant.CategoryCollection)
-
-	var categories []types.Category
-
-	cursor, err := categoryCollection.Find(context.Background(), bson.D{})
+func (h *Handler) ListCategoryController(c *gin.Context) {
+	categories, err := h.db.Categories().List(c)
 
 	if err != nil {
 		c.JSON(400, gin.H{
@@ -72,16 +68,9 @@ func ListCategoryController(c *gin.Context) {
 		return
 	}
 
-	defer cursor.Close(context.Background())
-
-	for cursor.Next(context.Background()) {
-		var category types.Category
-		cursor.Decode(&category)
-		categories = append(categories, category)
-	}
-
 	c.JSON(200, gin.H{
 		"categories": categories,
+		"tree":       helper.CategoryTree(categories),
 	})
 }
 
@@ -91,17 +80,25 @@ func ListCategoryController(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Product ID"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
 // @Success 200 {object}  string
 // @Router /v1/ecommerce/product/{id} [get]
-func ListSingleProductController(c *gin.Context) {
-	Id := c.Param("id")
-
-	var productCollection *mongo.Collection = database.GetCollection(database.DB, constant.ProductCollection)
-
-	var product types.Product
+func (h *Handler) ListSingleProductController(c *gin.Context) {
+	product, err := h.productFromParam(c)
 
-	err := productCollection.FindOne(context.Background(), bson.D{{"id", Id}}).Decode(&product)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"message": constant.BadRequestMessage,
+		})
+		return
+	}
 
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+	prices, err := h.newPricer(c)
 	if err != nil {
 		c.JSON(400, gin.H{
 			"message": constant.BadRequestMessage,
@@ -109,8 +106,14 @@ func ListSingleProductController(c *gin.Context) {
 		return
 	}
 
+	priced, err := prices.priced([]types.Product{product})
+	if err != nil {
+		c.JSON(500, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
 	c.JSON(200, gin.H{
-		"product": product,
+		"product": convertProducts(conv, priced)[0],
 	})
 }
 
@@ -119,29 +122,14 @@ func ListSingleProductController(c *gin.Context) {
 // @Tags User
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
 // @Param id path string true "Product ID"
 // @Success 200 {object}  string
 // @Router /v1/ecommerce/product-link/:id [get]
-func GetProductLink(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-	// verified user
-	_, _, err := helper.VerifyToken(token)
-
-	if err != nil {
-		c.JSON(400, gin.H{
-			"message": err.Error(),
-		})
-		return
-	}
-
+func (h *Handler) GetProductLink(c *gin.Context) {
 	Id := c.Param("id")
-	frontend := os.Getenv("frontEndUrl")
+	frontend := h.cfg.FrontendURL
 	link := frontend + "/products/" + Id
 
 	c.JSON(200, gin.H{
@@ -151,7 +139,7 @@ func GetProductLink(c *gin.Context) {
 }
 
 // @Summary Give rating
-// @Description Give rating
+// @Description Give a product a rating from 1 to 5
 // @Tags User
 // @Accept json
 // @Produce json
@@ -161,43 +149,25 @@ func GetProductLink(c *gin.Context) {
 // @Param rating body types.Rating true "Rating"
 // @Success 200 {object}  string
 // @Router /v1/ecommerce/rating/{id} [post]
-func GiveRating(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-	// verified user
-	_, _, err := helper.VerifyToken(token)
+func (h *Handler) GiveRating(c *gin.Context) {
+	var rating types.Rating
+
+	err := c.ShouldBindJSON(&rating)
 
 	if err != nil {
 		c.JSON(400, gin.H{
-			"message": err.Error(),
+			"message": constant.BadRequestMessage,
 		})
 		return
 	}
-
-	Id := c.Param("id")
-
-	var rating types.Rating
-
-	err = c.ShouldBindJSON(&rating)
-
-	if err != nil {
+	if rating.Rating < constant.RatingMin || rating.Rating > constant.RatingMax {
 		c.JSON(400, gin.H{
-			"message": constant.BadRequestMessage,
+			"message": constant.RatingRangeError,
 		})
 		return
 	}
 
-	var productCollection *mongo.Collection = database.GetCollection(database.DB, constant.ProductCollection)
-
-	var product types.Product
-
-	err = productCollection.FindOne(context.Background(), bson.D{{"id", Id}}).Decode(&product)
-
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(400, gin.H{
 			"message": constant.BadRequestMessage,
@@ -205,10 +175,7 @@ func GiveRating(c *gin.Context) {
 		return
 	}
 
-	product.Rating = (product.Rating*float64(product.NumRating) + rating.Rating) / float64(product.NumRating+1)
-	product.NumRating = product.NumRating + 1
-
-	_, updateErr := productCollection.UpdateOne(c, bson.M{"id": Id}, bson.M{"$set": bson.M{"rating": product.Rating, "numrating": product.NumRating}})
+	updateErr := h.db.Products().AddRating(c, id, rating.Rating)
 	if updateErr != nil {
 		c.JSON(400, gin.H{
 			"message": constant.BadRequestMessage,
@@ -231,29 +198,10 @@ func GiveRating(c *gin.Context) {
 // @Param id path string true "Product ID"
 // @Success 200 {object}  string
 // @Router /v1/ecommerce/comment/{id} [post]
-func CommentOnProduct(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-	// verified user
-	_, _, err := helper.VerifyToken(token)
-
-	if err != nil {
-		c.JSON(400, gin.H{
-			"message": err.Error(),
-		})
-		return
-	}
-
-	Id := c.Param("id")
-
+func (h *Handler) CommentOnProduct(c *gin.Context) {
 	var comment types.Comment
 
-	err = c.ShouldBindJSON(&comment)
+	err := c.ShouldBindJSON(&comment)
 
 	if err != nil {
 		c.JSON(400, gin.H{
@@ -262,12 +210,13 @@ func CommentOnProduct(c *gin.Context) {
 		return
 	}
 
-	var productCollection *mongo.Collection = database.GetCollection(database.DB, constant.ProductCollection)
-
-	var product types.Product
-
-	err = productCollection.FindOne(context.Background(), bson.D{{"id", Id}}).Decode(&product)
+	principal, ok := h.principal(c)
+	if !ok {
+		return
+	}
+	comment.Email = principal.Email
 
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
 		c.JSON(400, gin.H{
 			"message": constant.BadRequestMessage,
@@ -275,9 +224,7 @@ func CommentOnProduct(c *gin.Context) {
 		return
 	}
 
-	output := append(product.Comments, comment)
-
-	_, updateErr := productCollection.UpdateOne(c, bson.M{"id": Id}, bson.M{"$set": bson.M{"comments": output}})
+	updateErr := h.db.Products().AddComment(c, id, comment)
 	if updateErr != nil {
 		c.JSON(400, gin.H{
 			"message": constant.BadRequestMessage,
@@ -301,9 +248,11 @@ func CommentOnProduct(c *gin.Context) {
 // @Param limit body int true "Limit"
 // @Param page body int true "Page"
 // @Param offset body int true "Offset"
+// @Param currency query string false "Currency to show prices in"
+// @Param Accept-Currency header string false "Currencies to show prices in, most wanted first"
 // @Success 200 {object}  string
 // @Router /v1/ecommerce/search [post]
-func SearchProductController(c *gin.Context) {
+func (h *Handler) SearchProductController(c *gin.Context) {
 	var reqSearch struct {
 		Search string `json:"search"`
 		Limit  int    `json:"limit"`
@@ -312,51 +261,31 @@ func SearchProductController(c *gin.Context) {
 	}
 
 	err := c.ShouldBindJSON(&reqSearch)
-	if err != nil {
+	if err != nil || reqSearch.Limit < 0 {
 		c.JSON(400, gin.H{
 			"message": constant.BadRequestMessage,
 		})
 		return
 	}
 
-	token := c.Request.Header.Get("Authorization")
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-	// verified user
-	_, _, errToken := helper.VerifyToken(token)
-	if errToken != nil {
-		c.JSON(400, gin.H{
-			"message": errToken.Error(),
-		})
-		return
-	}
-
 	skip := reqSearch.Limit * (reqSearch.Page - 1)
 	if reqSearch.Offset > 0 {
 		skip = reqSearch.Offset
 	}
+	if skip < 0 {
+		skip = 0
+	}
 
-	var productCollection *mongo.Collection = database.GetCollection(database.DB, constant.ProductCollection)
-
-	var products []types.Product
-
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(reqSearch.Limit))
-	findOptions.SetSkip(int64(skip))
-
-	searchFilter := bson.M{}
+	query := database.ProductQuery{
+		InStock: true,
+		Skip:    int64(skip),
+		Limit:   int64(reqSearch.Limit),
+	}
 	if len(reqSearch.Search) > 3 {
-		searchFilter["$or"] = []bson.M{
-			{"name": bson.M{"$regex": reqSearch.Search, "$options": "i"}},
-			{"description": bson.M{"$regex": reqSearch.Search, "$options": "i"}},
-		}
+		query.Search = reqSearch.Search
 	}
 
-	cursor, err := productCollection.Find(context.Background(), searchFilter, findOptions)
+	products, count, err := h.db.Products().Find(c, query)
 
 	if err != nil {
 		c.JSON(400, gin.H{
@@ -365,21 +294,41 @@ func SearchProductController(c *gin.Context) {
 		return
 	}
 
-	count, err := productCollection.CountDocuments(context.Background(), searchFilter)
-
-	defer cursor.Close(context.Background())
+	conv, ok := h.converter(c)
+	if !ok {
+		return
+	}
+	prices, err := h.newPricer(c)
+	if err != nil {
+		c.JSON(400, gin.H{
+			"message": constant.BadRequestMessage,
+		})
+		return
+	}
 
-	for cursor.Next(context.Background()) {
-		var product types.Product
-		cursor.Decode(&product)
-		if product.Stock > 0 {
-			products = append(products, product)
-		}
+	priced, err := prices.priced(products)
+	if err != nil {
+		c.JSON(500, gin.H{"error": true, "message": err.Error()})
+		return
 	}
 
 	c.JSON(200, gin.H{
-		"products": products,
+		"products": convertProducts(conv, priced),
 		"total":    count,
 	})
 
 }
+
+// productFromParam loads the product addressed by the :id path parameter.
+// Hidden and deleted products are not found.
+func (h *Handler) productFromParam(c *gin.Context) (types.Product, error) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return types.Product{}, err
+	}
+	product, err := h.db.Products().FindByID(c, id)
+	if err == nil && !product.Visible() {
+		return types.Product{}, database.ErrNotFound
+	}
+	return product, err
+}
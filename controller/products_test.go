@@ -0,0 +1,60 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+)
+
+func TestListSingleProduct(t *testing.T) {
+	s := newServer(t)
+	tea := s.product("Tea", 100, 5)
+
+	code, body := s.do(http.MethodGet, "/product/"+tea.ID.Hex()+"?currency=USD", "", nil)
+	if code != http.StatusOK {
+		t.Fatalf("get: %d %v", code, body)
+	}
+	if _, ok := body["price"]; ok {
+		t.Errorf("price next to the product in %v", body)
+	}
+	// 100 rupees at 0.012
+	if field(body, "product", "effective_price", "currency") != "USD" || field(body, "product", "effective_price", "amount") != float64(120) {
+		t.Errorf("effective price = %v, want 1.20 USD", field(body, "product", "effective_price"))
+	}
+}
+
+func TestSearchProductLimit(t *testing.T) {
+	s := newServer(t)
+	s.user("a@example.com", nil)
+	token, _ := s.login("a@example.com")
+	for _, name := range []string{"Green Tea", "Black Tea", "White Tea"} {
+		s.product(name, 100, 5)
+	}
+
+	tests := []struct {
+		name     string
+		limit    int
+		wantCode int
+		wantLen  int
+	}{
+		{name: "limited", limit: 2, wantCode: http.StatusOK, wantLen: 2},
+		{name: "no limit", limit: 0, wantCode: http.StatusOK, wantLen: 3},
+		{name: "negative", limit: -1, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := s.do(http.MethodPost, constant.SearchProductRoute, token, map[string]interface{}{"search": "", "limit": tt.limit, "page": 1})
+			if code != tt.wantCode {
+				t.Fatalf("search: %d %v, want %d", code, body, tt.wantCode)
+			}
+			if code != http.StatusOK {
+				return
+			}
+			products, _ := body["products"].([]interface{})
+			if len(products) != tt.wantLen {
+				t.Errorf("%d products, want %d: %v", len(products), tt.wantLen, body)
+			}
+		})
+	}
+}
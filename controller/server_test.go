@@ -0,0 +1,182 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/config"
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/exchange"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/mailer"
+	"github.com/PiehTVH/go-ecommerce/payment"
+	"github.com/PiehTVH/go-ecommerce/router"
+	"github.com/PiehTVH/go-ecommerce/tax"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+)
+
+const api = "/v1/ecommerce"
+
+// outbox keeps every message the API sends.
+type outbox struct {
+	sent []mailer.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg mailer.Message) error {
+	o.sent = append(o.sent, msg)
+	return nil
+}
+
+// server is the API over the memory store.
+type server struct {
+	t    *testing.T
+	http http.Handler
+	db   database.Manager
+	mail *outbox
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	helper.SetTokenSecret("test-secret")
+	helper.SetPasswordHashing(helper.PasswordHashing{Algorithm: "bcrypt", BcryptCost: 10})
+
+	db := database.NewMemoryManager()
+	mail := &outbox{}
+	pay := payment.NewGateway(payment.NewFake("whsec_test"), payment.NewCashOnDelivery())
+	rates := exchange.NewStatic(exchange.Table{Base: "INR", Rates: map[string]*big.Rat{"USD": big.NewRat(12, 1000)}})
+	engine, err := router.New(config.Config{APIVersion: "v1", FrontendURL: "http://localhost", Currency: "INR"}, db, mail, pay, rates, tax.NewRules(db.TaxRules()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &server{t: t, http: engine, db: db, mail: mail}
+}
+
+// do sends body as JSON to the route and decodes the answer. token is the
+// access token of the caller, empty for none.
+func (s *server) do(method, route, token string, body interface{}, header ...string) (int, map[string]interface{}) {
+	s.t.Helper()
+	raw, err := json.Marshal(body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, api+route, bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.http.ServeHTTP(rec, req)
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		s.t.Fatalf("%s %s: decoding %q: %v", method, route, rec.Body.String(), err)
+	}
+	return rec.Code, out
+}
+
+// user stores a verified customer with the password "secret-pw1".
+func (s *server) user(email string, edit func(*types.User)) types.User {
+	s.t.Helper()
+	hash, err := helper.EncryptPassword("secret-pw1")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	user := types.User{Email: email, Password: hash, UserType: "user", Verified: true}
+	if edit != nil {
+		edit(&user)
+	}
+	if err := s.db.Users().Create(context.Background(), &user); err != nil {
+		s.t.Fatal(err)
+	}
+	return user
+}
+
+// login signs email in and returns its access and refresh token.
+func (s *server) login(email string) (string, string) {
+	s.t.Helper()
+	code, body := s.do(http.MethodPost, constant.UserLoginRoute, "", map[string]string{"email": email, "password": "secret-pw1"})
+	if code != http.StatusOK {
+		s.t.Fatalf("login %s: %d %v", email, code, body)
+	}
+	return body["token"].(string), body["refresh_token"].(string)
+}
+
+// admin stores an admin with two factor authentication and signs them in,
+// once per server since an authenticator code only works once.
+func (s *server) admin(email string) string {
+	s.t.Helper()
+	secret, err := helper.NewTOTPSecret()
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.user(email, func(u *types.User) {
+		u.UserType = constant.AdminUser
+		u.TotpEnabled, u.TotpSecret = true, secret
+	})
+
+	_, body := s.do(http.MethodPost, constant.UserLoginRoute, "", map[string]string{"email": email, "password": "secret-pw1"})
+	code, err := helper.TOTPCode(secret, time.Now().Unix()/30)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	status, body := s.do(http.MethodPost, constant.TwoFactorLoginRoute, "", map[string]interface{}{"challenge_token": body["challenge_token"], "code": code})
+	if status != http.StatusOK {
+		s.t.Fatalf("admin login %s: %d %v", email, status, body)
+	}
+	return body["token"].(string)
+}
+
+// product stores a product priced in rupees.
+func (s *server) product(name string, rupees int64, stock int) types.Product {
+	s.t.Helper()
+	product := types.Product{Name: name, Price: types.Money{Amount: rupees * 100, Currency: "INR"}, Stock: stock}
+	if err := s.db.Products().Create(context.Background(), &product); err != nil {
+		s.t.Fatal(err)
+	}
+	return product
+}
+
+// address gives email a default address in Bengaluru.
+func (s *server) address(email string) types.Address {
+	s.t.Helper()
+	address := types.Address{
+		Email: email,
+		PostalAddress: types.PostalAddress{
+			Name: "A Customer", Lines: []string{"1 MG Road"}, City: "Bengaluru",
+			Region: "KA", PostalCode: "560001", Country: "IN", Phone: "+919999999999",
+		},
+		DefaultShipping: true,
+		DefaultBilling:  true,
+	}
+	if err := s.db.Addresses().Create(context.Background(), &address); err != nil {
+		s.t.Fatal(err)
+	}
+	return address
+}
+
+// field walks the decoded answer along keys.
+func field(body map[string]interface{}, keys ...string) interface{} {
+	var v interface{} = body
+	for _, key := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
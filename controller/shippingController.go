@@ -0,0 +1,439 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/exchange"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/pricing"
+	"github.com/PiehTVH/go-ecommerce/shipping"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// shippingOptions lists the methods able to ship cart to country, cheapest
+// first.
+func (h *Handler) shippingOptions(c *gin.Context, cart *pricing.Cart, country string) error {
+	cart.ShippingOptions = []types.ShippingOption{}
+	methods, err := h.db.ShippingMethods().List(c)
+	if err != nil || len(methods) == 0 {
+		return err
+	}
+	zones, err := h.db.ShippingZones().List(c)
+	if err != nil {
+		return err
+	}
+
+	value, err := cart.Subtotal.Sub(cart.Discount)
+	if err != nil {
+		return err
+	}
+	weight, volume := cart.Parcel()
+	cart.ShippingRequired = true
+	cart.ShippingOptions, err = shipping.Options(zones, methods, shipping.Parcel{
+		Country: country,
+		Weight:  weight,
+		Volume:  volume,
+		Value:   value,
+	})
+	return err
+}
+
+// chooseShipping ships cart with the method picked at checkout and
+// returns it, an empty option when the store has no shipping methods. It
+// writes the error response itself, with the options in the currency of
+// conv.
+func chooseShipping(c *gin.Context, conv exchange.Converter, cart *pricing.Cart, methodID string) (types.ShippingOption, bool) {
+	if !cart.ShippingRequired {
+		return types.ShippingOption{}, true
+	}
+
+	shown, ok := convertCart(c, conv, *cart)
+	if !ok {
+		return types.ShippingOption{}, false
+	}
+	options := shown.ShippingOptions
+	switch {
+	case len(options) == 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.NoShippingMethodError})
+		return types.ShippingOption{}, false
+	case methodID == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.ShippingMethodRequiredError, "shipping_options": options})
+		return types.ShippingOption{}, false
+	}
+
+	for _, option := range cart.ShippingOptions {
+		if option.MethodID == methodID {
+			if err := cart.ChooseShipping(option); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+				return types.ShippingOption{}, false
+			}
+			return option, true
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.ShippingMethodError, "shipping_options": options})
+	return types.ShippingOption{}, false
+}
+
+// validShippingZoneData normalizes the countries of the payload and checks
+// that no other zone than self serves them. It writes the error response
+// itself.
+func (h *Handler) validShippingZoneData(c *gin.Context, data *types.ShippingZoneData, self primitive.ObjectID) bool {
+	countries, err := helper.NormalizeCountries(data.Countries)
+	if err == nil && strings.TrimSpace(data.Name) == "" {
+		err = errors.New("name can't be empty")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+	data.Countries = countries
+
+	zones, err := h.db.ShippingZones().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+	for _, zone := range zones {
+		if zone.ID == self {
+			continue
+		}
+		// only one zone may serve the countries no zone lists
+		overlap := len(zone.Countries) == 0 && len(countries) == 0
+		for _, country := range zone.Countries {
+			for _, wanted := range countries {
+				overlap = overlap || country == wanted
+			}
+		}
+		if overlap {
+			c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.ShippingZoneOverlapError, "zone": zone.Name})
+			return false
+		}
+	}
+	return true
+}
+
+// @Summary Add Shipping Zone
+// @Description Add a zone of countries served by the same shipping methods by admin. A zone without countries serves every country no other zone lists.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param zone body types.ShippingZoneData true "Shipping zone"
+// @Success 200 {object}  types.ShippingZone
+// @Router /v1/ecommerce/shipping-zone [post]
+func (h *Handler) AddShippingZone(c *gin.Context) {
+	var data types.ShippingZoneData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validShippingZoneData(c, &data, primitive.NilObjectID) {
+		return
+	}
+
+	now := time.Now().Unix()
+	zone := types.ShippingZone{
+		Name:      strings.TrimSpace(data.Name),
+		Countries: data.Countries,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.db.ShippingZones().Create(c, &zone); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": zone})
+}
+
+// @Summary Update Shipping Zone
+// @Description Change the name or the countries of a shipping zone by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Shipping zone ID"
+// @Param zone body types.ShippingZoneData true "Shipping zone"
+// @Success 200 {object}  types.ShippingZone
+// @Router /v1/ecommerce/shipping-zone/{id} [put]
+func (h *Handler) UpdateShippingZone(c *gin.Context) {
+	var data types.ShippingZoneData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ShippingZoneNotFoundError})
+		return
+	}
+	zone, err := h.db.ShippingZones().FindByID(c, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ShippingZoneNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validShippingZoneData(c, &data, zone.ID) {
+		return
+	}
+
+	zone.Name = strings.TrimSpace(data.Name)
+	zone.Countries = data.Countries
+	zone.UpdatedAt = time.Now().Unix()
+	if err := h.db.ShippingZones().Update(c, zone); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": zone})
+}
+
+// @Summary Delete Shipping Zone
+// @Description Delete a shipping zone without shipping methods by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Shipping zone ID"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/shipping-zone/{id} [delete]
+func (h *Handler) DeleteShippingZone(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ShippingZoneNotFoundError})
+		return
+	}
+
+	methods, err := h.db.ShippingMethods().ListByZone(c, id.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if len(methods) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.ShippingZoneHasMethodsError})
+		return
+	}
+
+	err = h.db.ShippingZones().Delete(c, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ShippingZoneNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary List Shipping Zones
+// @Description List every shipping zone by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/shipping-zone [get]
+func (h *Handler) ListShippingZones(c *gin.Context) {
+	zones, err := h.db.ShippingZones().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": zones})
+}
+
+// validShippingMethodData checks the payload and that its zone exists. It
+// writes the error response itself.
+func (h *Handler) validShippingMethodData(c *gin.Context, data *types.ShippingMethodData) bool {
+	data.Price = data.Price.DefaultCurrency(h.cfg.Currency)
+	data.FreeOver = data.FreeOver.DefaultCurrency(h.cfg.Currency)
+	for i := range data.Rates {
+		data.Rates[i].Price = data.Rates[i].Price.DefaultCurrency(h.cfg.Currency)
+	}
+	if err := helper.CheckShippingMethodValidation(*data, h.cfg.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+
+	zoneId, _ := primitive.ObjectIDFromHex(data.ZoneId)
+	if _, err := h.db.ShippingZones().FindByID(c, zoneId); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.ShippingZoneNotFoundError})
+		return false
+	}
+	return true
+}
+
+// @Summary Add Shipping Method
+// @Description Add a shipping method to a zone by admin. A flat method costs price, a free over threshold one costs price below free_over and nothing from it on, a weight based one costs the first of its rates whose up_to, in grams, covers the order.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param method body types.ShippingMethodData true "Shipping method"
+// @Success 200 {object}  types.ShippingMethod
+// @Router /v1/ecommerce/shipping-method [post]
+func (h *Handler) AddShippingMethod(c *gin.Context) {
+	var data types.ShippingMethodData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validShippingMethodData(c, &data) {
+		return
+	}
+
+	now := time.Now().Unix()
+	method := types.ShippingMethod{
+		ZoneId:            data.ZoneId,
+		Name:              strings.TrimSpace(data.Name),
+		Type:              data.Type,
+		Price:             data.Price,
+		FreeOver:          data.FreeOver,
+		Rates:             data.Rates,
+		VolumetricDivisor: data.VolumetricDivisor,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := h.db.ShippingMethods().Create(c, &method); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": method})
+}
+
+// @Summary Update Shipping Method
+// @Description Change a shipping method by admin, orders already placed keep what they paid
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Shipping method ID"
+// @Param method body types.ShippingMethodData true "Shipping method"
+// @Success 200 {object}  types.ShippingMethod
+// @Router /v1/ecommerce/shipping-method/{id} [put]
+func (h *Handler) UpdateShippingMethod(c *gin.Context) {
+	var data types.ShippingMethodData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ShippingMethodNotFoundError})
+		return
+	}
+	method, err := h.db.ShippingMethods().FindByID(c, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ShippingMethodNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validShippingMethodData(c, &data) {
+		return
+	}
+
+	method.ZoneId = data.ZoneId
+	method.Name = strings.TrimSpace(data.Name)
+	method.Type = data.Type
+	method.Price = data.Price
+	method.FreeOver = data.FreeOver
+	method.Rates = data.Rates
+	method.VolumetricDivisor = data.VolumetricDivisor
+	method.UpdatedAt = time.Now().Unix()
+	if err := h.db.ShippingMethods().Update(c, method); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": method})
+}
+
+// @Summary Delete Shipping Method
+// @Description Delete a shipping method by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Shipping method ID"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/shipping-method/{id} [delete]
+func (h *Handler) DeleteShippingMethod(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ShippingMethodNotFoundError})
+		return
+	}
+
+	err = h.db.ShippingMethods().Delete(c, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.ShippingMethodNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary List Shipping Methods
+// @Description List every shipping method by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/shipping-method [get]
+func (h *Handler) ListShippingMethods(c *gin.Context) {
+	methods, err := h.db.ShippingMethods().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": methods})
+}
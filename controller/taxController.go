@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/pricing"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// taxRegion returns where the carts of email are taxed and shipped to,
+// the default shipping address, nowhere when the address book is empty.
+func (h *Handler) taxRegion(c *gin.Context, email string) (types.TaxRegion, error) {
+	book, err := h.db.Addresses().ListByEmail(c, email)
+	if err != nil {
+		return types.TaxRegion{}, err
+	}
+	address, _ := defaultAddress(book, false)
+	return address.TaxRegion(), nil
+}
+
+// applyTax asks the tax calculator about the lines of cart shipped to
+// region and adds the tax to it.
+func (h *Handler) applyTax(c *gin.Context, cart *pricing.Cart, region types.TaxRegion) error {
+	cart.TaxRegion = region
+	taxes, err := h.taxes.Tax(c, pricing.TaxRequest{
+		Region:    region,
+		Currency:  h.cfg.Currency,
+		Inclusive: h.cfg.PricesIncludeTax,
+		Lines:     cart.Lines,
+	})
+	if err != nil {
+		return err
+	}
+	return cart.ApplyTax(taxes, h.cfg.PricesIncludeTax)
+}
+
+// validTaxRuleData normalizes the payload and checks it, along with the
+// category the rule is limited to. It writes the error response itself.
+func (h *Handler) validTaxRuleData(c *gin.Context, data *types.TaxRuleData) bool {
+	region, err := helper.NormalizeTaxRegion(data.Country, data.Region)
+	if err == nil {
+		data.Country, data.Region = region.Country, region.Region
+		err = helper.CheckTaxRuleValidation(*data)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+
+	if data.CategoryId != "" {
+		categoryId, _ := primitive.ObjectIDFromHex(data.CategoryId)
+		if _, err := h.db.Categories().FindByID(c, categoryId); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.CategoryNotFoundError})
+			return false
+		}
+	}
+	return true
+}
+
+// @Summary Add Tax Rule
+// @Description Add the tax rate of a country or one of its regions, for every product or for a category and its subcategories, by admin. The rate is in hundredths of a percent.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param rule body types.TaxRuleData true "Tax rule"
+// @Success 200 {object}  types.TaxRule
+// @Router /v1/ecommerce/tax-rule [post]
+func (h *Handler) AddTaxRule(c *gin.Context) {
+	var data types.TaxRuleData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validTaxRuleData(c, &data) {
+		return
+	}
+
+	now := time.Now().Unix()
+	rule := types.TaxRule{
+		Name:       strings.TrimSpace(data.Name),
+		Country:    data.Country,
+		Region:     data.Region,
+		CategoryId: data.CategoryId,
+		Rate:       data.Rate,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := h.db.TaxRules().Create(c, &rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": rule})
+}
+
+// @Summary Update Tax Rule
+// @Description Change a tax rule by admin, orders already placed keep their tax
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Tax rule ID"
+// @Param rule body types.TaxRuleData true "Tax rule"
+// @Success 200 {object}  types.TaxRule
+// @Router /v1/ecommerce/tax-rule/{id} [put]
+func (h *Handler) UpdateTaxRule(c *gin.Context) {
+	var data types.TaxRuleData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.TaxRuleNotFoundError})
+		return
+	}
+	rule, err := h.db.TaxRules().FindByID(c, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.TaxRuleNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	if !h.validTaxRuleData(c, &data) {
+		return
+	}
+
+	rule.Name = strings.TrimSpace(data.Name)
+	rule.Country = data.Country
+	rule.Region = data.Region
+	rule.CategoryId = data.CategoryId
+	rule.Rate = data.Rate
+	rule.UpdatedAt = time.Now().Unix()
+	if err := h.db.TaxRules().Update(c, rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": rule})
+}
+
+// @Summary Delete Tax Rule
+// @Description Delete a tax rule by admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param id path string true "Tax rule ID"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/tax-rule/{id} [delete]
+func (h *Handler) DeleteTaxRule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.TaxRuleNotFoundError})
+		return
+	}
+
+	err = h.db.TaxRules().Delete(c, id)
+	if errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": true, "message": constant.TaxRuleNotFoundError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary List Tax Rules
+// @Description List every tax rule by admin, along with whether prices include tax
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/tax-rule [get]
+func (h *Handler) ListTaxRules(c *gin.Context) {
+	rules, err := h.db.TaxRules().List(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": rules, "prices_include_tax": h.cfg.PricesIncludeTax})
+}
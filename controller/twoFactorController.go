@@ -0,0 +1,421 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+)
+
+// signIn finishes a sign in once the password or the email otp checked
+// out. Accounts with two factor authentication get a challenge instead of
+// tokens, admins without it have to enroll first.
+func (h *Handler) signIn(c *gin.Context, dbUser types.User) {
+	if dbUser.TotpEnabled || dbUser.UserType == constant.AdminUser {
+		h.challenge(c, dbUser)
+		return
+	}
+
+	tokens, err := h.startSession(c, dbUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
+		return
+	}
+
+	dbUser.Password = ""
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": dbUser, "token": tokens.AccessToken, "refresh_token": tokens.RefreshToken, "expires_in": tokens.ExpiresIn})
+}
+
+func (h *Handler) challenge(c *gin.Context, dbUser types.User) {
+	challengeToken, err := helper.GenerateChallengeToken(dbUser.Id.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
+		return
+	}
+
+	if dbUser.TotpEnabled {
+		c.JSON(http.StatusOK, gin.H{"error": false, "message": constant.TwoFactorRequiredMessage, "two_factor_required": true, "challenge_token": challengeToken})
+		return
+	}
+
+	// mandatory enrollment, the first valid code turns it on
+	if dbUser.TotpPendingSecret == "" {
+		secret, err := helper.NewTOTPSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+		dbUser.TotpPendingSecret = secret
+		dbUser.UpdatedAt = time.Now().Unix()
+		if err := h.db.Users().Update(c, dbUser); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"error":               false,
+		"message":             constant.TwoFactorEnrollMessage,
+		"two_factor_required": true,
+		"enrollment_required": true,
+		"challenge_token":     challengeToken,
+		"secret":              dbUser.TotpPendingSecret,
+		"otpauth_url":         helper.TOTPProvisioningURI(dbUser.Email, dbUser.TotpPendingSecret),
+	})
+}
+
+// checkSecondFactor validates an authenticator or recovery code of a user
+// with two factor authentication against secret, counting wrong codes
+// towards a lockout. A used code is consumed in the store and on dbUser,
+// so of two requests with the same code only one gets through. It writes
+// the error response itself and returns false on failure.
+func (h *Handler) checkSecondFactor(c *gin.Context, dbUser *types.User, secret string, code string) bool {
+	key := helper.TwoFactorThrottleKey(dbUser.Id.Hex())
+	now := time.Now()
+
+	throttle, err := h.db.LoginThrottles().Get(c, key)
+	stale := err == nil && helper.ThrottleExpired(throttle, now.Unix())
+	if err == nil && !stale {
+		if lockedUntil := helper.LockedUntil(throttle, constant.TwoFactorMaxFailures); lockedUntil > now.Unix() {
+			c.Header("Retry-After", strconv.FormatInt(lockedUntil-now.Unix(), 10))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": true, "message": constant.TooManyLoginAttemptsError})
+			return false
+		}
+	}
+
+	var used bool
+	var useErr error
+	if step, ok := helper.ValidateTOTP(secret, code, now, dbUser.TotpLastStep); ok {
+		useErr = h.db.Users().UseTOTPStep(c, dbUser.Id, step)
+		if used = useErr == nil; used {
+			dbUser.TotpLastStep = step
+		}
+	} else if i := recoveryCodeIndex(*dbUser, code); i >= 0 {
+		useErr = h.db.Users().UseRecoveryCode(c, dbUser.Id, dbUser.RecoveryCodes[i])
+		if used = useErr == nil; used {
+			dbUser.RecoveryCodes = append(dbUser.RecoveryCodes[:i:i], dbUser.RecoveryCodes[i+1:]...)
+		}
+	}
+	// ErrNotFound means another request used the code first
+	if useErr != nil && !errors.Is(useErr, database.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": useErr.Error()})
+		return false
+	}
+
+	if !used {
+		if stale {
+			h.db.LoginThrottles().Reset(c, key)
+		}
+		if _, err := h.db.LoginThrottles().Fail(c, key, now.Unix()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return false
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidTwoFactorCodeError})
+		return false
+	}
+
+	if err := h.db.LoginThrottles().Reset(c, key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return false
+	}
+	return true
+}
+
+func recoveryCodeIndex(dbUser types.User, code string) int {
+	if code == "" {
+		return -1
+	}
+	hash := helper.HashRecoveryCode(code)
+	for i, stored := range dbUser.RecoveryCodes {
+		if stored == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// enableTwoFactor turns on the pending secret of dbUser and returns fresh
+// recovery codes.
+func enableTwoFactor(dbUser *types.User) ([]string, error) {
+	codes, hashes, err := helper.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	dbUser.TotpEnabled = true
+	dbUser.TotpSecret = dbUser.TotpPendingSecret
+	dbUser.TotpPendingSecret = ""
+	dbUser.RecoveryCodes = hashes
+	dbUser.UpdatedAt = time.Now().Unix()
+	return codes, nil
+}
+
+// @Summary Two factor login
+// @Description Exchange the challenge token from login and an authenticator or recovery code for a token pair. Admins enrolling for the first time get their recovery codes here.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param login body types.TwoFactorLogin true "Challenge and code"
+// @Success 200 {object}  string
+// @Failure 401 {object}  string
+// @Router /v1/ecommerce/login-2fa [post]
+func (h *Handler) TwoFactorLogin(c *gin.Context) {
+	var req types.TwoFactorLogin
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	userId, err := helper.VerifyChallengeToken(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidChallengeTokenError})
+		return
+	}
+
+	dbUser, err := h.db.Users().FindByID(c, userId)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidChallengeTokenError})
+		return
+	}
+	if dbUser.IsBlocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": true, "message": constant.UserBlockedError})
+		return
+	}
+
+	var recoveryCodes []string
+	switch {
+	case dbUser.TotpEnabled:
+		// the code is consumed by the check, nothing else changes
+		if !h.checkSecondFactor(c, &dbUser, dbUser.TotpSecret, req.Code) {
+			return
+		}
+	case dbUser.UserType == constant.AdminUser && dbUser.TotpPendingSecret != "":
+		// recovery codes do not exist yet, so only the app code counts
+		if !h.checkSecondFactor(c, &dbUser, dbUser.TotpPendingSecret, req.Code) {
+			return
+		}
+		if recoveryCodes, err = enableTwoFactor(&dbUser); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+		if err := h.db.Users().Update(c, dbUser); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+	default:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidChallengeTokenError})
+		return
+	}
+
+	tokens, err := h.startSession(c, dbUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
+		return
+	}
+
+	dbUser.Password = ""
+
+	response := gin.H{"error": false, "message": "success", "data": dbUser, "token": tokens.AccessToken, "refresh_token": tokens.RefreshToken, "expires_in": tokens.ExpiresIn}
+	if recoveryCodes != nil {
+		response["recovery_codes"] = recoveryCodes
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Start two factor setup
+// @Description Create an authenticator secret and its provisioning URI. It is only used after being confirmed with a code.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/2fa/setup [post]
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	dbUser, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if dbUser.TotpEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.TwoFactorAlreadyEnabledError})
+		return
+	}
+
+	secret, err := helper.NewTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	dbUser.TotpPendingSecret = secret
+	dbUser.UpdatedAt = time.Now().Unix()
+	if err := h.db.Users().Update(c, dbUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "secret": secret, "otpauth_url": helper.TOTPProvisioningURI(dbUser.Email, secret)})
+}
+
+// @Summary Enable two factor
+// @Description Confirm the setup with a code from the authenticator app. The recovery codes are only shown once.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param code body types.TwoFactorCode true "Code"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/2fa/enable [post]
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	var req types.TwoFactorCode
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	dbUser, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if dbUser.TotpEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": true, "message": constant.TwoFactorAlreadyEnabledError})
+		return
+	}
+	if dbUser.TotpPendingSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.TwoFactorSetupRequiredError})
+		return
+	}
+
+	if !h.checkSecondFactor(c, &dbUser, dbUser.TotpPendingSecret, req.Code) {
+		return
+	}
+
+	recoveryCodes, err := enableTwoFactor(&dbUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if err := h.db.Users().Update(c, dbUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "recovery_codes": recoveryCodes})
+}
+
+// @Summary Disable two factor
+// @Description Turn two factor authentication off. Admin accounts must keep it.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param disable body types.DisableTwoFactor true "Password and code"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/2fa/disable [post]
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	var req types.DisableTwoFactor
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	dbUser, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !dbUser.TotpEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.TwoFactorNotEnabledError})
+		return
+	}
+	if dbUser.UserType == constant.AdminUser {
+		c.JSON(http.StatusForbidden, gin.H{"error": true, "message": constant.TwoFactorMandatoryError})
+		return
+	}
+	if !helper.ComparePassword(dbUser.Password, req.Password) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.PasswordNotMatchedError})
+		return
+	}
+	if !h.checkSecondFactor(c, &dbUser, dbUser.TotpSecret, req.Code) {
+		return
+	}
+
+	dbUser.TotpEnabled = false
+	dbUser.TotpSecret = ""
+	dbUser.TotpPendingSecret = ""
+	dbUser.RecoveryCodes = nil
+	dbUser.UpdatedAt = time.Now().Unix()
+	if err := h.db.Users().Update(c, dbUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+}
+
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes, the old ones stop working
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Param code body types.TwoFactorCode true "Code"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/2fa/recovery-codes [post]
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req types.TwoFactorCode
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	dbUser, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !dbUser.TotpEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.TwoFactorNotEnabledError})
+		return
+	}
+	if !h.checkSecondFactor(c, &dbUser, dbUser.TotpSecret, req.Code) {
+		return
+	}
+
+	recoveryCodes, hashes, err := helper.NewRecoveryCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	dbUser.RecoveryCodes = hashes
+	dbUser.UpdatedAt = time.Now().Unix()
+	if err := h.db.Users().Update(c, dbUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "recovery_codes": recoveryCodes})
+}
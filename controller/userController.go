@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/PiehTVH/go-ecommerce/constant"
@@ -10,9 +13,7 @@ import (
 	"github.com/PiehTVH/go-ecommerce/types"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type JwtClaim struct {
@@ -30,17 +31,20 @@ type JwtClaim struct {
 // @Success		200	{object}	string
 // @Failure		500	{object}	string
 // @Router			/v1/ecommerce/signup [post]
-func RegisterUser(c *gin.Context) {
+func (h *Handler) RegisterUser(c *gin.Context) {
 	var userClient types.UserClient
-	var dbUser types.User
 
 	defer c.Request.Body.Close()
 	// binding the request body to userClient
 	reqErr := c.ShouldBindJSON(&userClient)
 	if reqErr != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": reqErr.Error()})
+		return
 	}
 
+	// accounts are told apart by their lower cased email
+	userClient.Email = helper.NormalizeEmail(userClient.Email)
+
 	// checking the payload
 	err := helper.CheckUserValidation(userClient)
 	if err != nil {
@@ -48,50 +52,57 @@ func RegisterUser(c *gin.Context) {
 		return
 	}
 
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-
 	// checking if email is unique
-	emailExists := userCollection.FindOne(c, bson.M{"email": userClient.Email}).Decode(&dbUser)
+	_, emailExists := h.db.Users().FindByEmail(c, userClient.Email)
 	if emailExists == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email already exists"})
 		return
 	}
 
+	password, err := helper.EncryptPassword(userClient.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
 	// creating the user object
-	dbUser = types.User{
+	dbUser := types.User{
 		Name:      userClient.Name,
 		Email:     userClient.Email,
 		Phone:     userClient.Phone,
-		Password:  helper.EncryptPassword(userClient.Password),
+		Password:  password,
 		UserType:  "user",
 		IsBlocked: false,
-		Address:   "",
+		Verified:  false,
 		Favourite: []string{},
 		CreatedAt: time.Now().Unix(),
 		UpdatedAt: time.Now().Unix(),
 		Id:        primitive.NewObjectID(),
 	}
 
-	_, insertErr := userCollection.InsertOne(c, dbUser)
+	insertErr := h.db.Users().Create(c, &dbUser)
+	if errors.Is(insertErr, database.ErrDuplicate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email already exists"})
+		return
+	}
 	if insertErr != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": insertErr.Error()})
 		return
 	}
 
-	// jwt token
-	token, err := helper.GenerateToken(dbUser.Id.Hex(), dbUser.Email, dbUser.UserType)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
+	dbUser.Password = ""
+
+	// the account can only sign in once the otp has been verified
+	if err := h.sendOtp(c, dbUser.Email); err != nil {
+		c.JSON(http.StatusOK, gin.H{"error": false, "message": "registered, but the otp could not be sent. please request a new one", "data": dbUser})
 		return
 	}
 
-	dbUser.Password = ""
-
-	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": dbUser, "token": token})
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": constant.OtpSentMessage, "data": dbUser})
 }
 
 // Login
-func UserLogin(c *gin.Context) {
+func (h *Handler) UserLogin(c *gin.Context) {
 	var loginReq types.Login
 
 	defer c.Request.Body.Close()
@@ -103,154 +114,86 @@ func UserLogin(c *gin.Context) {
 		return
 	}
 
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-	var dbUser types.User
+	loginReq.Email = helper.NormalizeEmail(loginReq.Email)
 
-	// checking if email exists
-	emailExists := userCollection.FindOne(c, bson.M{"email": loginReq.Email}).Decode(&dbUser)
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email not found"})
+	// refusing while the account or the client is locked out
+	lockedUntil, err := h.loginLockedUntil(c, loginReq.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
 		return
 	}
-
-	// checking the password
-	if !helper.ComparePassword(dbUser.Password, loginReq.Password) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "password not matched"})
+	if lockedUntil > 0 {
+		c.Header("Retry-After", strconv.FormatInt(lockedUntil-time.Now().Unix(), 10))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": true, "message": constant.TooManyLoginAttemptsError})
 		return
 	}
 
-	// jwt token
-	token, err := helper.GenerateToken(dbUser.Id.Hex(), dbUser.Email, dbUser.UserType)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
-		return
+	// checking the credentials, an unknown email costs the same as a wrong password
+	dbUser, emailErr := h.db.Users().FindByEmail(c, loginReq.Email)
+	reason := ""
+	if emailErr != nil {
+		helper.SimulatePasswordCheck(loginReq.Password)
+		reason = "unknown email"
+	} else if !helper.ComparePassword(dbUser.Password, loginReq.Password) {
+		reason = "wrong password"
 	}
 
-	dbUser.Password = ""
-
-	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": dbUser, "token": token})
-}
-
-func SignOut(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
-}
-
-func AddAddress(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
+	if reason != "" {
+		if err := h.loginFailed(c, loginReq.Email, reason); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.PasswordNotMatchedError})
 		return
 	}
 
-	// verified token
-	_, _, err := helper.VerifyToken(token)
-	if err != nil {
-		c.JSON(400, gin.H{
-			"message": err.Error(),
-		})
+	if err := h.db.LoginThrottles().Reset(c, helper.AccountThrottleKey(loginReq.Email)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
 		return
 	}
 
-	var addAddress types.AddressData
-	defer c.Request.Body.Close()
-
-	// binding the request body to address
-	reqErr := c.ShouldBindJSON(&addAddress)
-	if reqErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": reqErr.Error()})
-		return
+	// the plain password is only at hand now, so outdated hashes are
+	// upgraded here. Only the hash is written and only while it is the one
+	// checked, a password changed meanwhile is kept. A failure keeps the
+	// old hash, which still works.
+	if helper.NeedsRehash(dbUser.Password) {
+		if password, err := helper.EncryptPassword(loginReq.Password); err != nil {
+			log.Printf("rehashing password of %s: %v", dbUser.Email, err)
+		} else if err := h.db.Users().UpdatePassword(c, dbUser.Id, dbUser.Password, password); err != nil {
+			log.Printf("rehashing password of %s: %v", dbUser.Email, err)
+		}
 	}
 
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-	var dbUser types.User
-
-	// checking if user exists
-	emailExists := userCollection.FindOne(c, bson.M{"email": addAddress.Email}).Decode(&dbUser)
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email not found"})
+	if dbUser.IsBlocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": true, "message": constant.UserBlockedError})
 		return
 	}
 
-	// updating the address
-	_, updateErr := userCollection.UpdateOne(c,
-		bson.M{"email": addAddress.Email},
-		bson.M{"$set": bson.M{"address": addAddress.Address}})
-	if updateErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": updateErr.Error()})
+	if !dbUser.Verified {
+		c.JSON(http.StatusForbidden, gin.H{"error": true, "message": constant.EmailIsNotVerified})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+	// jwt token, or a second factor challenge
+	h.signIn(c, dbUser)
 }
 
-func EditAddress(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
+func (h *Handler) SignOut(c *gin.Context) {
+	principal, ok := h.principal(c)
+	if !ok {
 		return
 	}
 
-	// Verified user
-	_, _, err := helper.VerifyToken(token)
+	err := h.db.Sessions().Revoke(c, principal.SessionID, constant.RevokedLogout, time.Now().Unix())
 	if err != nil {
-		c.JSON(400, gin.H{
-			"message": err.Error(),
-		})
-		return
-	}
-
-	var editAddress types.AddressData
-
-	defer c.Request.Body.Close()
-
-	// binding the request body to address
-	reqErr := c.ShouldBindJSON(&editAddress)
-	if reqErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": reqErr.Error()})
-		return
-	}
-
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-
-	var dbUser types.User
-
-	// checking if user exists
-	emailExists := userCollection.FindOne(c, bson.M{"email": editAddress.Email}).Decode(&dbUser)
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email not found"})
-		return
-	}
-
-	// updating the address
-	_, updateErr := userCollection.UpdateOne(c, bson.M{"email": editAddress.Email}, bson.M{"$set": bson.M{"address": editAddress.Address}})
-	if updateErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": updateErr.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
 }
 
-func UpdateUser(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-
-	// verified user
-	_, _, err := helper.VerifyToken(token)
-	if err != nil {
-		c.JSON(400, gin.H{"message": err.Error()})
-		return
-	}
-
+func (h *Handler) UpdateUser(c *gin.Context) {
 	var updatePassword types.UpdatePassword
 
 	defer c.Request.Body.Close()
@@ -262,13 +205,8 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-	var dbUser types.User
-
-	// checking if user exists
-	emailExists := userCollection.FindOne(c, bson.M{"email": updatePassword.Email}).Decode(&dbUser)
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email not found"})
+	dbUser, ok := h.currentUser(c)
+	if !ok {
 		return
 	}
 
@@ -278,36 +216,41 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if err := helper.ValidatePassword(updatePassword.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
 	// updating the password
-	_, updateErr := userCollection.UpdateOne(c, bson.M{"email": updatePassword.Email}, bson.M{"$set": bson.M{"password": helper.EncryptPassword(updatePassword.NewPassword)}})
+	password, err := helper.EncryptPassword(updatePassword.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	dbUser.Password = password
+	dbUser.UpdatedAt = time.Now().Unix()
+	updateErr := h.db.Users().Update(c, dbUser)
 	if updateErr != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": updateErr.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
-}
-
-func EditName(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
+	// every other device has to sign in again with the new password
+	if err := h.revokeSessions(c, dbUser.Id, constant.RevokedPasswordChange); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
 		return
 	}
 
-	// verified user
-	_, _, err := helper.VerifyToken(token)
-
+	tokens, err := h.startSession(c, dbUser)
 	if err != nil {
-		c.JSON(400, gin.H{
-			"message": err.Error(),
-		})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate token"})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "token": tokens.AccessToken, "refresh_token": tokens.RefreshToken, "expires_in": tokens.ExpiresIn})
+}
+
+func (h *Handler) EditName(c *gin.Context) {
 	var editName types.NameData
 
 	defer c.Request.Body.Close()
@@ -319,20 +262,15 @@ func EditName(c *gin.Context) {
 		return
 	}
 
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-
-	var dbUser types.User
-
-	// checking if user exists
-	emailExists := userCollection.FindOne(c, bson.M{"email": editName.Email}).Decode(&dbUser)
-
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email not found"})
+	dbUser, ok := h.currentUser(c)
+	if !ok {
 		return
 	}
 
 	// updating the name
-	_, updateErr := userCollection.UpdateOne(c, bson.M{"email": editName.Email}, bson.M{"$set": bson.M{"name": editName.Name}})
+	dbUser.Name = editName.Name
+	dbUser.UpdatedAt = time.Now().Unix()
+	updateErr := h.db.Users().Update(c, dbUser)
 
 	if updateErr != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": updateErr.Error()})
@@ -342,28 +280,8 @@ func EditName(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
 }
 
-func AddToFavorite(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-
-	// verified user
-	_, _, err := helper.VerifyToken(token)
-
-	if err != nil {
-		c.JSON(400, gin.H{
-			"message": err.Error(),
-		})
-		return
-	}
-
+func (h *Handler) AddToFavorite(c *gin.Context) {
 	var req struct {
-		Email     string `json:"email" bson:"email"`
 		ProductId string `json:"productId" bson:"productId"`
 	}
 
@@ -377,20 +295,20 @@ func AddToFavorite(c *gin.Context) {
 		return
 	}
 
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-
-	var dbUser types.User
-
-	// checking if user exists
-	emailExists := userCollection.FindOne(c, bson.M{"email": req.Email}).Decode(&dbUser)
-
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email not found"})
+	dbUser, ok := h.currentUser(c)
+	if !ok {
 		return
 	}
 
 	// updating the favorite
-	_, updateErr := userCollection.UpdateOne(c, bson.M{"email": req.Email}, bson.M{"$push": bson.M{"favourite": req.ProductId}})
+	for _, v := range dbUser.Favourite {
+		if v == req.ProductId {
+			c.JSON(http.StatusOK, gin.H{"error": false, "message": "Added to Favorite"})
+			return
+		}
+	}
+	dbUser.Favourite = append(dbUser.Favourite, req.ProductId)
+	updateErr := h.db.Users().Update(c, dbUser)
 	if updateErr != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": updateErr.Error()})
 		return
@@ -399,28 +317,8 @@ func AddToFavorite(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"error": false, "message": "Added to Favorite"})
 }
 
-func RemoveFromFavorite(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-
-	// verified user
-	_, _, err := helper.VerifyToken(token)
-
-	if err != nil {
-		c.JSON(400, gin.H{
-			"message": err.Error(),
-		})
-		return
-	}
-
+func (h *Handler) RemoveFromFavorite(c *gin.Context) {
 	var req struct {
-		Email     string `json:"email" bson:"email"`
 		ProductId string `json:"productId" bson:"productId"`
 	}
 
@@ -434,20 +332,20 @@ func RemoveFromFavorite(c *gin.Context) {
 		return
 	}
 
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-
-	var dbUser types.User
-
-	// checking if user exists
-	emailExists := userCollection.FindOne(c, bson.M{"email": req.Email}).Decode(&dbUser)
-
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email not found"})
+	dbUser, ok := h.currentUser(c)
+	if !ok {
 		return
 	}
 
 	// updating the favourite
-	_, updateErr := userCollection.UpdateOne(c, bson.M{"email": req.Email}, bson.M{"$pull": bson.M{"favourite": req.ProductId}})
+	favourite := []string{}
+	for _, v := range dbUser.Favourite {
+		if v != req.ProductId {
+			favourite = append(favourite, v)
+		}
+	}
+	dbUser.Favourite = favourite
+	updateErr := h.db.Users().Update(c, dbUser)
 	if updateErr != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": updateErr.Error()})
 		return
@@ -456,219 +354,23 @@ func RemoveFromFavorite(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"error": false, "message": "Removed from Favorite"})
 }
 
-func ListFavorite(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
-
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-
-	// verified user
-	_, _, err := helper.VerifyToken(token)
-
-	if err != nil {
-		c.JSON(400, gin.H{
-			"message": err.Error(),
-		})
-		return
-	}
-
-	var req struct {
-		Email string `json:"email" bson:"email"`
-	}
-
-	defer c.Request.Body.Close()
-
-	// binding the request body to address
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{
-			"message": "Invalid request",
-		})
-		return
-	}
-
-	var userCollection *mongo.Collection = database.GetCollection(database.DB, constant.UsersCollection)
-
-	var dbUser types.User
-
-	// checking if user exists
-	emailExists := userCollection.FindOne(c, bson.M{"email": req.Email}).Decode(&dbUser)
-
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "email not found"})
+func (h *Handler) ListFavorite(c *gin.Context) {
+	dbUser, ok := h.currentUser(c)
+	if !ok {
 		return
 	}
 
-	var allFav []types.Product
-	var productCollection *mongo.Collection = database.GetCollection(database.DB, constant.ProductCollection)
+	allFav := []types.Product{}
 	for _, v := range dbUser.Favourite {
-		var singleProduct types.Product
-		productCollection.FindOne(c, bson.M{"id": v}).Decode(&singleProduct)
-		allFav = append(allFav, singleProduct)
-	}
-	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": allFav})
-}
-
-func AddToCart(c *gin.Context) {
-	var addToCart types.AddToCart
-	token := c.Request.Header.Get("Authorization")
-
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-
-	defer c.Request.Body.Close()
-
-	// binding the request body to address
-	if err := c.ShouldBindJSON(&addToCart); err != nil {
-		c.JSON(400, gin.H{
-			"message": "Invalid request",
-		})
-		return
-	}
-
-	var cartCollection *mongo.Collection = database.GetCollection(database.DB, constant.CartItemCollection)
-	var productCollection *mongo.Collection = database.GetCollection(database.DB, constant.ProductCollection)
-
-	var dbCart types.CartItem
-
-	// if user already has products on the cart
-	emailExists := cartCollection.FindOne(c, bson.M{"email": addToCart.Email}).Decode(&dbCart)
-	if emailExists != nil {
-		var product types.Product
-		err := productCollection.FindOne(c, bson.M{"id": addToCart.ProductID}).Decode(&product)
+		productId, err := primitive.ObjectIDFromHex(v)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "product not found"})
-			return
-		}
-
-		// creating the cart object
-		dbCart = types.CartItem{
-			Email:     addToCart.Email,
-			NumItems:  1,
-			ChekedOut: false,
-			Total:     float64(product.Price * addToCart.Quantity),
-			Products: []types.ProductInCart{
-				{
-					ProductID: addToCart.ProductID,
-					Quantity:  addToCart.Quantity,
-				},
-			},
+			continue
 		}
-
-		_, insertErr := cartCollection.InsertOne(c, dbCart)
-		if insertErr != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": insertErr.Error()})
-			return
+		singleProduct, err := h.db.Products().FindByID(c, productId)
+		if err != nil || !singleProduct.Visible() {
+			continue
 		}
-
-		c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
-		return
-	}
-
-	// if user does not have products on the cart
-	var product types.Product
-	err := productCollection.FindOne(c, bson.M{"id": addToCart.ProductID}).Decode(&product)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "product not found"})
-		return
-	}
-
-	// getting the cart for the user
-	var cart types.CartItem
-	cartCollection.FindOne(c, bson.M{"email": addToCart.Email}).Decode(&cart)
-
-	dbCart.Total = cart.Total
-	dbCart = types.CartItem{
-		Email:     addToCart.Email,
-		NumItems:  dbCart.NumItems + 1,
-		ChekedOut: false,
-		Total:     dbCart.Total + float64(product.Price*addToCart.Quantity),
-		Products: append(dbCart.Products, types.ProductInCart{
-			ProductID: addToCart.ProductID,
-			Quantity:  addToCart.Quantity,
-		}),
-	}
-
-	_, updateErr := cartCollection.UpdateOne(c, bson.M{"email": addToCart.Email}, bson.M{"$set": dbCart})
-	if updateErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": updateErr.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
-}
-
-func RemoveFromCart(c *gin.Context) {
-	var addToCart struct {
-		Email     string `json:"email" bson:"email"`
-		ProductId string `json:"productId" bson:"productId"`
-	}
-	token := c.Request.Header.Get("Authorization")
-	if token == "" {
-		c.JSON(400, gin.H{
-			"message": "Token is required",
-		})
-		return
-	}
-
-	defer c.Request.Body.Close()
-
-	// binding the request body to address
-	if err := c.ShouldBindJSON(&addToCart); err != nil {
-		c.JSON(400, gin.H{
-			"message": "Invalid request",
-		})
-		return
-	}
-
-	var cartCollection *mongo.Collection = database.GetCollection(database.DB, constant.CartItemCollection)
-	var productCollection *mongo.Collection = database.GetCollection(database.DB, constant.ProductCollection)
-
-	var dbCart types.CartItem
-	var product types.Product
-
-	// get the product
-	err := productCollection.FindOne(c, bson.M{"id": addToCart.ProductId}).Decode(&product)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "product not found"})
-		return
-	}
-
-	// if user already has products on the cart
-	emailExists := cartCollection.FindOne(c, bson.M{"email": addToCart.Email}).Decode(&dbCart)
-	if emailExists != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": "cart not found"})
-		return
-	}
-
-	var numberofItems int
-
-	// total minus for removed product
-	for _, v := range dbCart.Products {
-		if v.ProductID == addToCart.ProductId {
-			numberofItems = v.Quantity
-		}
-	}
-
-	dbCart.NumItems = dbCart.NumItems - 1
-	dbCart.Total = dbCart.Total - float64(product.Price*numberofItems)
-
-	_, updateErr := cartCollection.UpdateOne(
-		c,
-		bson.M{"email": addToCart.Email},
-		bson.M{"$pull": bson.M{"products": bson.M{"product_id": addToCart.ProductId}},
-			"$set": bson.M{"total": dbCart.Total}})
-	if updateErr != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": updateErr.Error()})
-		return
+		allFav = append(allFav, singleProduct)
 	}
-
-	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success"})
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": allFav})
 }
@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/mailer"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"github.com/gin-gonic/gin"
+)
+
+// errTooManyOtps is returned by sendOtp once the address was sent
+// constant.OtpMaxSends otps in the current window.
+var errTooManyOtps = errors.New(constant.OtpSendsExceededError)
+
+// sendOtp replaces any pending otp of the address with a fresh one and
+// emails it. The wrong guesses at earlier otps still count until the
+// window is over.
+func (h *Handler) sendOtp(c *gin.Context, email string) error {
+	verification, err := h.db.Verifications().FindByEmail(c, email)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	newWindow := now.Unix() >= verification.WindowStart+constant.OtpWindow*60
+	if !newWindow && verification.Sends >= constant.OtpMaxSends {
+		return errTooManyOtps
+	}
+	if newWindow {
+		verification.WindowStart = now.Unix()
+	}
+
+	otp, err := helper.GenerateOTP()
+	if err != nil {
+		return err
+	}
+	verification.Email = email
+	verification.OtpHash = helper.HashOTP(email, otp)
+	verification.Status = false
+	verification.CreatedAt = now.Unix()
+	verification.ExpiresAt = now.Add(constant.OtpExpiry * time.Minute).Unix()
+
+	if err := h.db.Verifications().Issue(c, verification, newWindow); err != nil {
+		return err
+	}
+
+	return h.mail.Send(c, mailer.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", otp, constant.OtpExpiry),
+	})
+}
+
+// unverifiedUser loads the account of the email in the request body and
+// makes sure it still needs verifying. An unknown and an already verified
+// email get the same answer, so it can't tell which addresses have an
+// account.
+func (h *Handler) unverifiedUser(c *gin.Context, email string) (types.User, bool) {
+	dbUser, err := h.db.Users().FindByEmail(c, email)
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return types.User{}, false
+	}
+	if err != nil || dbUser.Verified {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.NoPendingVerificationError})
+		return types.User{}, false
+	}
+	return dbUser, true
+}
+
+// @Summary Verify email
+// @Description Start the verification of an email by sending it an otp
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param email body types.EmailData true "Email"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/verify-email [post]
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req types.EmailData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	req.Email = helper.NormalizeEmail(req.Email)
+
+	if _, ok := h.unverifiedUser(c, req.Email); !ok {
+		return
+	}
+
+	// an otp that can still be used has to be resent explicitly
+	verification, err := h.db.Verifications().FindByEmail(c, req.Email)
+	if err == nil && verification.ExpiresAt > time.Now().Unix() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.OptAlreadySentError})
+		return
+	}
+
+	h.respondOtpSent(c, h.sendOtp(c, req.Email))
+}
+
+// respondOtpSent answers a request that sent an otp, err is what sendOtp
+// returned.
+func (h *Handler) respondOtpSent(c *gin.Context, err error) {
+	if errors.Is(err, errTooManyOtps) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": constant.OtpSentMessage})
+}
+
+// @Summary Resend email
+// @Description Send a new otp, at most once per otp validation window and a few times an hour. Wrong guesses at the previous otps still count.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param email body types.EmailData true "Email"
+// @Success 200 {object}  string
+// @Failure 429 {object}  string
+// @Router /v1/ecommerce/resend-email [post]
+func (h *Handler) ResendEmail(c *gin.Context) {
+	var req types.EmailData
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	req.Email = helper.NormalizeEmail(req.Email)
+
+	if _, ok := h.unverifiedUser(c, req.Email); !ok {
+		return
+	}
+
+	verification, err := h.db.Verifications().FindByEmail(c, req.Email)
+	if err == nil && time.Now().Unix() < verification.CreatedAt+constant.OtpValidation {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": true, "message": constant.OptAlreadySentError})
+		return
+	}
+
+	h.respondOtpSent(c, h.sendOtp(c, req.Email))
+}
+
+// @Summary Verify otp
+// @Description Check the otp sent to an email, signs the user in on success
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param otp body types.VerifyOtp true "Otp"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/verify-otp [post]
+func (h *Handler) VerifyOtp(c *gin.Context) {
+	var req types.VerifyOtp
+
+	defer c.Request.Body.Close()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+	req.Email = helper.NormalizeEmail(req.Email)
+
+	dbUser, ok := h.unverifiedUser(c, req.Email)
+	if !ok {
+		return
+	}
+
+	verification, err := h.db.Verifications().FindByEmail(c, req.Email)
+	if err != nil || verification.OtpHash == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.OtpValidationError})
+		return
+	}
+	if time.Now().Unix() >= verification.ExpiresAt {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.OtpExpiredValidationError})
+		return
+	}
+	// every guess is counted before it is checked, so parallel guesses
+	// can't get past the limit
+	err = h.db.Verifications().UseAttempt(c, req.Email, constant.OtpMaxAttempts)
+	if errors.Is(err, database.ErrLimitReached) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": true, "message": constant.OtpAttemptsExceededError})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	hash := helper.HashOTP(req.Email, req.Otp)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(verification.OtpHash)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "message": constant.OtpValidationError})
+		return
+	}
+
+	// the otp is single use
+	if err := h.db.Verifications().MarkVerified(c, req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	dbUser.Verified = true
+	dbUser.UpdatedAt = time.Now().Unix()
+	if err := h.db.Users().Update(c, dbUser); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": true, "message": err.Error()})
+		return
+	}
+
+	// the address is verified either way, a blocked account just isn't
+	// signed in
+	if dbUser.IsBlocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": true, "message": constant.UserBlockedError})
+		return
+	}
+
+	// jwt token, or a second factor challenge
+	h.signIn(c, dbUser)
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+func TestCartReplace(t *testing.T) {
+	ctx := context.Background()
+	tea := []types.ProductInCart{{ProductID: "tea", Quantity: 2}}
+	teaAndMug := []types.ProductInCart{{ProductID: "tea", Quantity: 2}, {ProductID: "mug", Quantity: 1}}
+
+	tests := []struct {
+		name    string
+		change  func(*types.CartItem)
+		wantErr error
+	}{
+		{name: "unchanged", change: func(*types.CartItem) {}},
+		{name: "item added", change: func(c *types.CartItem) { c.Products = teaAndMug }, wantErr: ErrNotFound},
+		{name: "quantity changed", change: func(c *types.CartItem) { c.Products = []types.ProductInCart{{ProductID: "tea", Quantity: 3}} }, wantErr: ErrNotFound},
+		{name: "coupon applied", change: func(c *types.CartItem) { c.Coupon = "SAVE10" }, wantErr: ErrNotFound},
+		{name: "checked out", change: func(c *types.CartItem) { c.ChekedOut = true }, wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryManager()
+			if err := db.Carts().Save(ctx, types.CartItem{Email: "a@x", Products: tea}); err != nil {
+				t.Fatal(err)
+			}
+			read, err := db.Carts().FindByEmail(ctx, "a@x")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// another request writes the cart in between
+			current := read
+			tt.change(&current)
+			if err := db.Carts().Save(ctx, current); err != nil {
+				t.Fatal(err)
+			}
+
+			err = db.Carts().Replace(ctx, read, types.CartItem{Email: "a@x", ChekedOut: true})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Replace() error = %v, want %v", err, tt.wantErr)
+			}
+			stored, _ := db.Carts().FindByEmail(ctx, "a@x")
+			if stored.ChekedOut != (tt.wantErr == nil || current.ChekedOut) {
+				t.Errorf("checked out = %v", stored.ChekedOut)
+			}
+		})
+	}
+}
@@ -3,64 +3,75 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
-	"os"
 	"time"
 
-	"github.com/PiehTVH/go-ecommerce/types"
-	"github.com/joho/godotenv"
+	"github.com/PiehTVH/go-ecommerce/config"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type manager struct {
-	connection *mongo.Client
-	ctx        context.Context
-	cancel     context.CancelFunc
-}
-
-var Mgr Manager
-
+// Manager hands out the repositories backing the API. Controllers only
+// depend on this interface, so a Mongo or an in-memory store can be
+// injected interchangeably.
 type Manager interface {
-	Insert(interface{}, string) (interface{}, error)
-	GetSingleRecordByEmail(string, string) *types.Verification
-	GetSingleRecordByEmailForUser(string, string) types.User
+	Users() UserRepository
+	Addresses() AddressRepository
+	Products() ProductRepository
+	Categories() CategoryRepository
+	Carts() CartRepository
+	Coupons() CouponRepository
+	Offers() OfferRepository
+	TaxRules() TaxRuleRepository
+	ShippingZones() ShippingZoneRepository
+	ShippingMethods() ShippingMethodRepository
+	Orders() OrderRepository
+	Sessions() SessionRepository
+	Verifications() VerificationRepository
+	PasswordResets() PasswordResetRepository
+	LoginAttempts() LoginAttemptRepository
+	LoginThrottles() LoginThrottleRepository
+	// Migrate brings documents stored by earlier versions up to date, each
+	// migration runs once. Amounts are converted to currency, the store
+	// currency, which can't change once they were.
+	Migrate(ctx context.Context, currency string) error
+	Disconnect(ctx context.Context) error
 }
 
-func EnvMongoURI() string {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+// Open connects the store chosen by the configuration.
+func Open(ctx context.Context, cfg config.Database) (Manager, error) {
+	if cfg.Driver == "memory" {
+		return NewMemoryManager(), nil
 	}
 
-	return os.Getenv("DB_HOST")
-}
-
-func ConnectDB() *mongo.Client {
-	client, err := mongo.NewClient(options.Client().ApplyURI(EnvMongoURI()))
+	client, err := ConnectDB(ctx, cfg.URI)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	m, err := NewMongoManager(ctx, client, cfg.Name)
+	if err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	return m, nil
+}
+
+// ConnectDB connects to the mongo server at uri and pings it, giving up
+// when ctx is done or after 10 seconds.
+func ConnectDB(ctx context.Context, uri string) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
-	err = client.Connect(ctx)
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// ping the database
 	err = client.Ping(ctx, nil)
 	if err != nil {
-		log.Fatal(err)
+		client.Disconnect(context.Background())
+		return nil, err
 	}
 	fmt.Println("Connected to MongoDB")
-	return client
-}
-
-var DB *mongo.Client = ConnectDB()
-
-// getting database collections
-func GetCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	collection := client.Database("Elegance").Collection(collectionName)
-	return collection
+	return client, nil
 }
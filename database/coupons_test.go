@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+func TestCouponRedeem(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name         string
+		usageLimit   int
+		perUserLimit int
+		redeemers    []string
+		wantErrs     []error
+		wantUsed     int
+	}{
+		{
+			name:      "unlimited",
+			redeemers: []string{"a@x", "a@x", "b@x"},
+			wantErrs:  []error{nil, nil, nil},
+			wantUsed:  3,
+		},
+		{
+			name:       "usage limit",
+			usageLimit: 2,
+			redeemers:  []string{"a@x", "b@x", "c@x"},
+			wantErrs:   []error{nil, nil, ErrLimitReached},
+			wantUsed:   2,
+		},
+		{
+			name:         "per user limit",
+			perUserLimit: 1,
+			redeemers:    []string{"a@x", "a@x", "b@x"},
+			wantErrs:     []error{nil, ErrLimitReached, nil},
+			wantUsed:     2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryManager()
+			coupon := types.Coupon{Name: "SAVE", UsageLimit: tt.usageLimit, PerUserLimit: tt.perUserLimit}
+			if err := db.Coupons().Create(ctx, &coupon); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, email := range tt.redeemers {
+				if err := db.Coupons().Redeem(ctx, coupon, email); !errors.Is(err, tt.wantErrs[i]) {
+					t.Errorf("Redeem(%s) #%d error = %v, want %v", email, i, err, tt.wantErrs[i])
+				}
+			}
+			stored, _ := db.Coupons().FindByID(ctx, coupon.ID)
+			if stored.UsedCount != tt.wantUsed {
+				t.Errorf("used count %d, want %d", stored.UsedCount, tt.wantUsed)
+			}
+		})
+	}
+}
+
+func TestCouponRelease(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryManager()
+	coupon := types.Coupon{Name: "ONCE", UsageLimit: 1, PerUserLimit: 1}
+	if err := db.Coupons().Create(ctx, &coupon); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Coupons().Redeem(ctx, coupon, "a@x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Coupons().Redeem(ctx, coupon, "b@x"); !errors.Is(err, ErrLimitReached) {
+		t.Fatalf("Redeem() of a used up coupon error = %v, want %v", err, ErrLimitReached)
+	}
+	if err := db.Coupons().Release(ctx, coupon, "a@x"); err != nil {
+		t.Fatal(err)
+	}
+	if used, _ := db.Coupons().UsedBy(ctx, coupon.ID, "a@x"); used != 0 {
+		t.Errorf("UsedBy() after Release = %d, want 0", used)
+	}
+	if err := db.Coupons().Redeem(ctx, coupon, "b@x"); err != nil {
+		t.Errorf("Redeem() after Release error = %v", err)
+	}
+
+	// releasing more than was redeemed never goes below zero
+	for i := 0; i < 3; i++ {
+		if err := db.Coupons().Release(ctx, coupon, "a@x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored, _ := db.Coupons().FindByID(ctx, coupon.ID)
+	if stored.UsedCount != 0 {
+		t.Errorf("used count %d, want 0", stored.UsedCount)
+	}
+}
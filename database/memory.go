@@ -0,0 +1,1126 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// table is a goroutine safe, insertion ordered store. Rows are kept bson
+// encoded so callers never share memory with the store and values round
+// trip exactly like they would through Mongo.
+type table[T any] struct {
+	mu   sync.RWMutex
+	rows map[string][]byte
+	keys []string
+}
+
+func newTable[T any]() *table[T] {
+	return &table[T]{rows: map[string][]byte{}}
+}
+
+func (t *table[T]) get(key string) (T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var out T
+	raw, ok := t.rows[key]
+	if !ok {
+		return out, ErrNotFound
+	}
+	err := bson.Unmarshal(raw, &out)
+	return out, err
+}
+
+// put stores v under key. Unless upsert is set it fails with ErrNotFound
+// when the key is missing.
+func (t *table[T]) put(key string, v T, upsert bool) error {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.rows[key]; !ok {
+		if !upsert {
+			return ErrNotFound
+		}
+		t.keys = append(t.keys, key)
+	}
+	t.rows[key] = raw
+	return nil
+}
+
+func (t *table[T]) insert(key string, v T) error {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.rows[key]; ok {
+		return ErrDuplicate
+	}
+	t.keys = append(t.keys, key)
+	t.rows[key] = raw
+	return nil
+}
+
+func (t *table[T]) delete(key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.rows[key]; !ok {
+		return ErrNotFound
+	}
+	delete(t.rows, key)
+	for i, k := range t.keys {
+		if k == key {
+			t.keys = append(t.keys[:i], t.keys[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// where returns every row accepted by match, in insertion order.
+func (t *table[T]) where(match func(T) bool) ([]T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	out := []T{}
+	for _, key := range t.keys {
+		var v T
+		if err := bson.Unmarshal(t.rows[key], &v); err != nil {
+			return nil, err
+		}
+		if match == nil || match(v) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// first returns the first row accepted by match.
+func (t *table[T]) first(match func(T) bool) (T, error) {
+	rows, err := t.where(match)
+	if err != nil || len(rows) == 0 {
+		var zero T
+		if err == nil {
+			err = ErrNotFound
+		}
+		return zero, err
+	}
+	return rows[0], nil
+}
+
+type memoryManager struct {
+	users      *memoryUsers
+	addresses  *memoryAddresses
+	products   *memoryProducts
+	categories *memoryCategories
+	carts      *memoryCarts
+	coupons    *memoryCoupons
+	offers     *memoryOffers
+	taxRules   *memoryTaxRules
+	zones      *memoryShippingZones
+	methods    *memoryShippingMethods
+	orders     *memoryOrders
+	sessions   *memorySessions
+
+	verifications  *memoryVerifications
+	passwordResets *memoryPasswordResets
+	loginAttempts  *memoryLoginAttempts
+	loginThrottles *memoryLoginThrottles
+}
+
+// NewMemoryManager returns a Manager that keeps everything in process. It
+// is meant for local runs and tests, nothing survives a restart.
+func NewMemoryManager() Manager {
+	return &memoryManager{
+		users:      &memoryUsers{t: newTable[types.User]()},
+		addresses:  &memoryAddresses{newTable[types.Address]()},
+		products:   &memoryProducts{t: newTable[types.Product]()},
+		categories: &memoryCategories{t: newTable[types.Category]()},
+		carts:      &memoryCarts{t: newTable[types.CartItem]()},
+		coupons:    &memoryCoupons{t: newTable[types.Coupon](), usages: newTable[types.CouponUsage]()},
+		offers:     &memoryOffers{newTable[types.Offer]()},
+		taxRules:   &memoryTaxRules{newTable[types.TaxRule]()},
+		zones:      &memoryShippingZones{newTable[types.ShippingZone]()},
+		methods:    &memoryShippingMethods{newTable[types.ShippingMethod]()},
+		orders:     &memoryOrders{t: newTable[types.Order]()},
+		sessions:   &memorySessions{t: newTable[types.Session]()},
+
+		verifications:  &memoryVerifications{t: newTable[types.Verification]()},
+		passwordResets: &memoryPasswordResets{t: newTable[types.PasswordReset]()},
+		loginAttempts:  &memoryLoginAttempts{newTable[types.LoginAttempt]()},
+		loginThrottles: &memoryLoginThrottles{t: newTable[types.LoginThrottle]()},
+	}
+}
+
+func (m *memoryManager) Users() UserRepository          { return m.users }
+func (m *memoryManager) Addresses() AddressRepository   { return m.addresses }
+func (m *memoryManager) Products() ProductRepository    { return m.products }
+func (m *memoryManager) Categories() CategoryRepository { return m.categories }
+func (m *memoryManager) Carts() CartRepository          { return m.carts }
+func (m *memoryManager) Coupons() CouponRepository      { return m.coupons }
+func (m *memoryManager) Offers() OfferRepository        { return m.offers }
+func (m *memoryManager) TaxRules() TaxRuleRepository    { return m.taxRules }
+func (m *memoryManager) Orders() OrderRepository        { return m.orders }
+func (m *memoryManager) Sessions() SessionRepository    { return m.sessions }
+
+func (m *memoryManager) ShippingZones() ShippingZoneRepository {
+	return m.zones
+}
+
+func (m *memoryManager) ShippingMethods() ShippingMethodRepository {
+	return m.methods
+}
+
+func (m *memoryManager) Verifications() VerificationRepository {
+	return m.verifications
+}
+
+func (m *memoryManager) PasswordResets() PasswordResetRepository {
+	return m.passwordResets
+}
+
+func (m *memoryManager) LoginAttempts() LoginAttemptRepository {
+	return m.loginAttempts
+}
+
+func (m *memoryManager) LoginThrottles() LoginThrottleRepository {
+	return m.loginThrottles
+}
+
+// Migrate has nothing to do, the memory store starts empty every time.
+func (m *memoryManager) Migrate(ctx context.Context, currency string) error {
+	return nil
+}
+
+func (m *memoryManager) Disconnect(ctx context.Context) error { return nil }
+
+// users
+
+type memoryUsers struct {
+	t *table[types.User]
+	// mu serialises writes so the email uniqueness check cannot race
+	mu sync.Mutex
+}
+
+func (r *memoryUsers) Create(ctx context.Context, user *types.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.FindByEmail(ctx, user.Email); err == nil {
+		return ErrDuplicate
+	}
+	newID(&user.Id)
+	return r.t.insert(user.Id.Hex(), *user)
+}
+
+func (r *memoryUsers) FindByID(ctx context.Context, id primitive.ObjectID) (types.User, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryUsers) FindByEmail(ctx context.Context, email string) (types.User, error) {
+	return r.t.first(func(u types.User) bool { return u.Email == email })
+}
+
+func (r *memoryUsers) List(ctx context.Context) ([]types.User, error) {
+	return r.t.where(nil)
+}
+
+func (r *memoryUsers) Update(ctx context.Context, user types.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if other, err := r.FindByEmail(ctx, user.Email); err == nil && other.Id != user.Id {
+		return ErrDuplicate
+	}
+	return r.t.put(user.Id.Hex(), user, false)
+}
+
+func (r *memoryUsers) UpdatePassword(ctx context.Context, id primitive.ObjectID, oldHash, newHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.t.get(id.Hex())
+	if err != nil {
+		return err
+	}
+	if user.Password != oldHash {
+		return ErrNotFound
+	}
+	user.Password = newHash
+	return r.t.put(user.Id.Hex(), user, false)
+}
+
+func (r *memoryUsers) UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.t.get(id.Hex())
+	if err != nil {
+		return err
+	}
+	if user.TotpLastStep >= step {
+		return ErrNotFound
+	}
+	user.TotpLastStep = step
+	return r.t.put(user.Id.Hex(), user, false)
+}
+
+func (r *memoryUsers) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.t.get(id.Hex())
+	if err != nil {
+		return err
+	}
+	for i, stored := range user.RecoveryCodes {
+		if stored == hash {
+			user.RecoveryCodes = append(user.RecoveryCodes[:i:i], user.RecoveryCodes[i+1:]...)
+			return r.t.put(user.Id.Hex(), user, false)
+		}
+	}
+	return ErrNotFound
+}
+
+// addresses
+
+type memoryAddresses struct{ t *table[types.Address] }
+
+func (r *memoryAddresses) Create(ctx context.Context, address *types.Address) error {
+	newID(&address.ID)
+	return r.t.insert(address.ID.Hex(), *address)
+}
+
+func (r *memoryAddresses) FindByID(ctx context.Context, id primitive.ObjectID) (types.Address, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryAddresses) ListByEmail(ctx context.Context, email string) ([]types.Address, error) {
+	return r.t.where(func(a types.Address) bool { return a.Email == email })
+}
+
+func (r *memoryAddresses) Update(ctx context.Context, address types.Address) error {
+	return r.t.put(address.ID.Hex(), address, false)
+}
+
+func (r *memoryAddresses) ClearDefaults(ctx context.Context, address types.Address) error {
+	others, err := r.t.where(func(a types.Address) bool {
+		return a.Email == address.Email && a.ID != address.ID &&
+			((address.DefaultShipping && a.DefaultShipping) || (address.DefaultBilling && a.DefaultBilling))
+	})
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		other.DefaultShipping = other.DefaultShipping && !address.DefaultShipping
+		other.DefaultBilling = other.DefaultBilling && !address.DefaultBilling
+		other.UpdatedAt = address.UpdatedAt
+		if err := r.t.put(other.ID.Hex(), other, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryAddresses) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.t.delete(id.Hex())
+}
+
+// products
+
+type memoryProducts struct {
+	t *table[types.Product]
+	// mu serialises writes so stock changes and edits don't overwrite
+	// each other
+	mu sync.Mutex
+}
+
+func (r *memoryProducts) Create(ctx context.Context, product *types.Product) error {
+	newID(&product.ID)
+	return r.t.insert(product.ID.Hex(), *product)
+}
+
+func (r *memoryProducts) FindByID(ctx context.Context, id primitive.ObjectID) (types.Product, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryProducts) Find(ctx context.Context, query ProductQuery) ([]types.Product, int64, error) {
+	search := strings.ToLower(query.Search)
+	products, err := r.t.where(func(p types.Product) bool {
+		if query.InStock && p.Stock <= 0 {
+			return false
+		}
+		if query.CategoryIDs != nil && !contains(query.CategoryIDs, p.CategoryId) {
+			return false
+		}
+		if (!query.IncludeHidden && p.Hidden) || (!query.IncludeDeleted && p.DeletedAt > 0) {
+			return false
+		}
+		if search == "" {
+			return true
+		}
+		return strings.Contains(strings.ToLower(p.Name), search) ||
+			strings.Contains(strings.ToLower(p.Description), search)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return paginate(products, query.Skip, query.Limit), int64(len(products)), nil
+}
+
+func (r *memoryProducts) UpdateDetails(ctx context.Context, product types.Product) (types.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.t.get(product.ID.Hex())
+	if err != nil {
+		return types.Product{}, err
+	}
+	if stored.DeletedAt > 0 {
+		return types.Product{}, ErrNotFound
+	}
+	stored.Name = product.Name
+	stored.Price = product.Price
+	stored.Description = product.Description
+	stored.Images = product.Images
+	stored.Keywords = product.Keywords
+	stored.CategoryId = product.CategoryId
+	stored.Weight = product.Weight
+	stored.Dimensions = product.Dimensions
+	stored.Hidden = product.Hidden
+	stored.UpdatedAt = product.UpdatedAt
+	return stored, r.t.put(stored.ID.Hex(), stored, false)
+}
+
+func (r *memoryProducts) MarkDeleted(ctx context.Context, id primitive.ObjectID, at int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, err := r.t.get(id.Hex())
+	if err != nil {
+		return err
+	}
+	if product.DeletedAt > 0 {
+		return ErrNotFound
+	}
+	product.DeletedAt, product.UpdatedAt = at, at
+	return r.t.put(product.ID.Hex(), product, false)
+}
+
+// visible loads a product customers can see, the lock must be held.
+func (r *memoryProducts) visible(id primitive.ObjectID) (types.Product, error) {
+	product, err := r.t.get(id.Hex())
+	if err == nil && !product.Visible() {
+		return types.Product{}, ErrNotFound
+	}
+	return product, err
+}
+
+func (r *memoryProducts) AddRating(ctx context.Context, id primitive.ObjectID, rating float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, err := r.visible(id)
+	if err != nil {
+		return err
+	}
+	product.Rating = (product.Rating*float64(product.NumRating) + rating) / float64(product.NumRating+1)
+	product.NumRating++
+	return r.t.put(product.ID.Hex(), product, false)
+}
+
+func (r *memoryProducts) AddComment(ctx context.Context, id primitive.ObjectID, comment types.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, err := r.visible(id)
+	if err != nil {
+		return err
+	}
+	product.Comments = append(product.Comments, comment)
+	return r.t.put(product.ID.Hex(), product, false)
+}
+
+func (r *memoryProducts) AdjustStock(ctx context.Context, id primitive.ObjectID, delta int) (types.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, err := r.t.get(id.Hex())
+	if err != nil {
+		return types.Product{}, err
+	}
+	if product.DeletedAt > 0 {
+		return types.Product{}, ErrNotFound
+	}
+	if product.Stock+delta < 0 {
+		return types.Product{}, ErrInsufficientStock
+	}
+	product.Stock += delta
+	return product, r.t.put(product.ID.Hex(), product, false)
+}
+
+func (r *memoryProducts) ReassignCategory(ctx context.Context, from, to string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	products, err := r.t.where(func(p types.Product) bool { return p.CategoryId == from })
+	if err != nil {
+		return 0, err
+	}
+	for _, product := range products {
+		product.CategoryId = to
+		if err := r.t.put(product.ID.Hex(), product, false); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(products)), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func paginate[T any](rows []T, skip, limit int64) []T {
+	if skip >= int64(len(rows)) {
+		return []T{}
+	}
+	if skip > 0 {
+		rows = rows[skip:]
+	}
+	if limit > 0 && limit < int64(len(rows)) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// categories
+
+type memoryCategories struct {
+	t *table[types.Category]
+	// mu serialises writes so the slug uniqueness check cannot race
+	mu sync.Mutex
+}
+
+func (r *memoryCategories) Create(ctx context.Context, category *types.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.FindBySlug(ctx, category.Slug); category.Slug != "" && err == nil {
+		return ErrDuplicate
+	}
+	newID(&category.ID)
+	return r.t.insert(category.ID.Hex(), *category)
+}
+
+func (r *memoryCategories) FindByID(ctx context.Context, id primitive.ObjectID) (types.Category, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryCategories) FindBySlug(ctx context.Context, slug string) (types.Category, error) {
+	return r.t.first(func(c types.Category) bool { return c.Slug == slug })
+}
+
+func (r *memoryCategories) List(ctx context.Context) ([]types.Category, error) {
+	categories, err := r.t.where(nil)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].Position != categories[j].Position {
+			return categories[i].Position < categories[j].Position
+		}
+		return categories[i].Category < categories[j].Category
+	})
+	return categories, nil
+}
+
+func (r *memoryCategories) Update(ctx context.Context, category types.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if other, err := r.FindBySlug(ctx, category.Slug); category.Slug != "" && err == nil && other.ID != category.ID {
+		return ErrDuplicate
+	}
+	return r.t.put(category.ID.Hex(), category, false)
+}
+
+func (r *memoryCategories) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.t.delete(id.Hex())
+}
+
+// carts
+
+type memoryCarts struct {
+	t *table[types.CartItem]
+	// mu serialises writes so Replace compares and saves at once
+	mu sync.Mutex
+}
+
+func (r *memoryCarts) FindByEmail(ctx context.Context, email string) (types.CartItem, error) {
+	return r.t.get(email)
+}
+
+func (r *memoryCarts) Save(ctx context.Context, cart types.CartItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.t.put(cart.Email, cart, true)
+}
+
+func (r *memoryCarts) Replace(ctx context.Context, old, cart types.CartItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.t.get(old.Email)
+	if err != nil {
+		return err
+	}
+	if stored.ChekedOut != old.ChekedOut || stored.Coupon != old.Coupon || !reflect.DeepEqual(stored.Products, old.Products) {
+		return ErrNotFound
+	}
+	return r.t.put(cart.Email, cart, false)
+}
+
+func (r *memoryCarts) Delete(ctx context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.t.delete(email)
+}
+
+// coupons
+
+type memoryCoupons struct {
+	t      *table[types.Coupon]
+	usages *table[types.CouponUsage]
+	// mu serialises writes so names stay unique and Redeem is atomic
+	mu sync.Mutex
+}
+
+func (r *memoryCoupons) Create(ctx context.Context, coupon *types.Coupon) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.FindByName(ctx, coupon.Name); err == nil {
+		return ErrDuplicate
+	}
+	newID(&coupon.ID)
+	return r.t.insert(coupon.ID.Hex(), *coupon)
+}
+
+func (r *memoryCoupons) FindByID(ctx context.Context, id primitive.ObjectID) (types.Coupon, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryCoupons) FindByName(ctx context.Context, name string) (types.Coupon, error) {
+	return r.t.first(func(c types.Coupon) bool { return c.Name == name })
+}
+
+func (r *memoryCoupons) List(ctx context.Context) ([]types.Coupon, error) {
+	return r.t.where(nil)
+}
+
+func (r *memoryCoupons) Update(ctx context.Context, coupon types.Coupon) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.t.put(coupon.ID.Hex(), coupon, false)
+}
+
+func (r *memoryCoupons) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.t.delete(id.Hex())
+}
+
+func (r *memoryCoupons) Redeem(ctx context.Context, coupon types.Coupon, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.t.get(coupon.ID.Hex())
+	if err != nil {
+		return ErrLimitReached
+	}
+	key := couponUsageKey(coupon.ID, email)
+	usage, err := r.usages.get(key)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if (coupon.UsageLimit > 0 && stored.UsedCount >= coupon.UsageLimit) ||
+		(coupon.PerUserLimit > 0 && usage.Count >= coupon.PerUserLimit) {
+		return ErrLimitReached
+	}
+
+	usage = types.CouponUsage{ID: key, CouponID: coupon.ID.Hex(), Email: email, Count: usage.Count + 1}
+	if err := r.usages.put(key, usage, true); err != nil {
+		return err
+	}
+	stored.UsedCount++
+	return r.t.put(stored.ID.Hex(), stored, false)
+}
+
+func (r *memoryCoupons) Release(ctx context.Context, coupon types.Coupon, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := couponUsageKey(coupon.ID, email)
+	if usage, err := r.usages.get(key); err == nil && usage.Count > 0 {
+		usage.Count--
+		if err := r.usages.put(key, usage, false); err != nil {
+			return err
+		}
+	}
+	if stored, err := r.t.get(coupon.ID.Hex()); err == nil && stored.UsedCount > 0 {
+		stored.UsedCount--
+		return r.t.put(stored.ID.Hex(), stored, false)
+	}
+	return nil
+}
+
+func (r *memoryCoupons) UsedBy(ctx context.Context, id primitive.ObjectID, email string) (int, error) {
+	usage, err := r.usages.get(couponUsageKey(id, email))
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	return usage.Count, err
+}
+
+// offers
+
+type memoryOffers struct{ t *table[types.Offer] }
+
+func (r *memoryOffers) Create(ctx context.Context, offer *types.Offer) error {
+	newID(&offer.ID)
+	return r.t.insert(offer.ID.Hex(), *offer)
+}
+
+func (r *memoryOffers) FindByID(ctx context.Context, id primitive.ObjectID) (types.Offer, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryOffers) List(ctx context.Context) ([]types.Offer, error) {
+	return r.t.where(nil)
+}
+
+func (r *memoryOffers) Running(ctx context.Context, now int64) ([]types.Offer, error) {
+	return r.t.where(func(o types.Offer) bool { return o.Running(now) })
+}
+
+func (r *memoryOffers) Update(ctx context.Context, offer types.Offer) error {
+	return r.t.put(offer.ID.Hex(), offer, false)
+}
+
+func (r *memoryOffers) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.t.delete(id.Hex())
+}
+
+// tax rules
+
+type memoryTaxRules struct{ t *table[types.TaxRule] }
+
+func (r *memoryTaxRules) Create(ctx context.Context, rule *types.TaxRule) error {
+	newID(&rule.ID)
+	return r.t.insert(rule.ID.Hex(), *rule)
+}
+
+func (r *memoryTaxRules) FindByID(ctx context.Context, id primitive.ObjectID) (types.TaxRule, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryTaxRules) List(ctx context.Context) ([]types.TaxRule, error) {
+	return r.t.where(nil)
+}
+
+func (r *memoryTaxRules) Update(ctx context.Context, rule types.TaxRule) error {
+	return r.t.put(rule.ID.Hex(), rule, false)
+}
+
+func (r *memoryTaxRules) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.t.delete(id.Hex())
+}
+
+// shipping
+
+type memoryShippingZones struct{ t *table[types.ShippingZone] }
+
+func (r *memoryShippingZones) Create(ctx context.Context, zone *types.ShippingZone) error {
+	newID(&zone.ID)
+	return r.t.insert(zone.ID.Hex(), *zone)
+}
+
+func (r *memoryShippingZones) FindByID(ctx context.Context, id primitive.ObjectID) (types.ShippingZone, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryShippingZones) List(ctx context.Context) ([]types.ShippingZone, error) {
+	return r.t.where(nil)
+}
+
+func (r *memoryShippingZones) Update(ctx context.Context, zone types.ShippingZone) error {
+	return r.t.put(zone.ID.Hex(), zone, false)
+}
+
+func (r *memoryShippingZones) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.t.delete(id.Hex())
+}
+
+type memoryShippingMethods struct{ t *table[types.ShippingMethod] }
+
+func (r *memoryShippingMethods) Create(ctx context.Context, method *types.ShippingMethod) error {
+	newID(&method.ID)
+	return r.t.insert(method.ID.Hex(), *method)
+}
+
+func (r *memoryShippingMethods) FindByID(ctx context.Context, id primitive.ObjectID) (types.ShippingMethod, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryShippingMethods) List(ctx context.Context) ([]types.ShippingMethod, error) {
+	return r.t.where(nil)
+}
+
+func (r *memoryShippingMethods) ListByZone(ctx context.Context, zoneID string) ([]types.ShippingMethod, error) {
+	return r.t.where(func(m types.ShippingMethod) bool { return m.ZoneId == zoneID })
+}
+
+func (r *memoryShippingMethods) Update(ctx context.Context, method types.ShippingMethod) error {
+	return r.t.put(method.ID.Hex(), method, false)
+}
+
+func (r *memoryShippingMethods) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.t.delete(id.Hex())
+}
+
+// orders
+
+type memoryOrders struct {
+	t *table[types.Order]
+	// mu serialises writes so idempotency keys stay unique and
+	// Transition is atomic
+	mu sync.Mutex
+}
+
+func (r *memoryOrders) Create(ctx context.Context, order *types.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if order.IdempotencyKey != "" {
+		if _, err := r.FindByIdempotencyKey(ctx, order.Email, order.IdempotencyKey); err == nil {
+			return ErrDuplicate
+		}
+	}
+	newID(&order.ID)
+	return r.t.insert(order.ID.Hex(), *order)
+}
+
+func (r *memoryOrders) FindByID(ctx context.Context, id primitive.ObjectID) (types.Order, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memoryOrders) FindByIdempotencyKey(ctx context.Context, email, key string) (types.Order, error) {
+	return r.t.first(func(o types.Order) bool { return o.Email == email && o.IdempotencyKey == key })
+}
+
+func (r *memoryOrders) ListByEmail(ctx context.Context, email string) ([]types.Order, error) {
+	orders, err := r.t.where(func(o types.Order) bool { return o.Email == email })
+	return newestFirst(orders), err
+}
+
+func (r *memoryOrders) Find(ctx context.Context, query OrderQuery) ([]types.Order, int64, error) {
+	orders, err := r.t.where(func(o types.Order) bool {
+		return (query.Email == "" || o.Email == query.Email) &&
+			(query.Status == "" || o.Status == query.Status) &&
+			(query.From == 0 || o.CreatedAt >= query.From) &&
+			(query.To == 0 || o.CreatedAt < query.To)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return paginate(newestFirst(orders), query.Skip, query.Limit), int64(len(orders)), nil
+}
+
+func (r *memoryOrders) Update(ctx context.Context, order types.Order) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.t.put(order.ID.Hex(), order, false)
+}
+
+func (r *memoryOrders) Transition(ctx context.Context, id primitive.ObjectID, from string, event types.OrderEvent) (types.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, err := r.t.get(id.Hex())
+	if err != nil {
+		return types.Order{}, err
+	}
+	if order.Status != from {
+		return types.Order{}, ErrNotFound
+	}
+	order.Status = event.Status
+	order.UpdatedAt = event.At
+	order.History = append(order.History, event)
+	return order, r.t.put(order.ID.Hex(), order, false)
+}
+
+func (r *memoryOrders) SetPayment(ctx context.Context, id primitive.ObjectID, payment types.Payment) (types.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order, err := r.t.get(id.Hex())
+	if err != nil {
+		return types.Order{}, err
+	}
+	order.Payment = payment
+	return order, r.t.put(order.ID.Hex(), order, false)
+}
+
+func newestFirst(orders []types.Order) []types.Order {
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].CreatedAt > orders[j].CreatedAt })
+	return orders
+}
+
+// sessions
+
+type memorySessions struct {
+	t *table[types.Session]
+	// mu makes the read-modify-write updates below atomic
+	mu sync.Mutex
+}
+
+func (r *memorySessions) Create(ctx context.Context, session *types.Session) error {
+	newID(&session.ID)
+	return r.t.insert(session.ID.Hex(), *session)
+}
+
+func (r *memorySessions) FindByID(ctx context.Context, id primitive.ObjectID) (types.Session, error) {
+	return r.t.get(id.Hex())
+}
+
+func (r *memorySessions) Rotate(ctx context.Context, id primitive.ObjectID, oldHash, newHash string, at int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, err := r.t.get(id.Hex())
+	if err != nil {
+		return err
+	}
+	if session.RefreshHash != oldHash || session.RevokedAt != 0 {
+		return ErrNotFound
+	}
+	session.PreviousHashes = append(session.PreviousHashes, oldHash)
+	if extra := len(session.PreviousHashes) - constant.RefreshHashHistory; extra > 0 {
+		session.PreviousHashes = session.PreviousHashes[extra:]
+	}
+	session.RefreshHash = newHash
+	session.LastUsedAt = at
+	return r.t.put(id.Hex(), session, false)
+}
+
+func (r *memorySessions) Revoke(ctx context.Context, id primitive.ObjectID, reason string, at int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, err := r.t.get(id.Hex())
+	if err != nil || session.RevokedAt != 0 {
+		return nil
+	}
+	session.RevokedAt = at
+	session.RevokedReason = reason
+	return r.t.put(id.Hex(), session, false)
+}
+
+func (r *memorySessions) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID, reason string, at int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sessions, err := r.t.where(func(s types.Session) bool { return s.UserID == userID && s.RevokedAt == 0 })
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		session.RevokedAt = at
+		session.RevokedReason = reason
+		if err := r.t.put(session.ID.Hex(), session, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifications
+
+type memoryVerifications struct {
+	t *table[types.Verification]
+	// mu makes the counters atomic
+	mu sync.Mutex
+}
+
+func (r *memoryVerifications) FindByEmail(ctx context.Context, email string) (types.Verification, error) {
+	return r.t.get(email)
+}
+
+func (r *memoryVerifications) Issue(ctx context.Context, verification types.Verification, newWindow bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.t.get(verification.Email)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	attempts, sends := stored.Attempts, stored.Sends+1
+	if newWindow {
+		attempts, sends = 0, 1
+	}
+	verification.ID = stored.ID
+	newID(&verification.ID)
+	verification.Attempts, verification.Sends = attempts, sends
+	return r.t.put(verification.Email, verification, true)
+}
+
+func (r *memoryVerifications) UseAttempt(ctx context.Context, email string, max int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification, err := r.t.get(email)
+	if err == ErrNotFound || (err == nil && verification.Attempts >= max) {
+		return ErrLimitReached
+	}
+	if err != nil {
+		return err
+	}
+	verification.Attempts++
+	return r.t.put(email, verification, false)
+}
+
+func (r *memoryVerifications) MarkVerified(ctx context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	verification, err := r.t.get(email)
+	if err != nil {
+		return err
+	}
+	verification.Status = true
+	verification.OtpHash = ""
+	return r.t.put(email, verification, false)
+}
+
+// password resets
+
+type memoryPasswordResets struct {
+	t  *table[types.PasswordReset]
+	mu sync.Mutex
+}
+
+func (r *memoryPasswordResets) Create(ctx context.Context, reset *types.PasswordReset) error {
+	newID(&reset.ID)
+	return r.t.insert(reset.ID.Hex(), *reset)
+}
+
+func (r *memoryPasswordResets) FindByHash(ctx context.Context, tokenHash string) (types.PasswordReset, error) {
+	return r.t.first(func(p types.PasswordReset) bool { return p.TokenHash == tokenHash })
+}
+
+func (r *memoryPasswordResets) MarkUsed(ctx context.Context, id primitive.ObjectID, at int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset, err := r.t.get(id.Hex())
+	if err != nil {
+		return err
+	}
+	if reset.UsedAt != 0 {
+		return ErrNotFound
+	}
+	reset.UsedAt = at
+	return r.t.put(id.Hex(), reset, false)
+}
+
+func (r *memoryPasswordResets) InvalidateForUser(ctx context.Context, userID primitive.ObjectID, at int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resets, err := r.t.where(func(p types.PasswordReset) bool { return p.UserID == userID && p.UsedAt == 0 })
+	if err != nil {
+		return err
+	}
+	for _, reset := range resets {
+		reset.UsedAt = at
+		if err := r.t.put(reset.ID.Hex(), reset, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// login attempts
+
+type memoryLoginAttempts struct{ t *table[types.LoginAttempt] }
+
+func (r *memoryLoginAttempts) Create(ctx context.Context, attempt *types.LoginAttempt) error {
+	newID(&attempt.ID)
+	return r.t.insert(attempt.ID.Hex(), *attempt)
+}
+
+func (r *memoryLoginAttempts) Recent(ctx context.Context, email string, limit int64) ([]types.LoginAttempt, error) {
+	attempts, err := r.t.where(func(a types.LoginAttempt) bool { return a.Email == email })
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].CreatedAt > attempts[j].CreatedAt })
+	return paginate(attempts, 0, limit), nil
+}
+
+// login throttles
+
+type memoryLoginThrottles struct {
+	t  *table[types.LoginThrottle]
+	mu sync.Mutex
+}
+
+func (r *memoryLoginThrottles) Get(ctx context.Context, key string) (types.LoginThrottle, error) {
+	return r.t.get(key)
+}
+
+func (r *memoryLoginThrottles) Fail(ctx context.Context, key string, at int64) (types.LoginThrottle, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	throttle, err := r.t.get(key)
+	if err != nil && err != ErrNotFound {
+		return throttle, err
+	}
+	throttle.Key = key
+	throttle.Failures++
+	throttle.LastFailureAt = at
+	return throttle, r.t.put(key, throttle, true)
+}
+
+func (r *memoryLoginThrottles) Reset(ctx context.Context, key string) error {
+	if err := r.t.delete(key); err != nil && err != ErrNotFound {
+		return err
+	}
+	return nil
+}
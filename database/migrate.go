@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// migration rewrites documents stored by an earlier version of the API.
+// It must leave documents already in the new shape alone, since two
+// instances starting together may both run it.
+type migration struct {
+	name string
+	run  func(ctx context.Context, db *mongo.Database, currency string) error
+}
+
+// migrations run in order, each once per database
+var migrations = []migration{
+	{"money-minor-units", migrateMoney},
+	{"address-book", migrateAddresses},
+	{"lower-case-emails", migrateEmails},
+	{"legacy-offers", migrateOffers},
+}
+
+func (m *manager) Migrate(ctx context.Context, currency string) error {
+	applied := m.collection(constant.MigrationCollection)
+	if err := checkCurrency(ctx, applied, currency); err != nil {
+		return err
+	}
+	for _, mig := range migrations {
+		err := applied.FindOne(ctx, bson.M{"_id": mig.name}).Err()
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+
+		if err := mig.run(ctx, m.db, currency); err != nil {
+			return fmt.Errorf("migration %s: %w", mig.name, err)
+		}
+		_, err = applied.InsertOne(ctx, bson.M{"_id": mig.name, "currency": currency, "applied_at": time.Now().Unix()})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCurrency refuses to start with another store currency than the one
+// amounts were converted to, they would be read as amounts of the new one.
+func checkCurrency(ctx context.Context, applied *mongo.Collection, currency string) error {
+	var record struct {
+		Currency string `bson:"currency"`
+	}
+	err := applied.FindOne(ctx, bson.M{"_id": migrations[0].name}).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if record.Currency != currency {
+		return fmt.Errorf("amounts are stored in %s, the store currency can't be changed to %s", record.Currency, currency)
+	}
+	return nil
+}
+
+// numeric matches documents where any of fields still holds a plain number.
+func numeric(fields ...string) bson.M {
+	or := bson.A{}
+	for _, field := range fields {
+		or = append(or, bson.M{field: bson.M{"$type": "number"}})
+	}
+	return bson.M{"$or": or}
+}
+
+// migrateMoney turns the plain numbers prices used to be stored as into
+// types.Money in currency. They counted whole units, so they are scaled
+// to minor units. Order totals used to be floats, their fractions are
+// rounded half up to the minor unit.
+func migrateMoney(ctx context.Context, db *mongo.Database, currency string) error {
+	factor := types.MinorFactor(currency)
+	money := func(path string) bson.M {
+		return bson.M{"$cond": bson.A{
+			bson.M{"$isNumber": path},
+			bson.M{
+				"amount":   bson.M{"$toLong": bson.M{"$floor": bson.M{"$add": bson.A{bson.M{"$multiply": bson.A{path, factor}}, 0.5}}}},
+				"currency": currency,
+			},
+			path,
+		}}
+	}
+	set := func(fields bson.M) mongo.Pipeline {
+		return mongo.Pipeline{{{Key: "$set", Value: fields}}}
+	}
+
+	_, err := db.Collection(constant.ProductCollection).UpdateMany(ctx,
+		numeric("price"),
+		set(bson.M{"price": money("$price")}))
+	if err != nil {
+		return err
+	}
+
+	// fixed coupons kept their amount in discount, it moves to amount_off
+	fixed := bson.M{"$and": bson.A{
+		bson.M{"$eq": bson.A{"$type", constant.CouponFixed}},
+		bson.M{"$isNumber": "$discount"},
+		bson.M{"$eq": bson.A{bson.M{"$type": "$amount_off"}, "missing"}},
+	}}
+	_, err = db.Collection(constant.CouponCollection).UpdateMany(ctx,
+		bson.M{"$or": bson.A{
+			numeric("min_cart_value", "max_discount"),
+			bson.M{"type": constant.CouponFixed, "amount_off": bson.M{"$exists": false}},
+		}},
+		set(bson.M{
+			"min_cart_value": money("$min_cart_value"),
+			"max_discount":   money("$max_discount"),
+			"amount_off":     bson.M{"$cond": bson.A{fixed, money("$discount"), "$amount_off"}},
+			"discount":       bson.M{"$cond": bson.A{fixed, 0, "$discount"}},
+		}))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection(constant.OrderCollection).UpdateMany(ctx,
+		numeric("subtotal", "discount", "total", "payment.amount", "items.list_price", "items.unit_price"),
+		set(bson.M{
+			"subtotal": money("$subtotal"),
+			"discount": money("$discount"),
+			"total":    money("$total"),
+			"items": bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$items", bson.A{}}},
+				"as":    "item",
+				"in": bson.M{"$mergeObjects": bson.A{"$$item", bson.M{
+					"list_price": money("$$item.list_price"),
+					"unit_price": money("$$item.unit_price"),
+				}}},
+			}},
+			"payment": bson.M{"$cond": bson.A{
+				bson.M{"$isNumber": "$payment.amount"},
+				bson.M{"$mergeObjects": bson.A{"$payment", bson.M{"amount": money("$payment.amount")}}},
+				"$payment",
+			}},
+		}))
+	if err != nil {
+		return err
+	}
+
+	// carts no longer store a total, it is worked out when they are read
+	_, err = db.Collection(constant.CartItemCollection).UpdateMany(ctx,
+		bson.M{"total": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"total": ""}})
+	return err
+}
+
+// migrateAddresses moves the free text address users used to have, with
+// its country and region, into their address book as the default one.
+// The address book entry reuses the id of the user, so running it twice
+// adds nothing. Orders keep the text as the only line of their addresses.
+func migrateAddresses(ctx context.Context, db *mongo.Database, currency string) error {
+	cursor, err := db.Collection(constant.UsersCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"address": bson.M{"$type": "string", "$gt": ""}}}},
+		{{Key: "$project", Value: bson.M{
+			"email":            1,
+			"name":             1,
+			"phone":            1,
+			"lines":            bson.A{"$address"},
+			"country":          bson.M{"$ifNull": bson.A{"$country", ""}},
+			"region":           bson.M{"$ifNull": bson.A{"$region", ""}},
+			"default_shipping": bson.M{"$literal": true},
+			"default_billing":  bson.M{"$literal": true},
+			"created_at":       "$updated_at",
+			"updated_at":       "$updated_at",
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           constant.AddressCollection,
+			"on":             "_id",
+			"whenMatched":    "keepExisting",
+			"whenNotMatched": "insert",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := cursor.Close(ctx); err != nil {
+		return err
+	}
+
+	_, err = db.Collection(constant.UsersCollection).UpdateMany(ctx,
+		bson.M{"$or": bson.A{
+			bson.M{"address": bson.M{"$exists": true}},
+			bson.M{"country": bson.M{"$exists": true}},
+			bson.M{"region": bson.M{"$exists": true}},
+		}},
+		bson.M{"$unset": bson.M{"address": "", "country": "", "region": ""}})
+	if err != nil {
+		return err
+	}
+
+	snapshot := bson.M{
+		"lines":   bson.A{"$address"},
+		"country": bson.M{"$ifNull": bson.A{"$tax_region.country", ""}},
+		"region":  bson.M{"$ifNull": bson.A{"$tax_region.region", ""}},
+	}
+	_, err = db.Collection(constant.OrderCollection).UpdateMany(ctx,
+		bson.M{"address": bson.M{"$type": "string"}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"shipping_address": snapshot, "billing_address": snapshot}}},
+			{{Key: "$unset", Value: "address"}},
+		})
+	return err
+}
+
+// migrateEmails lower cases the emails accounts were registered with, and
+// the documents referring to them, since the API now lower cases every
+// email it is sent. Accounts whose emails only differ in case collide on
+// the unique index and make it fail, they have to be merged by hand.
+func migrateEmails(ctx context.Context, db *mongo.Database, currency string) error {
+	upper := bson.M{"email": bson.M{"$regex": "[A-Z]"}}
+	lower := bson.M{"$toLower": "$email"}
+
+	collections := []string{
+		constant.UsersCollection,
+		constant.VerificationsCollection,
+		constant.AddressCollection,
+		constant.CartItemCollection,
+		constant.OrderCollection,
+	}
+	for _, name := range collections {
+		_, err := db.Collection(name).UpdateMany(ctx, upper,
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{"email": lower}}}})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	// coupon usage counters are keyed by the email, they are copied under
+	// the new key before the old ones go
+	usages := db.Collection(constant.CouponUsageCollection)
+	cursor, err := usages.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: upper}},
+		{{Key: "$set", Value: bson.M{
+			"_id":   bson.M{"$concat": bson.A{"$coupon_id", ":", lower}},
+			"email": lower,
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           constant.CouponUsageCollection,
+			"on":             "_id",
+			"whenMatched":    "keepExisting",
+			"whenNotMatched": "insert",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := cursor.Close(ctx); err != nil {
+		return err
+	}
+	_, err = usages.DeleteMany(ctx, upper)
+	return err
+}
+
+// migrateOffers deletes the offers from before categories had ObjectIDs.
+// They point at a numeric category id nothing maps to a category anymore,
+// so they could never apply.
+func migrateOffers(ctx context.Context, db *mongo.Database, currency string) error {
+	_, err := db.Collection(constant.OfferCollection).DeleteMany(ctx, bson.M{"category_id": bson.M{"$type": "number"}})
+	return err
+}
@@ -0,0 +1,985 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type manager struct {
+	connection *mongo.Client
+	db         *mongo.Database
+}
+
+// NewMongoManager builds a Manager on top of an already connected client
+// and makes sure the indexes the repositories rely on exist.
+func NewMongoManager(ctx context.Context, client *mongo.Client, dbName string) (Manager, error) {
+	m := &manager{connection: client, db: client.Database(dbName)}
+
+	// unique keys the repositories rely on
+	unique := map[string]string{
+		constant.UsersCollection:         "email",
+		constant.CartItemCollection:      "email",
+		constant.VerificationsCollection: "email",
+		constant.PasswordResetCollection: "token_hash",
+		constant.CouponCollection:        "name",
+	}
+	for collection, key := range unique {
+		_, err := m.collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: key, Value: 1}},
+			Options: options.Index().SetUnique(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// categories created before slugs existed have none
+	_, err := m.collection(constant.CategoryCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"slug": bson.M{"$type": "string", "$gt": ""}}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// a checkout retried with the same key must not place a second order
+	_, err = m.collection(constant.OrderCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}, {Key: "idempotency_key", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$type": "string"}}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// address books are read by customer
+	_, err = m.collection(constant.AddressCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "email", Value: 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *manager) collection(name string) *mongo.Collection {
+	return m.db.Collection(name)
+}
+
+func (m *manager) Users() UserRepository {
+	return mongoUsers{m.collection(constant.UsersCollection)}
+}
+
+func (m *manager) Addresses() AddressRepository {
+	return mongoAddresses{m.collection(constant.AddressCollection)}
+}
+
+func (m *manager) Products() ProductRepository {
+	return mongoProducts{m.collection(constant.ProductCollection)}
+}
+
+func (m *manager) Categories() CategoryRepository {
+	return mongoCategories{m.collection(constant.CategoryCollection)}
+}
+
+func (m *manager) Carts() CartRepository {
+	return mongoCarts{m.collection(constant.CartItemCollection)}
+}
+
+func (m *manager) Coupons() CouponRepository {
+	return mongoCoupons{m.collection(constant.CouponCollection), m.collection(constant.CouponUsageCollection)}
+}
+
+func (m *manager) Offers() OfferRepository {
+	return mongoOffers{m.collection(constant.OfferCollection)}
+}
+
+func (m *manager) TaxRules() TaxRuleRepository {
+	return mongoTaxRules{m.collection(constant.TaxRuleCollection)}
+}
+
+func (m *manager) ShippingZones() ShippingZoneRepository {
+	return mongoShippingZones{m.collection(constant.ShippingZoneCollection)}
+}
+
+func (m *manager) ShippingMethods() ShippingMethodRepository {
+	return mongoShippingMethods{m.collection(constant.ShippingMethodCollection)}
+}
+
+func (m *manager) Orders() OrderRepository {
+	return mongoOrders{m.collection(constant.OrderCollection)}
+}
+
+func (m *manager) Sessions() SessionRepository {
+	return mongoSessions{m.collection(constant.SessionCollection)}
+}
+
+func (m *manager) Verifications() VerificationRepository {
+	return mongoVerifications{m.collection(constant.VerificationsCollection)}
+}
+
+func (m *manager) PasswordResets() PasswordResetRepository {
+	return mongoPasswordResets{m.collection(constant.PasswordResetCollection)}
+}
+
+func (m *manager) LoginAttempts() LoginAttemptRepository {
+	return mongoLoginAttempts{m.collection(constant.LoginAttemptCollection)}
+}
+
+func (m *manager) LoginThrottles() LoginThrottleRepository {
+	return mongoLoginThrottles{m.collection(constant.LoginThrottleCollection)}
+}
+
+func (m *manager) Disconnect(ctx context.Context) error {
+	return m.connection.Disconnect(ctx)
+}
+
+// helpers shared by the collection backed repositories
+
+func findOne[T any](ctx context.Context, coll *mongo.Collection, filter interface{}) (T, error) {
+	var out T
+	err := coll.FindOne(ctx, filter).Decode(&out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return out, ErrNotFound
+	}
+	return out, err
+}
+
+func findAll[T any](ctx context.Context, coll *mongo.Collection, filter interface{}, opts ...*options.FindOptions) ([]T, error) {
+	cursor, err := coll.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	out := []T{}
+	if err := cursor.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func insertOne(ctx context.Context, coll *mongo.Collection, doc interface{}) error {
+	_, err := coll.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func replaceOne(ctx context.Context, coll *mongo.Collection, filter interface{}, doc interface{}) error {
+	res, err := coll.ReplaceOne(ctx, filter, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// findOneAndUpdate applies update to the document matching filter and
+// returns it after the change.
+func findOneAndUpdate[T any](ctx context.Context, coll *mongo.Collection, filter interface{}, update interface{}) (T, error) {
+	var out T
+	err := coll.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return out, ErrNotFound
+	}
+	return out, err
+}
+
+// updateOne applies update to the document matching filter, ErrNotFound
+// when there is none.
+func updateOne(ctx context.Context, coll *mongo.Collection, filter interface{}, update interface{}) error {
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func deleteOne(ctx context.Context, coll *mongo.Collection, filter interface{}) error {
+	res, err := coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func newID(id *primitive.ObjectID) {
+	if id.IsZero() {
+		*id = primitive.NewObjectID()
+	}
+}
+
+// byCreation sorts documents in the order they were inserted
+var byCreation = options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+// users
+
+type mongoUsers struct{ coll *mongo.Collection }
+
+func (r mongoUsers) Create(ctx context.Context, user *types.User) error {
+	newID(&user.Id)
+	return insertOne(ctx, r.coll, user)
+}
+
+func (r mongoUsers) FindByID(ctx context.Context, id primitive.ObjectID) (types.User, error) {
+	return findOne[types.User](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoUsers) FindByEmail(ctx context.Context, email string) (types.User, error) {
+	return findOne[types.User](ctx, r.coll, bson.M{"email": email})
+}
+
+func (r mongoUsers) List(ctx context.Context) ([]types.User, error) {
+	return findAll[types.User](ctx, r.coll, bson.M{})
+}
+
+func (r mongoUsers) Update(ctx context.Context, user types.User) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": user.Id}, user)
+}
+
+func (r mongoUsers) UpdatePassword(ctx context.Context, id primitive.ObjectID, oldHash, newHash string) error {
+	return updateOne(ctx, r.coll,
+		bson.M{"_id": id, "password": oldHash},
+		bson.M{"$set": bson.M{"password": newHash}},
+	)
+}
+
+func (r mongoUsers) UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error {
+	return updateOne(ctx, r.coll,
+		bson.M{"_id": id, "$or": bson.A{
+			bson.M{"totp_last_step": bson.M{"$lt": step}},
+			bson.M{"totp_last_step": bson.M{"$exists": false}},
+		}},
+		bson.M{"$set": bson.M{"totp_last_step": step}},
+	)
+}
+
+func (r mongoUsers) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error {
+	return updateOne(ctx, r.coll,
+		bson.M{"_id": id, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+}
+
+// addresses
+
+type mongoAddresses struct{ coll *mongo.Collection }
+
+func (r mongoAddresses) Create(ctx context.Context, address *types.Address) error {
+	newID(&address.ID)
+	return insertOne(ctx, r.coll, address)
+}
+
+func (r mongoAddresses) FindByID(ctx context.Context, id primitive.ObjectID) (types.Address, error) {
+	return findOne[types.Address](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoAddresses) ListByEmail(ctx context.Context, email string) ([]types.Address, error) {
+	return findAll[types.Address](ctx, r.coll, bson.M{"email": email}, byCreation)
+}
+
+func (r mongoAddresses) Update(ctx context.Context, address types.Address) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": address.ID}, address)
+}
+
+func (r mongoAddresses) ClearDefaults(ctx context.Context, address types.Address) error {
+	set := bson.M{"updated_at": address.UpdatedAt}
+	held := bson.A{}
+	if address.DefaultShipping {
+		set["default_shipping"] = false
+		held = append(held, bson.M{"default_shipping": true})
+	}
+	if address.DefaultBilling {
+		set["default_billing"] = false
+		held = append(held, bson.M{"default_billing": true})
+	}
+	if len(held) == 0 {
+		return nil
+	}
+	_, err := r.coll.UpdateMany(ctx,
+		bson.M{"email": address.Email, "_id": bson.M{"$ne": address.ID}, "$or": held},
+		bson.M{"$set": set})
+	return err
+}
+
+func (r mongoAddresses) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.coll, bson.M{"_id": id})
+}
+
+// products
+
+type mongoProducts struct{ coll *mongo.Collection }
+
+func (r mongoProducts) Create(ctx context.Context, product *types.Product) error {
+	newID(&product.ID)
+	return insertOne(ctx, r.coll, product)
+}
+
+func (r mongoProducts) FindByID(ctx context.Context, id primitive.ObjectID) (types.Product, error) {
+	return findOne[types.Product](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoProducts) Find(ctx context.Context, query ProductQuery) ([]types.Product, int64, error) {
+	filter := bson.M{}
+	if query.Search != "" {
+		pattern := regexp.QuoteMeta(query.Search)
+		filter["$or"] = []bson.M{
+			{"name": bson.M{"$regex": pattern, "$options": "i"}},
+			{"description": bson.M{"$regex": pattern, "$options": "i"}},
+		}
+	}
+	if query.InStock {
+		filter["stock"] = bson.M{"$gt": 0}
+	}
+	if query.CategoryIDs != nil {
+		filter["category_id"] = bson.M{"$in": query.CategoryIDs}
+	}
+	if !query.IncludeHidden {
+		filter["hidden"] = bson.M{"$ne": true}
+	}
+	if !query.IncludeDeleted {
+		filter["deleted_at"] = bson.M{"$not": bson.M{"$gt": 0}}
+	}
+
+	findOptions := options.Find().SetSkip(query.Skip)
+	if query.Limit > 0 {
+		findOptions.SetLimit(query.Limit)
+	}
+
+	products, err := findAll[types.Product](ctx, r.coll, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := r.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return products, count, nil
+}
+
+// notDeleted matches products that are not deleted
+var notDeleted = bson.M{"$not": bson.M{"$gt": 0}}
+
+func (r mongoProducts) UpdateDetails(ctx context.Context, product types.Product) (types.Product, error) {
+	return findOneAndUpdate[types.Product](ctx, r.coll,
+		bson.M{"_id": product.ID, "deleted_at": notDeleted},
+		bson.M{"$set": bson.M{
+			"name":        product.Name,
+			"price":       product.Price,
+			"description": product.Description,
+			"images":      product.Images,
+			"keywords":    product.Keywords,
+			"category_id": product.CategoryId,
+			"weight":      product.Weight,
+			"dimensions":  product.Dimensions,
+			"hidden":      product.Hidden,
+			"updated_at":  product.UpdatedAt,
+		}})
+}
+
+func (r mongoProducts) MarkDeleted(ctx context.Context, id primitive.ObjectID, at int64) error {
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": id, "deleted_at": notDeleted},
+		bson.M{"$set": bson.M{"deleted_at": at, "updated_at": at}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// visible matches the products of id customers can see
+func visible(id primitive.ObjectID) bson.M {
+	return bson.M{"_id": id, "hidden": bson.M{"$ne": true}, "deleted_at": notDeleted}
+}
+
+func (r mongoProducts) AddRating(ctx context.Context, id primitive.ObjectID, rating float64) error {
+	count := bson.M{"$ifNull": bson.A{"$num_rating", 0}}
+	total := bson.M{"$add": bson.A{bson.M{"$multiply": bson.A{bson.M{"$ifNull": bson.A{"$rating", 0}}, count}}, rating}}
+	res, err := r.coll.UpdateOne(ctx, visible(id), mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"rating":     bson.M{"$divide": bson.A{total, bson.M{"$add": bson.A{count, 1}}}},
+			"num_rating": bson.M{"$add": bson.A{count, 1}},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r mongoProducts) AddComment(ctx context.Context, id primitive.ObjectID, comment types.Comment) error {
+	res, err := r.coll.UpdateOne(ctx, visible(id), bson.M{"$push": bson.M{"comments": comment}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r mongoProducts) AdjustStock(ctx context.Context, id primitive.ObjectID, delta int) (types.Product, error) {
+	filter := bson.M{"_id": id, "deleted_at": notDeleted}
+	if delta < 0 {
+		filter["stock"] = bson.M{"$gte": -delta}
+	}
+
+	var product types.Product
+	err := r.coll.FindOneAndUpdate(ctx, filter,
+		bson.M{"$inc": bson.M{"stock": delta}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&product)
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return product, err
+	}
+
+	// telling a missing product from a short stock
+	existing, err := r.FindByID(ctx, id)
+	if err != nil {
+		return types.Product{}, err
+	}
+	if existing.DeletedAt > 0 {
+		return types.Product{}, ErrNotFound
+	}
+	return types.Product{}, ErrInsufficientStock
+}
+
+func (r mongoProducts) ReassignCategory(ctx context.Context, from, to string) (int64, error) {
+	res, err := r.coll.UpdateMany(ctx, bson.M{"category_id": from}, bson.M{"$set": bson.M{"category_id": to}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// categories
+
+type mongoCategories struct{ coll *mongo.Collection }
+
+func (r mongoCategories) Create(ctx context.Context, category *types.Category) error {
+	newID(&category.ID)
+	return insertOne(ctx, r.coll, category)
+}
+
+func (r mongoCategories) FindByID(ctx context.Context, id primitive.ObjectID) (types.Category, error) {
+	return findOne[types.Category](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoCategories) FindBySlug(ctx context.Context, slug string) (types.Category, error) {
+	return findOne[types.Category](ctx, r.coll, bson.M{"slug": slug})
+}
+
+func (r mongoCategories) List(ctx context.Context) ([]types.Category, error) {
+	return findAll[types.Category](ctx, r.coll, bson.M{}, options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "category", Value: 1}}))
+}
+
+func (r mongoCategories) Update(ctx context.Context, category types.Category) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": category.ID}, category)
+}
+
+func (r mongoCategories) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.coll, bson.M{"_id": id})
+}
+
+// carts
+
+type mongoCarts struct{ coll *mongo.Collection }
+
+func (r mongoCarts) FindByEmail(ctx context.Context, email string) (types.CartItem, error) {
+	return findOne[types.CartItem](ctx, r.coll, bson.M{"email": email})
+}
+
+func (r mongoCarts) Save(ctx context.Context, cart types.CartItem) error {
+	_, err := r.coll.ReplaceOne(ctx, bson.M{"email": cart.Email}, cart, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r mongoCarts) Replace(ctx context.Context, old, cart types.CartItem) error {
+	return replaceOne(ctx, r.coll, bson.M{
+		"email":       old.Email,
+		"products":    old.Products,
+		"coupon":      old.Coupon,
+		"checked_out": old.ChekedOut,
+	}, cart)
+}
+
+func (r mongoCarts) Delete(ctx context.Context, email string) error {
+	return deleteOne(ctx, r.coll, bson.M{"email": email})
+}
+
+// coupons
+
+type mongoCoupons struct{ coll, usages *mongo.Collection }
+
+// couponUsageKey identifies the usage counter of one customer.
+func couponUsageKey(id primitive.ObjectID, email string) string {
+	return id.Hex() + ":" + email
+}
+
+func (r mongoCoupons) Create(ctx context.Context, coupon *types.Coupon) error {
+	newID(&coupon.ID)
+	return insertOne(ctx, r.coll, coupon)
+}
+
+func (r mongoCoupons) FindByID(ctx context.Context, id primitive.ObjectID) (types.Coupon, error) {
+	return findOne[types.Coupon](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoCoupons) FindByName(ctx context.Context, name string) (types.Coupon, error) {
+	return findOne[types.Coupon](ctx, r.coll, bson.M{"name": name})
+}
+
+func (r mongoCoupons) List(ctx context.Context) ([]types.Coupon, error) {
+	return findAll[types.Coupon](ctx, r.coll, bson.M{})
+}
+
+func (r mongoCoupons) Update(ctx context.Context, coupon types.Coupon) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": coupon.ID}, coupon)
+}
+
+func (r mongoCoupons) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoCoupons) Redeem(ctx context.Context, coupon types.Coupon, email string) error {
+	key := couponUsageKey(coupon.ID, email)
+	filter := bson.M{"_id": key}
+	if coupon.PerUserLimit > 0 {
+		// once the customer used all of theirs the filter no longer
+		// matches and the upsert collides with their counter
+		filter["count"] = bson.M{"$lt": coupon.PerUserLimit}
+	}
+	_, err := r.usages.UpdateOne(ctx, filter,
+		bson.M{"$inc": bson.M{"count": 1}, "$set": bson.M{"coupon_id": coupon.ID.Hex(), "email": email}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLimitReached
+	}
+	if err != nil {
+		return err
+	}
+
+	filter = bson.M{"_id": coupon.ID}
+	if coupon.UsageLimit > 0 {
+		filter["used_count"] = bson.M{"$lt": coupon.UsageLimit}
+	}
+	res, err := r.coll.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"used_count": 1}})
+	if err == nil && res.MatchedCount == 0 {
+		err = ErrLimitReached
+	}
+	if err != nil {
+		// hand the customer's use back
+		_, _ = r.usages.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$inc": bson.M{"count": -1}})
+		return err
+	}
+	return nil
+}
+
+func (r mongoCoupons) Release(ctx context.Context, coupon types.Coupon, email string) error {
+	_, err := r.usages.UpdateOne(ctx,
+		bson.M{"_id": couponUsageKey(coupon.ID, email), "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = r.coll.UpdateOne(ctx,
+		bson.M{"_id": coupon.ID, "used_count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"used_count": -1}},
+	)
+	return err
+}
+
+func (r mongoCoupons) UsedBy(ctx context.Context, id primitive.ObjectID, email string) (int, error) {
+	usage, err := findOne[types.CouponUsage](ctx, r.usages, bson.M{"_id": couponUsageKey(id, email)})
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	return usage.Count, err
+}
+
+// offers
+
+type mongoOffers struct{ coll *mongo.Collection }
+
+func (r mongoOffers) Create(ctx context.Context, offer *types.Offer) error {
+	newID(&offer.ID)
+	return insertOne(ctx, r.coll, offer)
+}
+
+func (r mongoOffers) FindByID(ctx context.Context, id primitive.ObjectID) (types.Offer, error) {
+	return findOne[types.Offer](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoOffers) List(ctx context.Context) ([]types.Offer, error) {
+	return findAll[types.Offer](ctx, r.coll, bson.M{})
+}
+
+func (r mongoOffers) Running(ctx context.Context, now int64) ([]types.Offer, error) {
+	return findAll[types.Offer](ctx, r.coll, bson.M{
+		"starts_at": bson.M{"$lte": now},
+		"$or":       bson.A{bson.M{"ends_at": 0}, bson.M{"ends_at": bson.M{"$gt": now}}},
+	})
+}
+
+func (r mongoOffers) Update(ctx context.Context, offer types.Offer) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": offer.ID}, offer)
+}
+
+func (r mongoOffers) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.coll, bson.M{"_id": id})
+}
+
+// tax rules
+
+type mongoTaxRules struct{ coll *mongo.Collection }
+
+func (r mongoTaxRules) Create(ctx context.Context, rule *types.TaxRule) error {
+	newID(&rule.ID)
+	return insertOne(ctx, r.coll, rule)
+}
+
+func (r mongoTaxRules) FindByID(ctx context.Context, id primitive.ObjectID) (types.TaxRule, error) {
+	return findOne[types.TaxRule](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoTaxRules) List(ctx context.Context) ([]types.TaxRule, error) {
+	return findAll[types.TaxRule](ctx, r.coll, bson.M{}, byCreation)
+}
+
+func (r mongoTaxRules) Update(ctx context.Context, rule types.TaxRule) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": rule.ID}, rule)
+}
+
+func (r mongoTaxRules) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.coll, bson.M{"_id": id})
+}
+
+// shipping
+
+type mongoShippingZones struct{ coll *mongo.Collection }
+
+func (r mongoShippingZones) Create(ctx context.Context, zone *types.ShippingZone) error {
+	newID(&zone.ID)
+	return insertOne(ctx, r.coll, zone)
+}
+
+func (r mongoShippingZones) FindByID(ctx context.Context, id primitive.ObjectID) (types.ShippingZone, error) {
+	return findOne[types.ShippingZone](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoShippingZones) List(ctx context.Context) ([]types.ShippingZone, error) {
+	return findAll[types.ShippingZone](ctx, r.coll, bson.M{}, byCreation)
+}
+
+func (r mongoShippingZones) Update(ctx context.Context, zone types.ShippingZone) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": zone.ID}, zone)
+}
+
+func (r mongoShippingZones) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.coll, bson.M{"_id": id})
+}
+
+type mongoShippingMethods struct{ coll *mongo.Collection }
+
+func (r mongoShippingMethods) Create(ctx context.Context, method *types.ShippingMethod) error {
+	newID(&method.ID)
+	return insertOne(ctx, r.coll, method)
+}
+
+func (r mongoShippingMethods) FindByID(ctx context.Context, id primitive.ObjectID) (types.ShippingMethod, error) {
+	return findOne[types.ShippingMethod](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoShippingMethods) List(ctx context.Context) ([]types.ShippingMethod, error) {
+	return findAll[types.ShippingMethod](ctx, r.coll, bson.M{}, byCreation)
+}
+
+func (r mongoShippingMethods) ListByZone(ctx context.Context, zoneID string) ([]types.ShippingMethod, error) {
+	return findAll[types.ShippingMethod](ctx, r.coll, bson.M{"zone_id": zoneID}, byCreation)
+}
+
+func (r mongoShippingMethods) Update(ctx context.Context, method types.ShippingMethod) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": method.ID}, method)
+}
+
+func (r mongoShippingMethods) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.coll, bson.M{"_id": id})
+}
+
+// orders
+
+type mongoOrders struct{ coll *mongo.Collection }
+
+func (r mongoOrders) Create(ctx context.Context, order *types.Order) error {
+	newID(&order.ID)
+	return insertOne(ctx, r.coll, order)
+}
+
+func (r mongoOrders) FindByID(ctx context.Context, id primitive.ObjectID) (types.Order, error) {
+	return findOne[types.Order](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoOrders) FindByIdempotencyKey(ctx context.Context, email, key string) (types.Order, error) {
+	return findOne[types.Order](ctx, r.coll, bson.M{"email": email, "idempotency_key": key})
+}
+
+func (r mongoOrders) ListByEmail(ctx context.Context, email string) ([]types.Order, error) {
+	return findAll[types.Order](ctx, r.coll, bson.M{"email": email}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+}
+
+func (r mongoOrders) Find(ctx context.Context, query OrderQuery) ([]types.Order, int64, error) {
+	filter := bson.M{}
+	if query.Email != "" {
+		filter["email"] = query.Email
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+	created := bson.M{}
+	if query.From > 0 {
+		created["$gte"] = query.From
+	}
+	if query.To > 0 {
+		created["$lt"] = query.To
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetSkip(query.Skip)
+	if query.Limit > 0 {
+		findOptions.SetLimit(query.Limit)
+	}
+
+	orders, err := findAll[types.Order](ctx, r.coll, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := r.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, count, nil
+}
+
+func (r mongoOrders) Update(ctx context.Context, order types.Order) error {
+	return replaceOne(ctx, r.coll, bson.M{"_id": order.ID}, order)
+}
+
+func (r mongoOrders) Transition(ctx context.Context, id primitive.ObjectID, from string, event types.OrderEvent) (types.Order, error) {
+	var order types.Order
+	err := r.coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": from},
+		bson.M{
+			"$set":  bson.M{"status": event.Status, "updated_at": event.At},
+			"$push": bson.M{"history": event},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return order, ErrNotFound
+	}
+	return order, err
+}
+
+func (r mongoOrders) SetPayment(ctx context.Context, id primitive.ObjectID, payment types.Payment) (types.Order, error) {
+	var order types.Order
+	err := r.coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"payment": payment}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return order, ErrNotFound
+	}
+	return order, err
+}
+
+// sessions
+
+type mongoSessions struct{ coll *mongo.Collection }
+
+func (r mongoSessions) Create(ctx context.Context, session *types.Session) error {
+	newID(&session.ID)
+	return insertOne(ctx, r.coll, session)
+}
+
+func (r mongoSessions) FindByID(ctx context.Context, id primitive.ObjectID) (types.Session, error) {
+	return findOne[types.Session](ctx, r.coll, bson.M{"_id": id})
+}
+
+func (r mongoSessions) Rotate(ctx context.Context, id primitive.ObjectID, oldHash, newHash string, at int64) error {
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": id, "refresh_hash": oldHash, "revoked_at": 0},
+		bson.M{
+			"$set":  bson.M{"refresh_hash": newHash, "last_used_at": at},
+			"$push": bson.M{"previous_hashes": bson.M{"$each": bson.A{oldHash}, "$slice": -constant.RefreshHashHistory}},
+		})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r mongoSessions) Revoke(ctx context.Context, id primitive.ObjectID, reason string, at int64) error {
+	_, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": 0},
+		bson.M{"$set": bson.M{"revoked_at": at, "revoked_reason": reason}})
+	return err
+}
+
+func (r mongoSessions) RevokeAllForUser(ctx context.Context, userID primitive.ObjectID, reason string, at int64) error {
+	_, err := r.coll.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked_at": 0},
+		bson.M{"$set": bson.M{"revoked_at": at, "revoked_reason": reason}})
+	return err
+}
+
+// verifications
+
+type mongoVerifications struct{ coll *mongo.Collection }
+
+func (r mongoVerifications) FindByEmail(ctx context.Context, email string) (types.Verification, error) {
+	return findOne[types.Verification](ctx, r.coll, bson.M{"email": email})
+}
+
+func (r mongoVerifications) Issue(ctx context.Context, verification types.Verification, newWindow bool) error {
+	set := bson.M{
+		"otp_hash":     verification.OtpHash,
+		"status":       verification.Status,
+		"window_start": verification.WindowStart,
+		"created_at":   verification.CreatedAt,
+		"expires_at":   verification.ExpiresAt,
+	}
+	update := bson.M{"$set": set}
+	if newWindow {
+		set["attempts"], set["sends"] = 0, 1
+	} else {
+		update["$inc"] = bson.M{"sends": 1}
+	}
+	_, err := r.coll.UpdateOne(ctx, bson.M{"email": verification.Email}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r mongoVerifications) UseAttempt(ctx context.Context, email string, max int) error {
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"email": email, "attempts": bson.M{"$lt": max}},
+		bson.M{"$inc": bson.M{"attempts": 1}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrLimitReached
+	}
+	return nil
+}
+
+func (r mongoVerifications) MarkVerified(ctx context.Context, email string) error {
+	return updateOne(ctx, r.coll, bson.M{"email": email}, bson.M{"$set": bson.M{"status": true, "otp_hash": ""}})
+}
+
+// password resets
+
+type mongoPasswordResets struct{ coll *mongo.Collection }
+
+func (r mongoPasswordResets) Create(ctx context.Context, reset *types.PasswordReset) error {
+	newID(&reset.ID)
+	return insertOne(ctx, r.coll, reset)
+}
+
+func (r mongoPasswordResets) FindByHash(ctx context.Context, tokenHash string) (types.PasswordReset, error) {
+	return findOne[types.PasswordReset](ctx, r.coll, bson.M{"token_hash": tokenHash})
+}
+
+func (r mongoPasswordResets) MarkUsed(ctx context.Context, id primitive.ObjectID, at int64) error {
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": id, "used_at": 0},
+		bson.M{"$set": bson.M{"used_at": at}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r mongoPasswordResets) InvalidateForUser(ctx context.Context, userID primitive.ObjectID, at int64) error {
+	_, err := r.coll.UpdateMany(ctx,
+		bson.M{"user_id": userID, "used_at": 0},
+		bson.M{"$set": bson.M{"used_at": at}})
+	return err
+}
+
+// login attempts
+
+type mongoLoginAttempts struct{ coll *mongo.Collection }
+
+func (r mongoLoginAttempts) Create(ctx context.Context, attempt *types.LoginAttempt) error {
+	newID(&attempt.ID)
+	return insertOne(ctx, r.coll, attempt)
+}
+
+func (r mongoLoginAttempts) Recent(ctx context.Context, email string, limit int64) ([]types.LoginAttempt, error) {
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	return findAll[types.LoginAttempt](ctx, r.coll, bson.M{"email": email}, findOptions)
+}
+
+// login throttles
+
+type mongoLoginThrottles struct{ coll *mongo.Collection }
+
+func (r mongoLoginThrottles) Get(ctx context.Context, key string) (types.LoginThrottle, error) {
+	return findOne[types.LoginThrottle](ctx, r.coll, bson.M{"_id": key})
+}
+
+func (r mongoLoginThrottles) Fail(ctx context.Context, key string, at int64) (types.LoginThrottle, error) {
+	var throttle types.LoginThrottle
+	err := r.coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"last_failure_at": at}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&throttle)
+	return throttle, err
+}
+
+func (r mongoLoginThrottles) Reset(ctx context.Context, key string) error {
+	_, err := r.coll.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+func TestOrderCreateIdempotency(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		first   types.Order
+		second  types.Order
+		wantErr error
+	}{
+		{
+			name:    "same key and customer",
+			first:   types.Order{Email: "a@x", IdempotencyKey: "k1"},
+			second:  types.Order{Email: "a@x", IdempotencyKey: "k1"},
+			wantErr: ErrDuplicate,
+		},
+		{
+			name:   "same key, other customer",
+			first:  types.Order{Email: "a@x", IdempotencyKey: "k1"},
+			second: types.Order{Email: "b@x", IdempotencyKey: "k1"},
+		},
+		{
+			name:   "other key",
+			first:  types.Order{Email: "a@x", IdempotencyKey: "k1"},
+			second: types.Order{Email: "a@x", IdempotencyKey: "k2"},
+		},
+		{
+			name:   "no key",
+			first:  types.Order{Email: "a@x"},
+			second: types.Order{Email: "a@x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryManager()
+			if err := db.Orders().Create(ctx, &tt.first); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Orders().Create(ctx, &tt.second); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("second Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				found, err := db.Orders().FindByIdempotencyKey(ctx, tt.first.Email, tt.first.IdempotencyKey)
+				if err != nil || found.ID != tt.first.ID {
+					t.Errorf("FindByIdempotencyKey() = %v, %v, want the first order", found.ID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderTransition(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		from       string
+		to         string
+		wantErr    error
+		wantStatus string
+	}{
+		{name: "from the current status", from: constant.OrderPendingPayment, to: constant.OrderPaid, wantStatus: constant.OrderPaid},
+		{name: "from a stale status", from: constant.OrderPaid, to: constant.OrderPacked, wantErr: ErrNotFound, wantStatus: constant.OrderPendingPayment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryManager()
+			order := types.Order{
+				Email:   "a@x",
+				Status:  constant.OrderPendingPayment,
+				History: []types.OrderEvent{{Status: constant.OrderPendingPayment, At: 1}},
+			}
+			if err := db.Orders().Create(ctx, &order); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := db.Orders().Transition(ctx, order.ID, tt.from, types.OrderEvent{Status: tt.to, At: 2, By: "admin@x"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Transition() error = %v, want %v", err, tt.wantErr)
+			}
+			stored, _ := db.Orders().FindByID(ctx, order.ID)
+			if stored.Status != tt.wantStatus {
+				t.Errorf("status %q, want %q", stored.Status, tt.wantStatus)
+			}
+			wantHistory := 1
+			if tt.wantErr == nil {
+				wantHistory = 2
+			}
+			if len(stored.History) != wantHistory {
+				t.Errorf("history has %d events, want %d", len(stored.History), wantHistory)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAdjustStock(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		stock     int
+		deleted   bool
+		delta     int
+		wantStock int
+		wantErr   error
+	}{
+		{name: "take some", stock: 5, delta: -3, wantStock: 2},
+		{name: "take all", stock: 5, delta: -5, wantStock: 0},
+		{name: "take too many", stock: 5, delta: -6, wantStock: 5, wantErr: ErrInsufficientStock},
+		{name: "give back", stock: 5, delta: 4, wantStock: 9},
+		{name: "deleted product", stock: 5, deleted: true, delta: -1, wantStock: 5, wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewMemoryManager()
+			product := types.Product{Name: "shirt", Stock: tt.stock}
+			if tt.deleted {
+				product.DeletedAt = 1
+			}
+			if err := db.Products().Create(ctx, &product); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := db.Products().AdjustStock(ctx, product.ID, tt.delta)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AdjustStock() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Stock != tt.wantStock {
+				t.Errorf("AdjustStock() returned stock %d, want %d", got.Stock, tt.wantStock)
+			}
+			stored, err := db.Products().FindByID(ctx, product.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Stock != tt.wantStock {
+				t.Errorf("stored stock %d, want %d", stored.Stock, tt.wantStock)
+			}
+		})
+	}
+
+	t.Run("unknown product", func(t *testing.T) {
+		_, err := NewMemoryManager().Products().AdjustStock(ctx, primitive.NewObjectID(), 1)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("AdjustStock() error = %v, want %v", err, ErrNotFound)
+		}
+	})
+}
+
+func TestAdjustStockConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryManager()
+	product := types.Product{Name: "shirt", Stock: 5}
+	if err := db.Products().Create(ctx, &product); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sold := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := db.Products().AdjustStock(ctx, product.ID, -1); err == nil {
+				mu.Lock()
+				sold++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored, _ := db.Products().FindByID(ctx, product.ID)
+	if sold != 5 || stored.Stock != 0 {
+		t.Errorf("sold %d leaving %d in stock, want 5 leaving 0", sold, stored.Stock)
+	}
+}
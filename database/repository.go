@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNotFound is returned when a lookup matches no record.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write would break a unique constraint.
+	ErrDuplicate = errors.New("record already exists")
+	// ErrInsufficientStock is returned when a stock change would go below zero.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrLimitReached is returned when a coupon has no uses left, overall
+	// or for the customer.
+	ErrLimitReached = errors.New("usage limit reached")
+)
+
+// ProductQuery narrows down a product listing.
+type ProductQuery struct {
+	// Search matches name or description, case-insensitively.
+	Search string
+	// InStock only returns products with stock left.
+	InStock bool
+	// CategoryIDs only returns products in one of these categories.
+	CategoryIDs []string
+	// hidden and deleted products are left out unless asked for
+	IncludeHidden  bool
+	IncludeDeleted bool
+	Skip           int64
+	Limit          int64
+}
+
+// OrderQuery narrows down an order listing, zero values do not filter.
+type OrderQuery struct {
+	Email  string
+	Status string
+	// From and To bound the creation time, To is exclusive
+	From  int64
+	To    int64
+	Skip  int64
+	Limit int64
+}
+
+type UserRepository interface {
+	Create(ctx context.Context, user *types.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.User, error)
+	FindByEmail(ctx context.Context, email string) (types.User, error)
+	List(ctx context.Context) ([]types.User, error)
+	Update(ctx context.Context, user types.User) error
+	// UpdatePassword replaces the password hash of the user. It fails with
+	// ErrNotFound when the stored hash is no longer oldHash, a password
+	// changed in the meantime stays.
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, oldHash, newHash string) error
+	// UseTOTPStep records step as the last authenticator code used. It
+	// fails with ErrNotFound unless step is later than the one recorded,
+	// so a code signs in once.
+	UseTOTPStep(ctx context.Context, id primitive.ObjectID, step int64) error
+	// UseRecoveryCode removes the recovery code with the given hash. It
+	// fails with ErrNotFound when the user no longer has it.
+	UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) error
+}
+
+// AddressRepository stores the address books of the customers,
+// ListByEmail returns a book in the order it was filled.
+type AddressRepository interface {
+	Create(ctx context.Context, address *types.Address) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Address, error)
+	ListByEmail(ctx context.Context, email string) ([]types.Address, error)
+	Update(ctx context.Context, address types.Address) error
+	// ClearDefaults takes the default flags address holds away from the
+	// other addresses of its book, in one write.
+	ClearDefaults(ctx context.Context, address types.Address) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type ProductRepository interface {
+	Create(ctx context.Context, product *types.Product) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Product, error)
+	// Find returns one page of matching products and the total match count.
+	Find(ctx context.Context, query ProductQuery) ([]types.Product, int64, error)
+	// UpdateDetails writes what the back office edits of a product that is
+	// not deleted and returns the product after the change. Stock, ratings
+	// and comments are left alone, checkouts and customers change them
+	// concurrently.
+	UpdateDetails(ctx context.Context, product types.Product) (types.Product, error)
+	// MarkDeleted sets the deletion time of a product not deleted yet.
+	MarkDeleted(ctx context.Context, id primitive.ObjectID, at int64) error
+	// AddRating folds rating into the average rating of a product
+	// customers can see, and AddComment appends a comment to it. Both
+	// are atomic and fail with ErrNotFound for other products.
+	AddRating(ctx context.Context, id primitive.ObjectID, rating float64) error
+	AddComment(ctx context.Context, id primitive.ObjectID, comment types.Comment) error
+	// AdjustStock atomically adds delta to the stock of a product that is
+	// not deleted and returns the product after the change. It fails with
+	// ErrInsufficientStock instead of going below zero.
+	AdjustStock(ctx context.Context, id primitive.ObjectID, delta int) (types.Product, error)
+	// ReassignCategory moves every product of a category, deleted ones
+	// included, to another and returns how many were moved.
+	ReassignCategory(ctx context.Context, from, to string) (int64, error)
+}
+
+// CategoryRepository stores categories, slugs are unique.
+type CategoryRepository interface {
+	Create(ctx context.Context, category *types.Category) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Category, error)
+	FindBySlug(ctx context.Context, slug string) (types.Category, error)
+	// List returns every category ordered by position, then name.
+	List(ctx context.Context) ([]types.Category, error)
+	Update(ctx context.Context, category types.Category) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// CartRepository stores one cart per user, keyed by email.
+type CartRepository interface {
+	FindByEmail(ctx context.Context, email string) (types.CartItem, error)
+	Save(ctx context.Context, cart types.CartItem) error
+	// Replace saves cart in place of old. It fails with ErrNotFound when
+	// the stored cart is no longer old.
+	Replace(ctx context.Context, old, cart types.CartItem) error
+	Delete(ctx context.Context, email string) error
+}
+
+type CouponRepository interface {
+	Create(ctx context.Context, coupon *types.Coupon) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Coupon, error)
+	FindByName(ctx context.Context, name string) (types.Coupon, error)
+	List(ctx context.Context) ([]types.Coupon, error)
+	Update(ctx context.Context, coupon types.Coupon) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// Redeem atomically counts one use of the coupon by email, within its
+	// UsageLimit and PerUserLimit. It fails with ErrLimitReached when
+	// either is used up.
+	Redeem(ctx context.Context, coupon types.Coupon, email string) error
+	// Release gives back a use taken by Redeem.
+	Release(ctx context.Context, coupon types.Coupon, email string) error
+	// UsedBy returns how many times email redeemed the coupon.
+	UsedBy(ctx context.Context, id primitive.ObjectID, email string) (int, error)
+}
+
+type OfferRepository interface {
+	Create(ctx context.Context, offer *types.Offer) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Offer, error)
+	List(ctx context.Context) ([]types.Offer, error)
+	// Running returns the offers whose time window contains now.
+	Running(ctx context.Context, now int64) ([]types.Offer, error)
+	Update(ctx context.Context, offer types.Offer) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// TaxRuleRepository stores tax rules, List returns them in the order they
+// were created.
+type TaxRuleRepository interface {
+	Create(ctx context.Context, rule *types.TaxRule) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.TaxRule, error)
+	List(ctx context.Context) ([]types.TaxRule, error)
+	Update(ctx context.Context, rule types.TaxRule) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ShippingZoneRepository stores shipping zones, List returns them in the
+// order they were created.
+type ShippingZoneRepository interface {
+	Create(ctx context.Context, zone *types.ShippingZone) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.ShippingZone, error)
+	List(ctx context.Context) ([]types.ShippingZone, error)
+	Update(ctx context.Context, zone types.ShippingZone) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ShippingMethodRepository stores shipping methods, lists come in the
+// order they were created.
+type ShippingMethodRepository interface {
+	Create(ctx context.Context, method *types.ShippingMethod) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.ShippingMethod, error)
+	List(ctx context.Context) ([]types.ShippingMethod, error)
+	ListByZone(ctx context.Context, zoneID string) ([]types.ShippingMethod, error)
+	Update(ctx context.Context, method types.ShippingMethod) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type OrderRepository interface {
+	// Create fails with ErrDuplicate when the customer already placed an
+	// order with the same idempotency key.
+	Create(ctx context.Context, order *types.Order) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Order, error)
+	// FindByIdempotencyKey returns the order email placed with key.
+	FindByIdempotencyKey(ctx context.Context, email, key string) (types.Order, error)
+	ListByEmail(ctx context.Context, email string) ([]types.Order, error)
+	// Find returns the orders matching query, newest first, and how many
+	// match in total.
+	Find(ctx context.Context, query OrderQuery) ([]types.Order, int64, error)
+	Update(ctx context.Context, order types.Order) error
+	// Transition moves the order from one status to event.Status and
+	// records event in its history. It fails with ErrNotFound when the
+	// order is no longer in status from.
+	Transition(ctx context.Context, id primitive.ObjectID, from string, event types.OrderEvent) (types.Order, error)
+	// SetPayment records the payment state of the order.
+	SetPayment(ctx context.Context, id primitive.ObjectID, payment types.Payment) (types.Order, error)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *types.Session) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Session, error)
+	// Rotate swaps the refresh token hash of an active session and keeps
+	// oldHash among its previous hashes. It fails with ErrNotFound when
+	// oldHash is no longer the current one.
+	Rotate(ctx context.Context, id primitive.ObjectID, oldHash, newHash string, at int64) error
+	Revoke(ctx context.Context, id primitive.ObjectID, reason string, at int64) error
+	RevokeAllForUser(ctx context.Context, userID primitive.ObjectID, reason string, at int64) error
+}
+
+// VerificationRepository keeps one email verification per address.
+type VerificationRepository interface {
+	FindByEmail(ctx context.Context, email string) (types.Verification, error)
+	// Issue stores the otp just sent to an address and counts the send.
+	// The guesses made so far are kept unless newWindow starts the count
+	// over.
+	Issue(ctx context.Context, verification types.Verification, newWindow bool) error
+	// UseAttempt atomically counts a guess at the otp of email. It fails
+	// with ErrLimitReached once max guesses were made.
+	UseAttempt(ctx context.Context, email string, max int) error
+	// MarkVerified consumes the otp of email.
+	MarkVerified(ctx context.Context, email string) error
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *types.PasswordReset) error
+	FindByHash(ctx context.Context, tokenHash string) (types.PasswordReset, error)
+	// MarkUsed consumes a reset token. It fails with ErrNotFound when the
+	// token was already used, so each one works exactly once.
+	MarkUsed(ctx context.Context, id primitive.ObjectID, at int64) error
+	// InvalidateForUser consumes every outstanding token of the user.
+	InvalidateForUser(ctx context.Context, userID primitive.ObjectID, at int64) error
+}
+
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt *types.LoginAttempt) error
+	// Recent returns the latest attempts for the email, newest first.
+	Recent(ctx context.Context, email string, limit int64) ([]types.LoginAttempt, error)
+}
+
+type LoginThrottleRepository interface {
+	Get(ctx context.Context, key string) (types.LoginThrottle, error)
+	// Fail atomically counts one more failure for key and returns the
+	// updated counter.
+	Fail(ctx context.Context, key string, at int64) (types.LoginThrottle, error)
+	Reset(ctx context.Context, key string) error
+}
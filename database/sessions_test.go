@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSessionRotate(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryManager()
+	session := types.Session{UserID: primitive.NewObjectID(), RefreshHash: "h0", ExpiresAt: 100}
+	if err := db.Sessions().Create(ctx, &session); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		oldHash, newHash string
+		wantErr          error
+		wantHash         string
+		wantPrevious     []string
+	}{
+		{oldHash: "h0", newHash: "h1", wantHash: "h1", wantPrevious: []string{"h0"}},
+		{oldHash: "h0", newHash: "hx", wantErr: ErrNotFound, wantHash: "h1", wantPrevious: []string{"h0"}},
+		{oldHash: "h1", newHash: "h2", wantHash: "h2", wantPrevious: []string{"h0", "h1"}},
+		// only the last RefreshHashHistory hashes are kept
+		{oldHash: "h2", newHash: "h3", wantHash: "h3", wantPrevious: []string{"h1", "h2"}},
+	}
+	for i, step := range steps {
+		err := db.Sessions().Rotate(ctx, session.ID, step.oldHash, step.newHash, int64(i))
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d: Rotate() error = %v, want %v", i, err, step.wantErr)
+		}
+		stored, _ := db.Sessions().FindByID(ctx, session.ID)
+		if stored.RefreshHash != step.wantHash {
+			t.Errorf("step %d: hash %q, want %q", i, stored.RefreshHash, step.wantHash)
+		}
+		if len(stored.PreviousHashes) != len(step.wantPrevious) {
+			t.Fatalf("step %d: previous hashes %v, want %v", i, stored.PreviousHashes, step.wantPrevious)
+		}
+		for j := range step.wantPrevious {
+			if stored.PreviousHashes[j] != step.wantPrevious[j] {
+				t.Errorf("step %d: previous hashes %v, want %v", i, stored.PreviousHashes, step.wantPrevious)
+			}
+		}
+	}
+
+	if err := db.Sessions().Revoke(ctx, session.ID, constant.RevokedTokenReuse, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sessions().Rotate(ctx, session.ID, "h3", "h4", 11); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Rotate() of a revoked session error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+func TestUserUseTOTPStep(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryManager()
+	user := types.User{Email: "a@x", TotpLastStep: 10}
+	if err := db.Users().Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		step     int64
+		wantErr  error
+		wantLast int64
+	}{
+		{step: 11, wantLast: 11},
+		// the same code again is a replay
+		{step: 11, wantErr: ErrNotFound, wantLast: 11},
+		{step: 9, wantErr: ErrNotFound, wantLast: 11},
+		{step: 13, wantLast: 13},
+	}
+	for i, step := range steps {
+		if err := db.Users().UseTOTPStep(ctx, user.Id, step.step); !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d: UseTOTPStep() error = %v, want %v", i, err, step.wantErr)
+		}
+		stored, _ := db.Users().FindByID(ctx, user.Id)
+		if stored.TotpLastStep != step.wantLast {
+			t.Errorf("step %d: last step %d, want %d", i, stored.TotpLastStep, step.wantLast)
+		}
+	}
+}
+
+func TestUserUseRecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryManager()
+	user := types.User{Email: "a@x", RecoveryCodes: []string{"r1", "r2", "r3"}}
+	if err := db.Users().Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		hash      string
+		wantErr   error
+		wantCodes []string
+	}{
+		{hash: "r2", wantCodes: []string{"r1", "r3"}},
+		{hash: "r2", wantErr: ErrNotFound, wantCodes: []string{"r1", "r3"}},
+		{hash: "rx", wantErr: ErrNotFound, wantCodes: []string{"r1", "r3"}},
+		{hash: "r1", wantCodes: []string{"r3"}},
+	}
+	for i, step := range steps {
+		if err := db.Users().UseRecoveryCode(ctx, user.Id, step.hash); !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d: UseRecoveryCode() error = %v, want %v", i, err, step.wantErr)
+		}
+		stored, _ := db.Users().FindByID(ctx, user.Id)
+		if !reflect.DeepEqual(stored.RecoveryCodes, step.wantCodes) {
+			t.Errorf("step %d: codes %v, want %v", i, stored.RecoveryCodes, step.wantCodes)
+		}
+	}
+}
+
+func TestUserUpdatePassword(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryManager()
+	user := types.User{Email: "a@x", Password: "old"}
+	if err := db.Users().Create(ctx, &user); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		oldHash, newHash string
+		wantErr          error
+		wantHash         string
+	}{
+		{oldHash: "old", newHash: "rehashed", wantHash: "rehashed"},
+		// a login that read the hash before the change loses
+		{oldHash: "old", newHash: "stale", wantErr: ErrNotFound, wantHash: "rehashed"},
+	}
+	for i, step := range steps {
+		if err := db.Users().UpdatePassword(ctx, user.Id, step.oldHash, step.newHash); !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d: UpdatePassword() error = %v, want %v", i, err, step.wantErr)
+		}
+		stored, _ := db.Users().FindByID(ctx, user.Id)
+		if stored.Password != step.wantHash {
+			t.Errorf("step %d: hash %q, want %q", i, stored.Password, step.wantHash)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package exchange converts store prices into the currency a customer
+// picked, at rates from a pluggable source.
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// ErrUnsupportedCurrency is returned for a currency there is no rate for.
+var ErrUnsupportedCurrency = errors.New("exchange: unsupported currency")
+
+// RateProvider hands out the current exchange rates.
+type RateProvider interface {
+	Rates(ctx context.Context) (Table, error)
+}
+
+// Config picks where rates come from. Without a file prices are only
+// shown in the store currency.
+type Config struct {
+	// File is a rate file read by File
+	File string
+	// Refresh is how often the file is read again
+	Refresh time.Duration
+}
+
+func (cfg Config) Validate() error {
+	if cfg.Refresh <= 0 {
+		return fmt.Errorf("exchange: refresh interval must be positive")
+	}
+	return nil
+}
+
+// New loads the configured rates once, so a broken rate file is reported
+// at startup, and returns them cached. store is the store currency, the
+// rates must cover it.
+func New(ctx context.Context, cfg Config, store string) (*Refresher, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var source RateProvider = NewStatic(Table{Base: store})
+	if cfg.File != "" {
+		source = NewFile(cfg.File)
+	}
+	r := NewRefresher(source, cfg.Refresh)
+	table, err := r.Rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := table.Converter(store, store); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Table holds how much of each currency one unit of Base buys, in major
+// units: with Base "INR", Rates["USD"] of 0.012 means 1 INR buys 0.012 USD.
+type Table struct {
+	Base  string
+	Rates map[string]*big.Rat
+	// AsOf is when the rates were published, 0 when unknown
+	AsOf int64
+}
+
+// rate returns the units of currency one unit of Base buys.
+func (t Table) rate(currency string) (*big.Rat, bool) {
+	if currency == t.Base {
+		return big.NewRat(1, 1), true
+	}
+	rate, ok := t.Rates[currency]
+	return rate, ok && rate.Sign() > 0
+}
+
+// Currencies lists the currencies prices can be shown in, sorted.
+func (t Table) Currencies() []string {
+	out := []string{t.Base}
+	for currency := range t.Rates {
+		if _, ok := t.rate(currency); ok && currency != t.Base {
+			out = append(out, currency)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Converter returns the conversion from one currency to another. Rates
+// between two currencies other than Base are crossed through it.
+func (t Table) Converter(from, to string) (Converter, error) {
+	fromRate, ok := t.rate(from)
+	if !ok {
+		return Converter{}, fmt.Errorf("%w %q", ErrUnsupportedCurrency, from)
+	}
+	toRate, ok := t.rate(to)
+	if !ok {
+		return Converter{}, fmt.Errorf("%w %q", ErrUnsupportedCurrency, to)
+	}
+	rate := new(big.Rat).Quo(toRate, fromRate)
+	return Converter{From: from, To: to, Rate: rate, AsOf: t.AsOf}, nil
+}
+
+// Converter turns amounts of one currency into another at a fixed rate.
+type Converter struct {
+	From string
+	To   string
+	// Rate is how many major units of To one major unit of From buys
+	Rate *big.Rat
+	AsOf int64
+}
+
+// Identity converts nothing, for customers who shop in the store
+// currency.
+func Identity(currency string) Converter {
+	return Converter{From: currency, To: currency, Rate: big.NewRat(1, 1)}
+}
+
+// Identity reports whether the converter leaves amounts unchanged.
+func (c Converter) Identity() bool {
+	return c.From == c.To
+}
+
+// Convert returns m in To, rounded half away from zero to the minor unit
+// of To. Amounts not in From are returned unchanged.
+func (c Converter) Convert(m types.Money) types.Money {
+	if c.Identity() || m.Currency != c.From {
+		return m
+	}
+
+	x := new(big.Rat).SetInt64(m.Amount)
+	x.Mul(x, c.Rate)
+	x.Mul(x, new(big.Rat).SetFrac64(types.MinorFactor(c.To), types.MinorFactor(c.From)))
+
+	// round half away from zero
+	num, den := new(big.Int).Abs(x.Num()), x.Denom()
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Mul(r, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if x.Sign() < 0 {
+		q.Neg(q)
+	}
+	return types.Money{Amount: q.Int64(), Currency: c.To}
+}
+
+// RateString is Rate as a decimal with up to ten places.
+func (c Converter) RateString() string {
+	s := strings.TrimRight(c.Rate.FloatString(10), "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,135 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+var testTable = Table{
+	Base: "INR",
+	Rates: map[string]*big.Rat{
+		"USD": big.NewRat(12, 1000),
+		"JPY": big.NewRat(18, 10),
+		"KWD": big.NewRat(37, 10000),
+		// a rate of zero is no rate
+		"XXX": new(big.Rat),
+	},
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		m        types.Money
+		want     types.Money
+	}{
+		{name: "to the same currency", from: "INR", to: "INR", m: types.NewMoney(12345, "INR"), want: types.NewMoney(12345, "INR")},
+		{name: "from base", from: "INR", to: "USD", m: types.NewMoney(10000, "INR"), want: types.NewMoney(120, "USD")},
+		{name: "to base", from: "USD", to: "INR", m: types.NewMoney(120, "USD"), want: types.NewMoney(10000, "INR")},
+		{name: "crossed through base", from: "USD", to: "JPY", m: types.NewMoney(100, "USD"), want: types.NewMoney(150, "JPY")},
+		{name: "to three decimals", from: "INR", to: "KWD", m: types.NewMoney(100000, "INR"), want: types.NewMoney(3700, "KWD")},
+		// 0.42 INR is 0.00504 USD
+		{name: "rounds down", from: "INR", to: "USD", m: types.NewMoney(42, "INR"), want: types.NewMoney(1, "USD")},
+		// 1.25 INR is 0.015 USD
+		{name: "rounds half away from zero", from: "INR", to: "USD", m: types.NewMoney(125, "INR"), want: types.NewMoney(2, "USD")},
+		{name: "negative", from: "INR", to: "USD", m: types.NewMoney(-125, "INR"), want: types.NewMoney(-2, "USD")},
+		{name: "other currency untouched", from: "INR", to: "USD", m: types.NewMoney(100, "JPY"), want: types.NewMoney(100, "JPY")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, err := testTable.Converter(tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := conv.Convert(tt.m); got != tt.want {
+				t.Errorf("Convert(%v) = %v, want %v", tt.m, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConverter(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantRate string
+		wantErr  error
+	}{
+		{from: "INR", to: "USD", wantRate: "0.012"},
+		{from: "USD", to: "INR", wantRate: "83.3333333333"},
+		{from: "INR", to: "INR", wantRate: "1"},
+		{from: "INR", to: "EUR", wantErr: ErrUnsupportedCurrency},
+		{from: "EUR", to: "INR", wantErr: ErrUnsupportedCurrency},
+		{from: "INR", to: "XXX", wantErr: ErrUnsupportedCurrency},
+	}
+	for _, tt := range tests {
+		conv, err := testTable.Converter(tt.from, tt.to)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Converter(%s, %s) error = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+			continue
+		}
+		if err == nil && conv.RateString() != tt.wantRate {
+			t.Errorf("Converter(%s, %s) rate = %s, want %s", tt.from, tt.to, conv.RateString(), tt.wantRate)
+		}
+	}
+}
+
+func TestCurrencies(t *testing.T) {
+	want := []string{"INR", "JPY", "KWD", "USD"}
+	if got := testTable.Currencies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Currencies() = %v, want %v", got, want)
+	}
+}
+
+func TestFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Table
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: `{"base": "INR", "as_of": "2024-05-01T00:00:00Z", "rates": {"USD": "0.012"}}`,
+			want:    Table{Base: "INR", Rates: map[string]*big.Rat{"USD": big.NewRat(12, 1000)}, AsOf: 1714521600},
+		},
+		{name: "not json", content: `base: INR`, wantErr: true},
+		{name: "bad base", content: `{"base": "rupee"}`, wantErr: true},
+		{name: "bad currency", content: `{"base": "INR", "rates": {"usd": "0.012"}}`, wantErr: true},
+		{name: "bad rate", content: `{"base": "INR", "rates": {"USD": "a lot"}}`, wantErr: true},
+		{name: "negative rate", content: `{"base": "INR", "rates": {"USD": "-0.012"}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rates.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewFile(path).Rates(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rates() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Base != tt.want.Base || got.AsOf != tt.want.AsOf || len(got.Rates) != len(tt.want.Rates) {
+				t.Fatalf("Rates() = %+v, want %+v", got, tt.want)
+			}
+			for currency, rate := range tt.want.Rates {
+				if got.Rates[currency] == nil || got.Rates[currency].Cmp(rate) != 0 {
+					t.Errorf("rate of %s = %v, want %v", currency, got.Rates[currency], rate)
+				}
+			}
+		})
+	}
+
+	if _, err := NewFile(filepath.Join(t.TempDir(), "missing.json")).Rates(context.Background()); err == nil {
+		t.Error("Rates() of a missing file succeeded")
+	}
+}
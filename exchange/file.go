@@ -0,0 +1,73 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// File reads rates from a JSON file such as
+//
+//	{"base": "INR", "as_of": "2024-05-01T00:00:00Z", "rates": {"USD": "0.012", "EUR": "0.011"}}
+//
+// Rates are decimal strings so they are read exactly. The file is read
+// again on every call, wrap it in a Refresher to cache it.
+type File struct {
+	path string
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+type rateFile struct {
+	Base  string            `json:"base"`
+	AsOf  time.Time         `json:"as_of"`
+	Rates map[string]string `json:"rates"`
+}
+
+func (f *File) Rates(ctx context.Context) (Table, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return Table{}, fmt.Errorf("exchange: %w", err)
+	}
+
+	var raw rateFile
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Table{}, fmt.Errorf("exchange: reading %s: %w", f.path, err)
+	}
+	if !types.ValidCurrency(raw.Base) {
+		return Table{}, fmt.Errorf("exchange: %s: base %q is not a currency code", f.path, raw.Base)
+	}
+
+	table := Table{Base: raw.Base, Rates: map[string]*big.Rat{}}
+	if !raw.AsOf.IsZero() {
+		table.AsOf = raw.AsOf.Unix()
+	}
+	for currency, value := range raw.Rates {
+		rate, ok := new(big.Rat).SetString(value)
+		if !types.ValidCurrency(currency) || !ok || rate.Sign() <= 0 {
+			return Table{}, fmt.Errorf("exchange: %s: invalid rate %q for %q", f.path, value, currency)
+		}
+		table.Rates[currency] = rate
+	}
+	return table, nil
+}
+
+// Static always hands out the same table.
+type Static struct {
+	table Table
+}
+
+func NewStatic(table Table) Static {
+	return Static{table: table}
+}
+
+func (s Static) Rates(ctx context.Context) (Table, error) {
+	return s.table, nil
+}
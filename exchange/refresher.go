@@ -0,0 +1,65 @@
+package exchange
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Refresher caches the rates of a slower source and reloads them every
+// interval while Run is going. When a reload fails the last rates stay in
+// use, so a broken source does not stop the storefront.
+type Refresher struct {
+	source   RateProvider
+	interval time.Duration
+
+	mu     sync.RWMutex
+	table  Table
+	loaded bool
+}
+
+func NewRefresher(source RateProvider, interval time.Duration) *Refresher {
+	return &Refresher{source: source, interval: interval}
+}
+
+// Rates returns the cached rates, loading them first if nothing is cached
+// yet.
+func (r *Refresher) Rates(ctx context.Context) (Table, error) {
+	r.mu.RLock()
+	table, loaded := r.table, r.loaded
+	r.mu.RUnlock()
+	if loaded {
+		return table, nil
+	}
+	return r.refresh(ctx)
+}
+
+func (r *Refresher) refresh(ctx context.Context) (Table, error) {
+	table, err := r.source.Rates(ctx)
+	if err != nil {
+		return Table{}, err
+	}
+
+	r.mu.Lock()
+	r.table, r.loaded = table, true
+	r.mu.Unlock()
+	return table, nil
+}
+
+// Run reloads the rates every interval until ctx is done.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.refresh(ctx); err != nil {
+				log.Printf("Failed to refresh exchange rates, keeping the last ones: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+var (
+	// postalCodePatterns are checked for the countries listed, which all
+	// require a postal code. Elsewhere it is optional and only has to look
+	// like one.
+	postalCodePatterns = map[string]*regexp.Regexp{
+		"AU": regexp.MustCompile(`^[0-9]{4}$`),
+		"CA": regexp.MustCompile(`^[A-Z][0-9][A-Z] ?[0-9][A-Z][0-9]$`),
+		"DE": regexp.MustCompile(`^[0-9]{5}$`),
+		"FR": regexp.MustCompile(`^[0-9]{5}$`),
+		"GB": regexp.MustCompile(`^[A-Z]{1,2}[0-9][A-Z0-9]? ?[0-9][A-Z]{2}$`),
+		"IN": regexp.MustCompile(`^[1-9][0-9]{5}$`),
+		"JP": regexp.MustCompile(`^[0-9]{3}-?[0-9]{4}$`),
+		"US": regexp.MustCompile(`^[0-9]{5}(-[0-9]{4})?$`),
+		"VN": regexp.MustCompile(`^[0-9]{6}$`),
+	}
+	postalCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`)
+	// regionCountries need a state or province in their addresses
+	regionCountries = map[string]bool{"AU": true, "CA": true, "IN": true, "US": true}
+	phonePattern    = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{5,19}$`)
+)
+
+// NormalizeAddress trims an address sent by a client, upper cases its
+// codes and checks it against the rules of its country.
+func NormalizeAddress(a types.PostalAddress) (types.PostalAddress, error) {
+	out := types.PostalAddress{
+		Name:       strings.TrimSpace(a.Name),
+		Lines:      []string{},
+		City:       strings.TrimSpace(a.City),
+		PostalCode: strings.ToUpper(strings.TrimSpace(a.PostalCode)),
+		Phone:      strings.TrimSpace(a.Phone),
+	}
+	for _, line := range a.Lines {
+		if line = strings.TrimSpace(line); line != "" {
+			out.Lines = append(out.Lines, line)
+		}
+	}
+
+	region, err := NormalizeTaxRegion(a.Country, a.Region)
+	if err != nil {
+		return out, err
+	}
+	out.Country, out.Region = region.Country, region.Region
+
+	switch {
+	case out.Name == "":
+		return out, errors.New("name can't be empty")
+	case len(out.Lines) == 0:
+		return out, errors.New("lines can't be empty")
+	case len(out.Lines) > constant.AddressLinesMax:
+		return out, fmt.Errorf("an address has at most %d lines", constant.AddressLinesMax)
+	case out.City == "":
+		return out, errors.New("city can't be empty")
+	case out.Country == "":
+		return out, errors.New("country can't be empty")
+	case out.Region == "" && regionCountries[out.Country]:
+		return out, fmt.Errorf("region can't be empty for an address in %s", out.Country)
+	case out.Phone != "" && !phonePattern.MatchString(out.Phone):
+		return out, errors.New("phone must be digits, optionally with a leading +")
+	}
+
+	if pattern, ok := postalCodePatterns[out.Country]; ok {
+		if !pattern.MatchString(out.PostalCode) {
+			return out, fmt.Errorf("postal_code is not valid for %s", out.Country)
+		}
+	} else if out.PostalCode != "" && !postalCodePattern.MatchString(out.PostalCode) {
+		return out, errors.New("postal_code must be letters, digits, spaces and dashes")
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package helper
+
+import "github.com/PiehTVH/go-ecommerce/types"
+
+// CartQuantity returns how many units of the product are in the cart.
+func CartQuantity(cart types.CartItem, productID string) int {
+	quantity := 0
+	for _, line := range cart.Products {
+		if line.ProductID == productID {
+			quantity += line.Quantity
+		}
+	}
+	return quantity
+}
+
+// SetCartQuantity sets the quantity of the product, adding its line when
+// it is missing and dropping it at zero. A product keeps one line, in the
+// place it was first added.
+func SetCartQuantity(cart *types.CartItem, productID string, quantity int) {
+	lines := []types.ProductInCart{}
+	placed := false
+	for _, line := range cart.Products {
+		if line.ProductID != productID {
+			lines = append(lines, line)
+			continue
+		}
+		if !placed && quantity > 0 {
+			lines = append(lines, types.ProductInCart{ProductID: productID, Quantity: quantity})
+		}
+		placed = true
+	}
+	if !placed && quantity > 0 {
+		lines = append(lines, types.ProductInCart{ProductID: productID, Quantity: quantity})
+	}
+	cart.Products = lines
+	cart.NumItems = 0
+	for _, line := range lines {
+		cart.NumItems += line.Quantity
+	}
+}
+
+// MergeCartLines folds repeated lines of a product into one, carts saved
+// before lines were merged may have several.
+func MergeCartLines(cart *types.CartItem) {
+	for _, line := range append([]types.ProductInCart{}, cart.Products...) {
+		SetCartQuantity(cart, line.ProductID, CartQuantity(*cart, line.ProductID))
+	}
+}
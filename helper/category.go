@@ -0,0 +1,110 @@
+package helper
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Slugify turns a category name into its url form, "Men's Wear" becomes
+// "men-s-wear".
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func CheckCategoryValidation(c types.CategoryData) error {
+	if strings.TrimSpace(c.Category) == "" {
+		return errors.New("category can't be empty")
+	}
+	if c.Slug != "" && !slugPattern.MatchString(c.Slug) {
+		return errors.New("slug may only contain lowercase letters, digits and single dashes")
+	}
+	if c.ParentId != "" && !primitive.IsValidObjectID(c.ParentId) {
+		return errors.New("parent_id must be a valid id")
+	}
+	return nil
+}
+
+// CategorySubtree returns the id of root and of every category below it.
+// A cycle in the parents ends the walk instead of looping.
+func CategorySubtree(categories []types.Category, root string) []string {
+	children := map[string][]string{}
+	for _, c := range categories {
+		children[c.ParentId] = append(children[c.ParentId], c.ID.Hex())
+	}
+
+	ids := []string{root}
+	seen := map[string]bool{root: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// CategoryTree nests categories under their parents, keeping their order.
+// Categories whose parent is missing are shown at the top level.
+func CategoryTree(categories []types.Category) []types.CategoryNode {
+	known := map[string]bool{}
+	for _, c := range categories {
+		known[c.ID.Hex()] = true
+	}
+
+	children := map[string][]types.Category{}
+	for _, c := range categories {
+		parent := c.ParentId
+		if !known[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], c)
+	}
+
+	var build func(parent string) []types.CategoryNode
+	build = func(parent string) []types.CategoryNode {
+		nodes := []types.CategoryNode{}
+		for _, c := range children[parent] {
+			nodes = append(nodes, types.CategoryNode{Category: c, Children: build(c.ID.Hex())})
+		}
+		return nodes
+	}
+	return build("")
+}
+
+// CategoryAncestors returns id followed by the ids of its parent, its
+// grandparent and so on up to the top level.
+func CategoryAncestors(categories []types.Category, id string) []string {
+	parents := map[string]string{}
+	for _, c := range categories {
+		parents[c.ID.Hex()] = c.ParentId
+	}
+
+	ids := []string{}
+	seen := map[string]bool{}
+	for id != "" && !seen[id] {
+		seen[id] = true
+		ids = append(ids, id)
+		id = parents[id]
+	}
+	return ids
+}
@@ -0,0 +1,65 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCategorySubtree(t *testing.T) {
+	ids := make([]primitive.ObjectID, 4)
+	for i := range ids {
+		ids[i] = primitive.NewObjectID()
+	}
+	hex := func(i int) string { return ids[i].Hex() }
+
+	tests := []struct {
+		name       string
+		categories []types.Category
+		root       string
+		want       []string
+	}{
+		{
+			name: "tree",
+			categories: []types.Category{
+				{ID: ids[0]},
+				{ID: ids[1], ParentId: hex(0)},
+				{ID: ids[2], ParentId: hex(1)},
+				{ID: ids[3]},
+			},
+			root: hex(0),
+			want: []string{hex(0), hex(1), hex(2)},
+		},
+		{
+			name:       "leaf",
+			categories: []types.Category{{ID: ids[0]}, {ID: ids[1], ParentId: hex(0)}},
+			root:       hex(1),
+			want:       []string{hex(1)},
+		},
+		{
+			name: "cycle",
+			categories: []types.Category{
+				{ID: ids[0], ParentId: hex(2)},
+				{ID: ids[1], ParentId: hex(0)},
+				{ID: ids[2], ParentId: hex(1)},
+			},
+			root: hex(0),
+			want: []string{hex(0), hex(1), hex(2)},
+		},
+		{
+			name:       "own parent",
+			categories: []types.Category{{ID: ids[0], ParentId: hex(0)}},
+			root:       hex(0),
+			want:       []string{hex(0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CategorySubtree(tt.categories, tt.root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CategorySubtree() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var couponNamePattern = regexp.MustCompile(`^[A-Z0-9_-]+$`)
+
+// CheckCouponValidation checks a coupon payload whose name has already
+// been upper cased. Its amounts must be in currency.
+func CheckCouponValidation(c types.CouponData, currency string) error {
+	if c.Name == "" {
+		return errors.New("name can't be empty")
+	}
+	if len(c.Name) > constant.CouponNameMaxLength || !couponNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("name may only contain up to %d letters, digits, dashes and underscores", constant.CouponNameMaxLength)
+	}
+	if c.Type != constant.CouponPercentage && c.Type != constant.CouponFixed {
+		return fmt.Errorf("type must be %q or %q", constant.CouponPercentage, constant.CouponFixed)
+	}
+	if err := CheckAmount("amount_off", c.AmountOff, currency); err != nil {
+		return err
+	}
+	if err := CheckAmount("min_cart_value", c.MinCartValue, currency); err != nil {
+		return err
+	}
+	if err := CheckAmount("max_discount", c.MaxDiscount, currency); err != nil {
+		return err
+	}
+	if c.Type == constant.CouponPercentage && (c.Discount <= 0 || c.Discount > 100) {
+		return errors.New("discount of a percentage coupon must be between 1 and 100")
+	}
+	if c.Type == constant.CouponFixed && (c.AmountOff.IsZero() || c.Discount != 0) {
+		return errors.New("a fixed coupon takes amount_off instead of discount")
+	}
+	if c.StartsAt < 0 || c.EndsAt < 0 || (c.EndsAt > 0 && c.EndsAt <= c.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	if c.UsageLimit < 0 || c.PerUserLimit < 0 {
+		return errors.New("usage limits can't be negative")
+	}
+	for _, id := range append(append([]string{}, c.CategoryIds...), c.ProductIds...) {
+		if !primitive.IsValidObjectID(id) {
+			return fmt.Errorf("%q is not a valid id", id)
+		}
+	}
+	return nil
+}
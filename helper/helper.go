@@ -2,13 +2,15 @@ package helper
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/PiehTVH/go-ecommerce/constant"
 	"github.com/PiehTVH/go-ecommerce/types"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
-	"golang.org/x/crypto/bcrypt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func CheckUserValidation(u types.UserClient) error {
@@ -24,62 +26,131 @@ func CheckUserValidation(u types.UserClient) error {
 	if u.Password == "" {
 		return errors.New("password can't be empty")
 	}
-	return nil
+	return ValidatePassword(u.Password)
 }
 
-func EncryptPassword(s string) string {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(s), bcrypt.MinCost)
-	if err != nil {
-		return ""
+// CheckProductValidation checks a product sent by the back office, priced
+// in currency. The category is checked against the database by the caller.
+func CheckProductValidation(p types.ProductData, currency string) error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name can't be empty")
 	}
-	return string(bytes) // Example: $2a$04$ky/fH/EcjZwcI0ZIJbYA8eUMjStsW.0D3ETbIxAX7HvR7TL1d.7x2
-}
-
-func ComparePassword(hashedPwd string, plainPwd string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(plainPwd))
-	return err == nil
+	if len(p.Name) > constant.ProductNameMaxLength {
+		return fmt.Errorf("name can't be longer than %d characters", constant.ProductNameMaxLength)
+	}
+	if err := CheckAmount("price", p.Price, currency); err != nil {
+		return err
+	}
+	if p.Price.IsZero() {
+		return errors.New("price must be greater than zero")
+	}
+	if p.Stock < 0 {
+		return errors.New("stock can't be negative")
+	}
+	if p.Weight < 0 {
+		return errors.New("weight can't be negative")
+	}
+	if d := p.Dimensions; d.Length < 0 || d.Width < 0 || d.Height < 0 {
+		return errors.New("dimensions can't be negative")
+	}
+	if !primitive.IsValidObjectID(p.CategoryId) {
+		return errors.New("category_id must be a valid id")
+	}
+	return nil
 }
 
-func IsUserAdmin(c *gin.Context, tokenString string) (bool, error) {
+// tokenSecret signs every JWT the API issues. It is set once at startup.
+var tokenSecret []byte
 
-	return true, nil
+var errNoTokenSecret = errors.New("token secret is not configured")
 
+func SetTokenSecret(secret string) {
+	tokenSecret = []byte(secret)
 }
 
-func GenerateToken(userId string, email string, userType string) (string, error) {
-	secretKey := os.Getenv("secretKey")
+// GenerateToken issues a short lived access token bound to a session.
+func GenerateToken(userId string, email string, userType string, sessionId string) (string, error) {
+	if len(tokenSecret) == 0 {
+		return "", errNoTokenSecret
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"email":  email,
 		"userId": userId,
 		"type":   userType,
-		"exp":    time.Now().Add(time.Hour * 2).Unix(), // Ex: 1729368780
+		"sid":    sessionId,
+		"exp":    time.Now().Add(AccessTokenTTL).Unix(), // Ex: 1729368780
 	})
 
-	return token.SignedString([]byte(secretKey))
+	return token.SignedString(tokenSecret)
 }
 
-func VerifyToken(tokenString string) (string, string, error) {
-	secretKey := os.Getenv("secretKey")
+// VerifyToken checks the signature and expiry of an access token and
+// returns the identity it was issued for. A leading "Bearer " is accepted.
+func VerifyToken(tokenString string) (types.Principal, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return types.Principal{}, err
+	}
+
+	// challenge tokens are signed with the same key but grant nothing
+	if _, ok := claims["purpose"]; ok {
+		return types.Principal{}, errors.New("not an access token")
+	}
+
+	email, _ := claims["email"].(string)
+	userType, _ := claims["type"].(string)
+	userId, _ := claims["userId"].(string)
+	sessionId, _ := claims["sid"].(string)
+
+	id, err := primitive.ObjectIDFromHex(userId)
+	if err != nil || email == "" || userType == "" {
+		return types.Principal{}, errors.New("could not parse claims")
+	}
+	sid, err := primitive.ObjectIDFromHex(sessionId)
+	if err != nil {
+		return types.Principal{}, errors.New("could not parse claims")
+	}
+
+	return types.Principal{UserID: id, Email: email, Role: userType, SessionID: sid}, nil
+}
+
+func parseClaims(tokenString string) (jwt.MapClaims, error) {
+	if len(tokenSecret) == 0 {
+		return nil, errNoTokenSecret
+	}
+	tokenString = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tokenString), "Bearer "))
+
 	token, err := jwt.Parse((tokenString), func(token *jwt.Token) (interface{}, error) {
 		_, ok := token.Method.(*jwt.SigningMethodHMAC)
 		if !ok {
 			return nil, errors.New("unexpected signing method")
 		}
 
-		return []byte(secretKey), nil
+		return tokenSecret, nil
 	})
 
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", "", errors.New("could not parse claims")
+	if !ok || !token.Valid {
+		return nil, errors.New("could not parse claims")
 	}
+	return claims, nil
+}
 
-	email := claims["type"].(string)
-	userType := claims["type"].(string)
+// SetPrincipal stores the verified caller on the request context.
+func SetPrincipal(c *gin.Context, principal types.Principal) {
+	c.Set(constant.PrincipalKey, principal)
+}
 
-	return email, userType, nil
+// CurrentUser returns the caller stored by the auth middleware.
+func CurrentUser(c *gin.Context) (types.Principal, bool) {
+	value, ok := c.Get(constant.PrincipalKey)
+	if !ok {
+		return types.Principal{}, false
+	}
+	principal, ok := value.(types.Principal)
+	return principal, ok
 }
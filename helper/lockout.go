@@ -0,0 +1,50 @@
+package helper
+
+import (
+	"strings"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// throttle keys, accounts and client IPs are counted separately
+func AccountThrottleKey(email string) string {
+	return "email:" + NormalizeEmail(email)
+}
+
+func IPThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// LockedUntil returns the unix time before which the throttled key may not
+// sign in again, or 0. Once maxFailures is reached every further failure
+// doubles the lockout, up to constant.LoginLockoutMax seconds.
+func LockedUntil(throttle types.LoginThrottle, maxFailures int) int64 {
+	if throttle.Failures < maxFailures {
+		return 0
+	}
+
+	lockout := int64(constant.LoginLockoutBase)
+	for i := maxFailures; i < throttle.Failures && lockout < constant.LoginLockoutMax; i++ {
+		lockout *= 2
+	}
+	if lockout > constant.LoginLockoutMax {
+		lockout = constant.LoginLockoutMax
+	}
+	return throttle.LastFailureAt + lockout
+}
+
+// ThrottleExpired reports whether the failures of a key are old enough to
+// be forgotten.
+func ThrottleExpired(throttle types.LoginThrottle, now int64) bool {
+	return now-throttle.LastFailureAt > constant.LoginFailureWindow*3600
+}
+
+// TwoFactorThrottleKey counts wrong second factor codes of one user.
+func TwoFactorThrottleKey(userId string) string {
+	return "2fa:" + userId
+}
@@ -0,0 +1,19 @@
+package helper
+
+import (
+	"fmt"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// CheckAmount checks that an amount sent by a client is not negative and
+// in currency, the one the store sells in. field names it in the error.
+func CheckAmount(field string, m types.Money, currency string) error {
+	if m.Currency != currency {
+		return fmt.Errorf("%s must be in %s", field, currency)
+	}
+	if m.Amount < 0 {
+		return fmt.Errorf("%s can't be negative", field)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package helper
+
+import (
+	"errors"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func CheckOfferValidation(o types.OfferData) error {
+	if (o.CategoryId == "") == (o.ProductId == "") {
+		return errors.New("an offer needs either a category_id or a product_id")
+	}
+	if o.CategoryId != "" && !primitive.IsValidObjectID(o.CategoryId) {
+		return errors.New("category_id must be a valid id")
+	}
+	if o.ProductId != "" && !primitive.IsValidObjectID(o.ProductId) {
+		return errors.New("product_id must be a valid id")
+	}
+	if o.Discount <= 0 || o.Discount > 100 {
+		return errors.New("discount must be a percentage between 1 and 100")
+	}
+	if o.StartsAt < 0 || o.EndsAt < 0 || (o.EndsAt > 0 && o.EndsAt <= o.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package helper
+
+import "github.com/PiehTVH/go-ecommerce/constant"
+
+// orderTransitions lists the states an order may move to from each state.
+// Cancelled and returned orders are final. An order waiting for its
+// payment is only packed when it is paid on delivery, the caller checks
+// the payment.
+var orderTransitions = map[string][]string{
+	constant.OrderPendingPayment: {constant.OrderPaid, constant.OrderPacked, constant.OrderCancelled},
+	constant.OrderPaid:           {constant.OrderPacked, constant.OrderCancelled},
+	constant.OrderPacked:         {constant.OrderShipped, constant.OrderCancelled},
+	constant.OrderShipped:        {constant.OrderDelivered, constant.OrderReturned},
+	constant.OrderDelivered:      {constant.OrderReturned},
+	constant.OrderCancelled:      {},
+	constant.OrderReturned:       {},
+}
+
+// IsOrderStatus reports whether status is a known order state.
+func IsOrderStatus(status string) bool {
+	_, ok := orderTransitions[status]
+	return ok
+}
+
+// CanTransition reports whether an order may move from one state to the
+// other.
+func CanTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Cancellable reports whether the customer may still cancel an order, it
+// is too late once it has been packed.
+func Cancellable(status string) bool {
+	return status == constant.OrderPendingPayment || status == constant.OrderPaid
+}
@@ -0,0 +1,205 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// PasswordHashing picks how new passwords are hashed. Hashes made with
+// other settings keep working and are upgraded on the next sign in.
+type PasswordHashing struct {
+	// Algorithm is HashBcrypt or HashArgon2id.
+	Algorithm  string
+	BcryptCost int
+	// argon2id parameters, memory in KiB
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
+}
+
+func DefaultPasswordHashing() PasswordHashing {
+	return PasswordHashing{
+		Algorithm:     HashBcrypt,
+		BcryptCost:    12,
+		Argon2Time:    3,
+		Argon2Memory:  64 * 1024,
+		Argon2Threads: 2,
+	}
+}
+
+func (p PasswordHashing) Validate() error {
+	switch p.Algorithm {
+	case HashBcrypt:
+		if p.BcryptCost < bcrypt.DefaultCost || p.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.DefaultCost, bcrypt.MaxCost)
+		}
+	case HashArgon2id:
+		if p.Argon2Time < 1 || p.Argon2Threads < 1 || p.Argon2Memory < 8*uint32(p.Argon2Threads) {
+			return errors.New("argon2id needs a time and thread count of at least 1 and 8 KiB of memory per thread")
+		}
+	default:
+		return fmt.Errorf("unknown password hash %q", p.Algorithm)
+	}
+	return nil
+}
+
+var (
+	hashingMu sync.RWMutex
+	hashing   = DefaultPasswordHashing()
+	// compared against when there is no account, see SimulatePasswordCheck
+	dummyHash string
+)
+
+// SetPasswordHashing changes the hashing of new passwords.
+func SetPasswordHashing(p PasswordHashing) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	hashingMu.Lock()
+	defer hashingMu.Unlock()
+	hashing = p
+	dummyHash = ""
+	return nil
+}
+
+func currentHashing() PasswordHashing {
+	hashingMu.RLock()
+	defer hashingMu.RUnlock()
+	return hashing
+}
+
+func EncryptPassword(s string) (string, error) {
+	p := currentHashing()
+	if p.Algorithm == HashArgon2id {
+		return hashArgon2id(s, p)
+	}
+
+	bytes, err := bcrypt.GenerateFromPassword([]byte(s), p.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil // Example: $2a$12$ky/fH/EcjZwcI0ZIJbYA8eUMjStsW.0D3ETbIxAX7HvR7TL1d.7x2
+}
+
+func ComparePassword(hashedPwd string, plainPwd string) bool {
+	if strings.HasPrefix(hashedPwd, "$"+HashArgon2id+"$") {
+		params, salt, key, err := parseArgon2id(hashedPwd)
+		if err != nil {
+			return false
+		}
+		other := argon2.IDKey([]byte(plainPwd), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(plainPwd))
+	return err == nil
+}
+
+// NeedsRehash reports whether a stored hash was made with weaker or other
+// settings than the current ones.
+func NeedsRehash(hashedPwd string) bool {
+	p := currentHashing()
+	if p.Algorithm == HashArgon2id {
+		params, _, _, err := parseArgon2id(hashedPwd)
+		return err != nil || params.Argon2Time != p.Argon2Time || params.Argon2Memory != p.Argon2Memory || params.Argon2Threads != p.Argon2Threads
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashedPwd))
+	return err != nil || cost < p.BcryptCost
+}
+
+// SimulatePasswordCheck costs as much as checking a real password, so a
+// sign in with an unknown email can not be told apart by its timing.
+func SimulatePasswordCheck(plainPwd string) {
+	hashingMu.RLock()
+	hash := dummyHash
+	hashingMu.RUnlock()
+
+	if hash == "" {
+		var err error
+		if hash, err = EncryptPassword("not a real password"); err != nil {
+			return
+		}
+		hashingMu.Lock()
+		dummyHash = hash
+		hashingMu.Unlock()
+	}
+
+	ComparePassword(hash, plainPwd)
+}
+
+// argon2id hashes use the PHC string format,
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func hashArgon2id(s string, p PasswordHashing) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(s), salt, p.Argon2Time, p.Argon2Memory, p.Argon2Threads, 32)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", HashArgon2id, argon2.Version, p.Argon2Memory, p.Argon2Time, p.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+var errMalformedHash = errors.New("malformed password hash")
+
+func parseArgon2id(hash string) (PasswordHashing, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id || parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return PasswordHashing{}, nil, nil, errMalformedHash
+	}
+
+	p := PasswordHashing{Algorithm: HashArgon2id}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Argon2Memory, &p.Argon2Time, &p.Argon2Threads); err != nil {
+		return PasswordHashing{}, nil, nil, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return PasswordHashing{}, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return PasswordHashing{}, nil, nil, errMalformedHash
+	}
+	return p, salt, key, nil
+}
+
+// ValidatePassword applies the password strength policy.
+func ValidatePassword(password string) error {
+	if len(password) < constant.PasswordMinLength {
+		return fmt.Errorf("password must be at least %d characters long", constant.PasswordMinLength)
+	}
+	// bcrypt ignores everything past 72 bytes
+	if len(password) > constant.PasswordMaxLength {
+		return fmt.Errorf("password must be at most %d bytes long", constant.PasswordMaxLength)
+	}
+
+	var letter, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !letter || !digit {
+		return errors.New("password must contain letters and digits")
+	}
+
+	return nil
+}
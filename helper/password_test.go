@@ -0,0 +1,135 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// withHashing hashes new passwords with p for the rest of the test.
+func withHashing(t *testing.T, p PasswordHashing) {
+	t.Helper()
+	previous := currentHashing()
+	if err := SetPasswordHashing(p); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetPasswordHashing(previous) })
+}
+
+var (
+	testBcrypt   = PasswordHashing{Algorithm: HashBcrypt, BcryptCost: bcrypt.DefaultCost}
+	testArgon2id = PasswordHashing{Algorithm: HashArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1}
+)
+
+func TestPasswordHashingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       PasswordHashing
+		wantErr bool
+	}{
+		{name: "default", p: DefaultPasswordHashing()},
+		{name: "bcrypt", p: testBcrypt},
+		{name: "bcrypt too cheap", p: PasswordHashing{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost}, wantErr: true},
+		{name: "bcrypt too dear", p: PasswordHashing{Algorithm: HashBcrypt, BcryptCost: bcrypt.MaxCost + 1}, wantErr: true},
+		{name: "argon2id", p: testArgon2id},
+		{name: "argon2id without time", p: PasswordHashing{Algorithm: HashArgon2id, Argon2Memory: 64, Argon2Threads: 1}, wantErr: true},
+		{name: "argon2id short of memory", p: PasswordHashing{Algorithm: HashArgon2id, Argon2Time: 1, Argon2Memory: 15, Argon2Threads: 2}, wantErr: true},
+		{name: "unknown", p: PasswordHashing{Algorithm: "md5"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestComparePassword(t *testing.T) {
+	for _, p := range []PasswordHashing{testBcrypt, testArgon2id} {
+		t.Run(p.Algorithm, func(t *testing.T) {
+			withHashing(t, p)
+			hash, err := EncryptPassword("secret123")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				password string
+				want     bool
+			}{
+				{password: "secret123", want: true},
+				{password: "secret124"},
+				{password: "Secret123"},
+				{password: ""},
+			}
+			for _, tt := range tests {
+				if got := ComparePassword(hash, tt.password); got != tt.want {
+					t.Errorf("ComparePassword(%q) = %v, want %v", tt.password, got, tt.want)
+				}
+			}
+		})
+	}
+
+	for _, hash := range []string{"", "plain", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$", "$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5"} {
+		if ComparePassword(hash, "") {
+			t.Errorf("ComparePassword() accepted the malformed hash %q", hash)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	withHashing(t, testBcrypt)
+	bcryptHash, err := EncryptPassword("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withHashing(t, testArgon2id)
+	argonHash, err := EncryptPassword("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		current PasswordHashing
+		hash    string
+		want    bool
+	}{
+		{name: "bcrypt at the current cost", current: testBcrypt, hash: bcryptHash},
+		{name: "bcrypt below the current cost", current: PasswordHashing{Algorithm: HashBcrypt, BcryptCost: bcrypt.DefaultCost + 1}, hash: bcryptHash, want: true},
+		{name: "argon2id moving to bcrypt", current: testBcrypt, hash: argonHash, want: true},
+		{name: "argon2id with the current settings", current: testArgon2id, hash: argonHash},
+		{name: "argon2id with other settings", current: PasswordHashing{Algorithm: HashArgon2id, Argon2Time: 2, Argon2Memory: 64, Argon2Threads: 1}, hash: argonHash, want: true},
+		{name: "bcrypt moving to argon2id", current: testArgon2id, hash: bcryptHash, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withHashing(t, tt.current)
+			if got := NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		wantErr  bool
+	}{
+		{password: "secret123"},
+		{password: "sécret123"},
+		{password: "short1", wantErr: true},
+		{password: "lettersonly", wantErr: true},
+		{password: "1234567890", wantErr: true},
+		{password: strings.Repeat("a1", 36)},
+		{password: strings.Repeat("a1", 36) + "a", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := ValidatePassword(tt.password); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePassword(%q) error = %v, want error %v", tt.password, err, tt.wantErr)
+		}
+	}
+}
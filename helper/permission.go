@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/gin-gonic/gin"
+)
+
+// rolePermissions lists what each role may do on top of being signed in.
+// Admins are not listed, they are granted everything.
+var rolePermissions = map[string][]string{
+	constant.NormalUser: {},
+	constant.CatalogManagerUser: {
+		constant.PermissionManageCatalog,
+		constant.PermissionManageCoupons,
+	},
+	constant.SupportUser: {
+		constant.PermissionViewOrders,
+		constant.PermissionManageOrders,
+		constant.PermissionViewUsers,
+		constant.PermissionUnlockUsers,
+	},
+}
+
+// IsValidRole reports whether role is one the permission model knows about.
+func IsValidRole(role string) bool {
+	if role == constant.AdminUser {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether a user with the given role may perform an
+// action guarded by permission.
+func HasPermission(role string, permission string) bool {
+	if !IsValidRole(role) {
+		return false
+	}
+	if permission == constant.PermissionPublic || permission == constant.PermissionAuthenticated {
+		return true
+	}
+	if role == constant.AdminUser {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUserAdmin reports whether the authenticated caller is an admin.
+func IsUserAdmin(c *gin.Context) bool {
+	principal, ok := CurrentUser(c)
+	return ok && principal.Role == constant.AdminUser
+}
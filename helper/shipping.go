@@ -0,0 +1,79 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NormalizeCountries upper cases the countries of a shipping zone and
+// checks them, each may be listed once.
+func NormalizeCountries(countries []string) ([]string, error) {
+	out := make([]string, 0, len(countries))
+	seen := map[string]bool{}
+	for _, country := range countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !countryPattern.MatchString(country) {
+			return nil, fmt.Errorf("country %q is not a two letter ISO 3166-1 code", country)
+		}
+		if seen[country] {
+			return nil, fmt.Errorf("country %s is listed twice", country)
+		}
+		seen[country] = true
+		out = append(out, country)
+	}
+	return out, nil
+}
+
+// CheckShippingMethodValidation checks a shipping method sent by the back
+// office, priced in currency. The zone is checked against the database by
+// the caller.
+func CheckShippingMethodValidation(m types.ShippingMethodData, currency string) error {
+	if strings.TrimSpace(m.Name) == "" {
+		return errors.New("name can't be empty")
+	}
+	if !primitive.IsValidObjectID(m.ZoneId) {
+		return errors.New("zone_id must be a valid id")
+	}
+	if err := CheckAmount("price", m.Price, currency); err != nil {
+		return err
+	}
+	if err := CheckAmount("free_over", m.FreeOver, currency); err != nil {
+		return err
+	}
+	if m.VolumetricDivisor < 0 {
+		return errors.New("volumetric_divisor can't be negative")
+	}
+
+	switch m.Type {
+	case constant.ShippingFlat:
+	case constant.ShippingFreeOver:
+		if m.FreeOver.IsZero() {
+			return errors.New("a free over threshold method needs free_over")
+		}
+	case constant.ShippingWeight:
+		if len(m.Rates) == 0 {
+			return errors.New("a weight based method needs rates")
+		}
+		for i, rate := range m.Rates {
+			if rate.UpTo <= 0 || (i > 0 && rate.UpTo <= m.Rates[i-1].UpTo) {
+				return errors.New("up_to of the rates must be positive and ascending")
+			}
+			if err := CheckAmount("rate price", rate.Price, currency); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("type must be %q, %q or %q", constant.ShippingFlat, constant.ShippingWeight, constant.ShippingFreeOver)
+	}
+
+	if len(m.Rates) > 0 {
+		return errors.New("only a weight based method takes rates")
+	}
+	return nil
+}
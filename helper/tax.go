@@ -0,0 +1,52 @@
+package helper
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	regionPattern  = regexp.MustCompile(`^[A-Z0-9-]{1,10}$`)
+)
+
+// NormalizeTaxRegion upper cases a country and region sent by a client
+// and checks them. An empty country is left for the caller to judge.
+func NormalizeTaxRegion(country, region string) (types.TaxRegion, error) {
+	r := types.TaxRegion{
+		Country: strings.ToUpper(strings.TrimSpace(country)),
+		Region:  strings.ToUpper(strings.TrimSpace(region)),
+	}
+	if r.Country != "" && !countryPattern.MatchString(r.Country) {
+		return r, errors.New("country must be a two letter ISO 3166-1 code")
+	}
+	if r.Region != "" && r.Country == "" {
+		return r, errors.New("region needs a country")
+	}
+	if r.Region != "" && !regionPattern.MatchString(r.Region) {
+		return r, errors.New("region must be a code of up to 10 letters, digits and dashes")
+	}
+	return r, nil
+}
+
+// CheckTaxRuleValidation checks a tax rule whose country and region have
+// been normalized. The category is checked against the database by the
+// caller.
+func CheckTaxRuleValidation(t types.TaxRuleData) error {
+	if t.Country == "" {
+		return errors.New("country can't be empty")
+	}
+	if t.CategoryId != "" && !primitive.IsValidObjectID(t.CategoryId) {
+		return errors.New("category_id must be a valid id")
+	}
+	if t.Rate < 0 || t.Rate > constant.TaxRateMax {
+		return fmt.Errorf("rate must be between 0 and %d hundredths of a percent", constant.TaxRateMax)
+	}
+	return nil
+}
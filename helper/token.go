@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	AccessTokenTTL  = constant.AccessTokenValidity * time.Minute
+	RefreshTokenTTL = constant.RefreshTokenValidity * 24 * time.Hour
+)
+
+var errMalformedRefreshToken = errors.New("malformed refresh token")
+
+// RandomToken returns n random bytes, url safe base64 encoded.
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken is how opaque tokens are stored, so a leaked database does not
+// leak usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewRefreshToken returns a refresh token for the session together with the
+// hash to store. The token is "<session id>.<secret>".
+func NewRefreshToken(sessionId primitive.ObjectID) (string, string, error) {
+	secret, err := RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	token := sessionId.Hex() + "." + secret
+	return token, HashToken(token), nil
+}
+
+// ParseRefreshToken returns the session a refresh token belongs to.
+func ParseRefreshToken(token string) (primitive.ObjectID, error) {
+	sessionId, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return primitive.NilObjectID, errMalformedRefreshToken
+	}
+	id, err := primitive.ObjectIDFromHex(sessionId)
+	if err != nil {
+		return primitive.NilObjectID, errMalformedRefreshToken
+	}
+	return id, nil
+}
+
+// GenerateOTP returns a random six digit code.
+func GenerateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// HashOTP binds an otp to the address it was sent to before hashing it.
+func HashOTP(email string, otp string) string {
+	return HashToken(email + ":" + otp)
+}
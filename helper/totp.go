@@ -0,0 +1,150 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/golang-jwt/jwt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ChallengeTokenTTL = constant.ChallengeTokenValidity * time.Minute
+
+	totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// NewTOTPSecret returns a random base32 secret for an authenticator app.
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPCode computes the RFC 6238 code of the secret for one time step.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < constant.TotpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", constant.TotpDigits, value%mod), nil
+}
+
+// ValidateTOTP checks a code against the time steps around now, allowing
+// for clock drift, and returns the step it matched. Steps up to and
+// including lastStep are refused so a code works only once.
+func ValidateTOTP(secret string, code string, now time.Time, lastStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != constant.TotpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / constant.TotpPeriod
+	for step := current - constant.TotpSkew; step <= current+constant.TotpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPProvisioningURI returns the otpauth:// URI authenticator apps read
+// from a QR code.
+func TOTPProvisioningURI(account string, secret string) string {
+	label := url.PathEscape(constant.TotpIssuer + ":" + account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", constant.TotpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(constant.TotpDigits))
+	query.Set("period", fmt.Sprint(constant.TotpPeriod))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// NewRecoveryCodes returns single use recovery codes and the hashes to
+// store for them.
+func NewRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, constant.RecoveryCodeCount)
+	hashes := make([]string, constant.RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode ignores case and dashes, codes are often typed by hand.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return HashToken(code)
+}
+
+// GenerateChallengeToken issues the short lived token handed out after the
+// password check, to be exchanged together with a second factor.
+func GenerateChallengeToken(userId string) (string, error) {
+	if len(tokenSecret) == 0 {
+		return "", errNoTokenSecret
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId":  userId,
+		"purpose": "2fa",
+		"exp":     time.Now().Add(ChallengeTokenTTL).Unix(),
+	})
+
+	return token.SignedString(tokenSecret)
+}
+
+// VerifyChallengeToken returns the user a challenge token was issued for.
+func VerifyChallengeToken(tokenString string) (primitive.ObjectID, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	purpose, _ := claims["purpose"].(string)
+	userId, _ := claims["userId"].(string)
+	id, err := primitive.ObjectIDFromHex(userId)
+	if err != nil || purpose != "2fa" {
+		return primitive.NilObjectID, errors.New("could not parse claims")
+	}
+	return id, nil
+}
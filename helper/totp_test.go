@@ -0,0 +1,100 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 test vectors,
+// "12345678901234567890", in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, cut to the last six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, tt.unix/constant.TotpPeriod)
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	// secrets are often typed in lower case
+	if got, _ := TOTPCode(strings.ToLower(rfc6238Secret), 1); got != "287082" {
+		t.Errorf("TOTPCode() of a lower case secret = %s, want 287082", got)
+	}
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("TOTPCode() of a malformed secret succeeded")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / constant.TotpPeriod
+	code := func(step int64) string {
+		c, err := TOTPCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", code: code(current), wantStep: current, wantOK: true},
+		{name: "surrounded by spaces", code: " " + code(current) + " ", wantStep: current, wantOK: true},
+		{name: "previous step", code: code(current - constant.TotpSkew), wantStep: current - constant.TotpSkew, wantOK: true},
+		{name: "next step", code: code(current + constant.TotpSkew), wantStep: current + constant.TotpSkew, wantOK: true},
+		{name: "too old", code: code(current - constant.TotpSkew - 1)},
+		{name: "too new", code: code(current + constant.TotpSkew + 1)},
+		{name: "already used", code: code(current), lastStep: current},
+		{name: "wrong length", code: code(current)[1:]},
+		{name: "wrong code", code: "000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, now, tt.lastStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != constant.RecoveryCodeCount || len(hashes) != constant.RecoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), constant.RecoveryCodeCount)
+	}
+
+	code := codes[0]
+	for _, typed := range []string{code, strings.ToUpper(code), strings.ReplaceAll(code, "-", ""), " " + code + " "} {
+		if HashRecoveryCode(typed) != hashes[0] {
+			t.Errorf("HashRecoveryCode(%q) does not match the hash of %q", typed, code)
+		}
+	}
+}
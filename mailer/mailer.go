@@ -0,0 +1,69 @@
+// Package mailer delivers transactional emails such as verification codes.
+package mailer
+
+import (
+	"context"
+	"fmt"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends a single plain text email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config picks and configures a Mailer. Driver is one of "smtp", "file"
+// or "log". There is no default, the log driver writes codes and reset
+// links to the logs and has to be asked for.
+type Config struct {
+	Driver string
+	From   string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// File receives every email when Driver is "file".
+	File string
+}
+
+// Validate checks that the chosen driver has what it needs.
+func (cfg Config) Validate() error {
+	switch cfg.Driver {
+	case "":
+		return fmt.Errorf("mailer: no driver set, choose smtp, file or log")
+	case "log":
+	case "file":
+		if cfg.File == "" {
+			return fmt.Errorf("mailer: file driver needs a file path")
+		}
+	case "smtp":
+		if cfg.SMTPHost == "" || cfg.SMTPPort == 0 || cfg.From == "" {
+			return fmt.Errorf("mailer: smtp driver needs a host, port and from address")
+		}
+	default:
+		return fmt.Errorf("mailer: unknown driver %q", cfg.Driver)
+	}
+	return nil
+}
+
+func New(cfg Config) (Mailer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Driver {
+	case "file":
+		return NewFileMailer(cfg.File), nil
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	default:
+		return NewLogMailer(), nil
+	}
+}
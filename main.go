@@ -1,41 +1,120 @@
-// package main
-
-// import (
-// 	"log"
-// 	"os"
-
-// 	"github.com/PiehTVH/go-ecommerce/database"
-// 	"github.com/joho/godotenv"
-// )
-
-// func init() {
-// 	if _, err := os.Stat(".env"); err == nil {
-// 		log.Println("Loading .env file")
-// 		err := godotenv.Load()
-
-// 		if err != nil {
-// 			log.Println("Error loading .env file")
-// 		}
-// 		log.Println("Loaded .env file successfully")
-// 	}
-// 	database.ConnectDB()
-// }
-
-// func main() {
-// 	// run the routes
-// 	// router.Clie
-// }
-
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/config"
+	"github.com/PiehTVH/go-ecommerce/database"
+	"github.com/PiehTVH/go-ecommerce/exchange"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/mailer"
+	"github.com/PiehTVH/go-ecommerce/payment"
+	"github.com/PiehTVH/go-ecommerce/router"
+	"github.com/PiehTVH/go-ecommerce/tax"
 )
 
-func hienCoXinhGaiKhong() {
-	fmt.Println("Hien rat xinh gai")
-}
 func main() {
-	slice := []string{99: ""}
-	fmt.Println(len(slice))
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	helper.SetTokenSecret(cfg.JWTSecret)
+	if err := helper.SetPasswordHashing(cfg.Hashing); err != nil {
+		return err
+	}
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return err
+	}
+
+	pay, err := payment.New(cfg.Payment)
+	if err != nil {
+		return err
+	}
+
+	// SIGINT or SIGTERM cancels ctx and starts the shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	rates, err := exchange.New(ctx, cfg.Exchange, cfg.Currency)
+	if err != nil {
+		return err
+	}
+	go rates.Run(ctx)
+
+	connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	db, err := database.Open(connectCtx, cfg.Database)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		disconnectCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.Disconnect(disconnectCtx); err != nil {
+			log.Printf("Failed to disconnect from the database: %v", err)
+		}
+	}()
+
+	migrateCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	err = db.Migrate(migrateCtx, cfg.Currency)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	handler, err := router.New(cfg, db, mail, pay, rates, tax.NewRules(db.TaxRules()))
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Listening on %s", server.Addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	stop()
+
+	// stop accepting connections and let in-flight requests finish
+	log.Printf("Shutting down, waiting up to %s for in-flight requests", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	return nil
 }
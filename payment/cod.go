@@ -0,0 +1,59 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+const (
+	CashOnDelivery  = "cod"
+	codIntentPrefix = "cod_"
+)
+
+// COD takes cash from the courier. Orders are authorized on the spot, the
+// capture records the cash collected on delivery and there are no webhooks.
+type COD struct{}
+
+func NewCashOnDelivery() COD {
+	return COD{}
+}
+
+func (COD) Name() string {
+	return CashOnDelivery
+}
+
+func (COD) CollectsOnDelivery() bool {
+	return true
+}
+
+func (COD) Authorize(ctx context.Context, req AuthorizeRequest) (Intent, error) {
+	if req.Amount.Amount <= 0 {
+		return Intent{}, ErrInvalidAmount
+	}
+	return Intent{ID: codIntentPrefix + req.OrderID, Provider: CashOnDelivery, Status: StatusAuthorized, Amount: req.Amount}, nil
+}
+
+func (COD) Capture(ctx context.Context, intentID string, amount types.Money) (Intent, error) {
+	return codSettle(intentID, amount, StatusCaptured)
+}
+
+func (COD) Refund(ctx context.Context, intentID string, amount types.Money) (Intent, error) {
+	return codSettle(intentID, amount, StatusRefunded)
+}
+
+func codSettle(intentID string, amount types.Money, status string) (Intent, error) {
+	if !strings.HasPrefix(intentID, codIntentPrefix) {
+		return Intent{}, ErrUnknownIntent
+	}
+	if amount.Amount <= 0 {
+		return Intent{}, ErrInvalidAmount
+	}
+	return Intent{ID: intentID, Provider: CashOnDelivery, Status: status, Amount: amount}, nil
+}
+
+func (COD) VerifyWebhook(payload []byte, header http.Header) (Event, error) {
+	return Event{}, ErrInvalidSignature
+}
@@ -0,0 +1,113 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+const (
+	FakeProvider = "fake"
+	// FakeSignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256 of
+	// t.payload>" on fake webhooks
+	FakeSignatureHeader = "Fake-Signature"
+	// fakeTolerance is how old a signed webhook may be, to stop replays
+	fakeTolerance    = 5 * time.Minute
+	fakeIntentPrefix = "fake_pi_"
+)
+
+// Fake is a gateway for local runs and tests. It keeps no state and moves
+// no money: intent ids derive from the order id, every authorization waits
+// for a webhook, and customers whose email local part ends in "+decline"
+// are declined.
+type Fake struct {
+	secret []byte
+}
+
+func NewFake(secret string) *Fake {
+	return &Fake{secret: []byte(secret)}
+}
+
+func (f *Fake) Name() string {
+	return FakeProvider
+}
+
+func (f *Fake) Authorize(ctx context.Context, req AuthorizeRequest) (Intent, error) {
+	if req.Amount.Amount <= 0 {
+		return Intent{}, ErrInvalidAmount
+	}
+	intent := Intent{ID: fakeIntentPrefix + req.OrderID, Provider: FakeProvider, Status: StatusRequiresAction, Amount: req.Amount}
+	if local, _, _ := strings.Cut(req.Email, "@"); strings.HasSuffix(local, "+decline") {
+		intent.Status = StatusFailed
+	}
+	return intent, nil
+}
+
+func (f *Fake) Capture(ctx context.Context, intentID string, amount types.Money) (Intent, error) {
+	return f.settle(intentID, amount, StatusCaptured)
+}
+
+func (f *Fake) Refund(ctx context.Context, intentID string, amount types.Money) (Intent, error) {
+	return f.settle(intentID, amount, StatusRefunded)
+}
+
+func (f *Fake) settle(intentID string, amount types.Money, status string) (Intent, error) {
+	if !strings.HasPrefix(intentID, fakeIntentPrefix) {
+		return Intent{}, ErrUnknownIntent
+	}
+	if amount.Amount <= 0 {
+		return Intent{}, ErrInvalidAmount
+	}
+	return Intent{ID: intentID, Provider: FakeProvider, Status: status, Amount: amount}, nil
+}
+
+// Sign returns the FakeSignatureHeader value of payload sent at at.
+func (f *Fake) Sign(payload []byte, at time.Time) string {
+	t := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + t + ",v1=" + f.mac(t, payload)
+}
+
+func (f *Fake) mac(t string, payload []byte) string {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(t + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *Fake) VerifyWebhook(payload []byte, header http.Header) (Event, error) {
+	var t, sig string
+	for _, part := range strings.Split(header.Get(FakeSignatureHeader), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			sig = value
+		}
+	}
+
+	sent, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || !hmac.Equal([]byte(sig), []byte(f.mac(t, payload))) {
+		return Event{}, ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > fakeTolerance || age < -fakeTolerance {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, err
+	}
+	if !strings.HasPrefix(event.IntentID, fakeIntentPrefix) {
+		return Event{}, ErrUnknownIntent
+	}
+	return event, nil
+}
@@ -0,0 +1,174 @@
+// Package payment takes payments for orders through pluggable providers
+// such as card gateways or cash on delivery.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// intent states
+const (
+	// StatusRequiresAction waits for the customer to pay, the provider
+	// reports the outcome through its webhook
+	StatusRequiresAction = "requires_action"
+	StatusAuthorized     = "authorized"
+	StatusCaptured       = "captured"
+	StatusRefunded       = "refunded"
+	StatusFailed         = "failed"
+)
+
+// webhook event types
+const (
+	EventAuthorized = "payment.authorized"
+	EventFailed     = "payment.failed"
+)
+
+var (
+	// ErrUnknownIntent is returned for an intent the provider did not make.
+	ErrUnknownIntent = errors.New("payment: unknown intent")
+	// ErrInvalidAmount is returned for an amount that is not positive or
+	// more than the intent holds.
+	ErrInvalidAmount = errors.New("payment: invalid amount")
+	// ErrInvalidSignature is returned for a webhook that does not come from
+	// the provider.
+	ErrInvalidSignature = errors.New("payment: invalid webhook signature")
+)
+
+// AuthorizeRequest asks for the total of an order.
+type AuthorizeRequest struct {
+	OrderID string
+	Email   string
+	Amount  types.Money
+}
+
+// Intent is a payment as the provider tracks it.
+type Intent struct {
+	ID       string
+	Provider string
+	Status   string
+	Amount   types.Money
+}
+
+// Event is a verified webhook notification about an intent.
+type Event struct {
+	Type     string      `json:"type"`
+	IntentID string      `json:"intent_id"`
+	OrderID  string      `json:"order_id"`
+	Amount   types.Money `json:"amount"`
+}
+
+// Provider takes payments. Authorize reserves the amount, Capture collects
+// it and Refund gives it back, captured or not.
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (Intent, error)
+	Capture(ctx context.Context, intentID string, amount types.Money) (Intent, error)
+	Refund(ctx context.Context, intentID string, amount types.Money) (Intent, error)
+	// VerifyWebhook checks the signature of a webhook request and decodes
+	// its event. It fails with ErrInvalidSignature for forged requests.
+	VerifyWebhook(payload []byte, header http.Header) (Event, error)
+}
+
+// collector is implemented by providers whose authorization is only a
+// promise to pay, the money comes in when the order is delivered.
+type collector interface {
+	CollectsOnDelivery() bool
+}
+
+// CollectsOnDelivery reports whether p takes the money on delivery, its
+// authorized orders are not paid yet.
+func CollectsOnDelivery(p Provider) bool {
+	c, ok := p.(collector)
+	return ok && c.CollectsOnDelivery()
+}
+
+// Config lists the providers customers can pay with. The first one is used
+// when checkout names none.
+type Config struct {
+	Providers []string
+	// FakeSecret signs the webhooks of the fake provider.
+	FakeSecret string
+}
+
+// Validate checks that every provider is known and has what it needs.
+func (cfg Config) Validate() error {
+	if len(cfg.Providers) == 0 {
+		return fmt.Errorf("payment: at least one provider must be enabled")
+	}
+	seen := map[string]bool{}
+	for _, name := range cfg.Providers {
+		if seen[name] {
+			return fmt.Errorf("payment: provider %q is listed twice", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case CashOnDelivery:
+		case FakeProvider:
+			if cfg.FakeSecret == "" {
+				return fmt.Errorf("payment: fake provider needs a webhook secret")
+			}
+		default:
+			return fmt.Errorf("payment: unknown provider %q", name)
+		}
+	}
+	return nil
+}
+
+// Gateway holds the enabled providers.
+type Gateway struct {
+	providers map[string]Provider
+	names     []string
+}
+
+func New(cfg Config) (*Gateway, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	providers := make([]Provider, 0, len(cfg.Providers))
+	for _, name := range cfg.Providers {
+		switch name {
+		case FakeProvider:
+			providers = append(providers, NewFake(cfg.FakeSecret))
+		case CashOnDelivery:
+			providers = append(providers, NewCashOnDelivery())
+		}
+	}
+	return NewGateway(providers...), nil
+}
+
+// NewGateway enables providers, the first one is the default.
+func NewGateway(providers ...Provider) *Gateway {
+	g := &Gateway{providers: map[string]Provider{}}
+	for _, p := range providers {
+		g.providers[p.Name()] = p
+		g.names = append(g.names, p.Name())
+	}
+	return g
+}
+
+// Provider returns the provider called name, or the default one when name
+// is empty.
+func (g *Gateway) Provider(name string) (Provider, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		if len(g.names) == 0 {
+			return nil, false
+		}
+		name = g.names[0]
+	}
+	p, ok := g.providers[name]
+	return p, ok
+}
+
+// Names lists the enabled providers, default first.
+func (g *Gateway) Names() []string {
+	return append([]string(nil), g.names...)
+}
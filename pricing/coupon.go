@@ -0,0 +1,146 @@
+package pricing
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// reasons a coupon is turned down, their messages are shown to customers
+var (
+	ErrCouponNotStarted    = errors.New(constant.CouponNotStartedError)
+	ErrCouponExpired       = errors.New(constant.CouponExpiredError)
+	ErrCouponUsedUp        = errors.New(constant.CouponUsedUpError)
+	ErrCouponUserLimit     = errors.New(constant.CouponUserLimitError)
+	ErrCouponMinCartValue  = errors.New(constant.CouponMinCartValueError)
+	ErrCouponNotApplicable = errors.New(constant.CouponNotApplicableError)
+)
+
+// CheckCoupon reports why coupon can not be used at now by a customer
+// who already redeemed it used times, nil when it can.
+func CheckCoupon(coupon types.Coupon, used int, now int64) error {
+	switch {
+	case coupon.StartsAt > now:
+		return ErrCouponNotStarted
+	case coupon.EndsAt > 0 && now >= coupon.EndsAt:
+		return ErrCouponExpired
+	case coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit:
+		return ErrCouponUsedUp
+	case coupon.PerUserLimit > 0 && used >= coupon.PerUserLimit:
+		return ErrCouponUserLimit
+	}
+	return nil
+}
+
+// inScope reports whether the coupon covers the product of line.
+func inScope(coupon types.Coupon, line Line) bool {
+	if len(coupon.ProductIds) == 0 && len(coupon.CategoryIds) == 0 {
+		return true
+	}
+	for _, id := range coupon.ProductIds {
+		if id == line.ProductID {
+			return true
+		}
+	}
+	for _, id := range coupon.CategoryIds {
+		for _, category := range line.CategoryIDs {
+			if id == category {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CouponDiscount returns the amount coupon takes off lines. The minimum
+// cart value is checked against the whole cart, the discount is worked
+// out on the lines in the coupon scope only and never exceeds them. A
+// percentage is taken of their total, not per unit, so it is rounded once.
+func CouponDiscount(coupon types.Coupon, lines []Line, used int, now int64) (types.Money, error) {
+	if err := CheckCoupon(coupon, used, now); err != nil {
+		return types.Money{}, err
+	}
+	subtotal, err := Subtotal(lines)
+	if err != nil {
+		return types.Money{}, err
+	}
+	short, err := subtotal.Less(coupon.MinCartValue)
+	if err != nil {
+		return types.Money{}, err
+	}
+	if short {
+		return types.Money{}, ErrCouponMinCartValue
+	}
+
+	eligible, _, err := scopeTotal(coupon, lines)
+	if err != nil {
+		return types.Money{}, err
+	}
+	if eligible.IsZero() {
+		return types.Money{}, ErrCouponNotApplicable
+	}
+
+	var discount types.Money
+	switch coupon.Type {
+	case constant.CouponFixed:
+		discount = coupon.AmountOff
+	default:
+		// coupons from before the type existed are percentages
+		discount = eligible.Percent(coupon.Discount)
+	}
+	if !coupon.MaxDiscount.IsZero() {
+		if discount, err = discount.Min(coupon.MaxDiscount); err != nil {
+			return types.Money{}, err
+		}
+	}
+	return discount.Min(eligible)
+}
+
+// scopeTotal adds up the lines in the coupon scope and returns their
+// indexes.
+func scopeTotal(coupon types.Coupon, lines []Line) (types.Money, []int, error) {
+	var total types.Money
+	in := []int{}
+	for i, line := range lines {
+		if !inScope(coupon, line) {
+			continue
+		}
+		var err error
+		if total, err = total.Add(line.Total()); err != nil {
+			return types.Money{}, nil, err
+		}
+		in = append(in, i)
+	}
+	return total, in, nil
+}
+
+// spreadDiscount shares discount out over the lines in the coupon scope in
+// proportion to their totals, so each line can be taxed on what is paid
+// for it. Shares are rounded down and the units left over go to the lines
+// that lost the most to rounding, the shares add up to discount exactly.
+func spreadDiscount(coupon types.Coupon, lines []Line, discount types.Money) error {
+	eligible, in, err := scopeTotal(coupon, lines)
+	if err != nil || eligible.Amount <= 0 {
+		return err
+	}
+
+	rest := discount.Amount
+	remainders := make([]uint64, len(lines))
+	for _, i := range in {
+		// discount never exceeds eligible, so the quotient fits
+		hi, lo := bits.Mul64(uint64(discount.Amount), uint64(lines[i].Total().Amount))
+		share, rem := bits.Div64(hi, lo, uint64(eligible.Amount))
+		lines[i].Discount = types.Money{Amount: int64(share), Currency: discount.Currency}
+		remainders[i] = rem
+		rest -= int64(share)
+	}
+
+	sort.SliceStable(in, func(a, b int) bool { return remainders[in[a]] > remainders[in[b]] })
+	for _, i := range in[:rest] {
+		lines[i].Discount.Amount++
+	}
+	return nil
+}
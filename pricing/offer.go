@@ -0,0 +1,52 @@
+package pricing
+
+import "github.com/PiehTVH/go-ecommerce/types"
+
+// Price is what a product costs once offers are taken into account.
+type Price struct {
+	ListPrice types.Money `json:"list_price"`
+	Price     types.Money `json:"price"`
+	// Offer is the offer Price comes from, nil when none applies
+	Offer *types.Offer `json:"offer,omitempty"`
+}
+
+// offerCovers reports whether offer targets the product or one of
+// categoryIDs.
+func offerCovers(offer types.Offer, productID string, categoryIDs []string) bool {
+	if offer.ProductId != "" {
+		return offer.ProductId == productID
+	}
+	for _, id := range categoryIDs {
+		if id == offer.CategoryId {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve picks the offer running at now that gives product the lowest
+// price. categoryIDs are the category of the product and its ancestors,
+// so offers on a parent category reach its subcategories. Offers do not
+// add up, only the best one applies. The percentage is taken of the unit
+// price, rounded half up to the minor unit.
+func Resolve(product types.Product, categoryIDs []string, offers []types.Offer, now int64) (Price, error) {
+	best := Price{ListPrice: product.Price, Price: product.Price}
+	for i, offer := range offers {
+		if !offer.Running(now) || !offerCovers(offer, product.ID.Hex(), categoryIDs) {
+			continue
+		}
+		price, err := product.Price.Sub(product.Price.Percent(offer.Discount))
+		if err != nil {
+			return Price{}, err
+		}
+		cheaper, err := price.Less(best.Price)
+		if err != nil {
+			return Price{}, err
+		}
+		if cheaper {
+			best.Price = price
+			best.Offer = &offers[i]
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,68 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolve(t *testing.T) {
+	product := types.Product{ID: primitive.NewObjectID(), Price: inr(999)}
+	categories := []string{"shirts", "clothing"}
+	tests := []struct {
+		name      string
+		offers    []types.Offer
+		wantPrice int64
+		wantOffer string
+	}{
+		{name: "no offer", wantPrice: 999},
+		// 15% of 9.99 is 1.4985
+		{name: "product offer", offers: []types.Offer{{Name: "p", ProductId: product.ID.Hex(), Discount: 15}}, wantPrice: 849, wantOffer: "p"},
+		{name: "parent category offer", offers: []types.Offer{{Name: "c", CategoryId: "clothing", Discount: 10}}, wantPrice: 899, wantOffer: "c"},
+		{
+			name: "best offer wins",
+			offers: []types.Offer{
+				{Name: "small", CategoryId: "shirts", Discount: 10},
+				{Name: "big", CategoryId: "clothing", Discount: 20},
+				{Name: "also big", ProductId: product.ID.Hex(), Discount: 20},
+			},
+			wantPrice: 799,
+			wantOffer: "big",
+		},
+		{
+			name: "offers outside their window",
+			offers: []types.Offer{
+				{Name: "later", CategoryId: "shirts", Discount: 50, StartsAt: 101},
+				{Name: "over", CategoryId: "shirts", Discount: 50, EndsAt: 100},
+			},
+			wantPrice: 999,
+		},
+		{
+			name: "offers on other products",
+			offers: []types.Offer{
+				{Name: "books", CategoryId: "books", Discount: 50},
+				{Name: "hat", ProductId: primitive.NewObjectID().Hex(), Discount: 50},
+			},
+			wantPrice: 999,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(product, categories, tt.offers, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ListPrice != inr(999) || got.Price != inr(tt.wantPrice) {
+				t.Errorf("Resolve() = %v from %v, want %d from 999", got.Price, got.ListPrice, tt.wantPrice)
+			}
+			offer := ""
+			if got.Offer != nil {
+				offer = got.Offer.Name
+			}
+			if offer != tt.wantOffer {
+				t.Errorf("Resolve() picked offer %q, want %q", offer, tt.wantOffer)
+			}
+		})
+	}
+}
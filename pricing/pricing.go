@@ -0,0 +1,206 @@
+// Package pricing works out what a cart costs: line totals, discounts,
+// tax, shipping and the amount left to pay.
+package pricing
+
+import "github.com/PiehTVH/go-ecommerce/types"
+
+// Line is one cart line as the pricing rules see it.
+type Line struct {
+	ProductID string `json:"product_id"`
+	Name      string `json:"name"`
+	// CategoryIDs holds the category of the product and its ancestors
+	CategoryIDs []string `json:"-"`
+	// Weight in grams and Volume in cubic millimetres of one unit
+	Weight    int         `json:"-"`
+	Volume    int64       `json:"-"`
+	ListPrice types.Money `json:"list_price"`
+	// UnitPrice is the list price less the best running offer
+	UnitPrice types.Money `json:"unit_price"`
+	Quantity  int         `json:"quantity"`
+	// Available is false when there is less stock than Quantity
+	Available bool `json:"available"`
+	// Discount is the share of the coupon discount taken off the line
+	Discount types.Money `json:"discount"`
+	// TaxRate is in hundredths of a percent
+	TaxRate int         `json:"tax_rate"`
+	Tax     types.Money `json:"tax"`
+}
+
+func (l Line) Total() types.Money {
+	return l.UnitPrice.Mul(l.Quantity)
+}
+
+// Taxable is what the tax of the line is worked out on, its total less
+// its share of the discount.
+func (l Line) Taxable() (types.Money, error) {
+	return l.Total().Sub(l.Discount)
+}
+
+// Breakdown is the price of a cart.
+type Breakdown struct {
+	Subtotal types.Money `json:"subtotal"`
+	Discount types.Money `json:"discount"`
+	// Tax is added to Total, unless TaxInclusive tells that the prices
+	// contain it already
+	Tax          types.Money `json:"tax"`
+	TaxInclusive bool        `json:"tax_inclusive"`
+	// Shipping is the price of the chosen shipping method
+	Shipping types.Money `json:"shipping"`
+	Total    types.Money `json:"total"`
+	// Coupon is the code the discount comes from
+	Coupon string `json:"coupon,omitempty"`
+}
+
+// Cart is a priced cart as the customer sees it.
+type Cart struct {
+	Lines    []Line `json:"items"`
+	NumItems int    `json:"num_items"`
+	Breakdown
+	// TaxRegion is where the cart is taxed, no tax is worked out without
+	// a country
+	TaxRegion types.TaxRegion `json:"tax_region"`
+	// ShippingRequired is set when the store has shipping methods, one of
+	// ShippingOptions, those able to ship the cart there, must then be
+	// chosen as ShippingMethod
+	ShippingRequired bool                   `json:"shipping_required"`
+	ShippingOptions  []types.ShippingOption `json:"shipping_options"`
+	ShippingMethod   string                 `json:"shipping_method,omitempty"`
+	// CouponError tells why the coupon on the cart gives no discount
+	CouponError string `json:"coupon_error,omitempty"`
+}
+
+// Convert returns cart with its amounts passed through convert, to show it
+// in another currency. The amounts of each line are converted one by one
+// and the totals summed up again from them, so the cart still adds up.
+func Convert(cart Cart, convert func(types.Money) types.Money) (Cart, error) {
+	var err error
+	zero := convert(types.Money{Currency: cart.Subtotal.Currency})
+	out := cart
+	out.Lines = make([]Line, 0, len(cart.Lines))
+	out.Discount, out.Tax = zero, zero
+	for _, line := range cart.Lines {
+		line.ListPrice = convert(line.ListPrice)
+		line.UnitPrice = convert(line.UnitPrice)
+		line.Discount = convert(line.Discount)
+		line.Tax = convert(line.Tax)
+		out.Lines = append(out.Lines, line)
+		if out.Discount, err = out.Discount.Add(line.Discount); err != nil {
+			return Cart{}, err
+		}
+		if out.Tax, err = out.Tax.Add(line.Tax); err != nil {
+			return Cart{}, err
+		}
+	}
+
+	out.ShippingOptions = make([]types.ShippingOption, 0, len(cart.ShippingOptions))
+	for _, option := range cart.ShippingOptions {
+		option.Price = convert(option.Price)
+		out.ShippingOptions = append(out.ShippingOptions, option)
+	}
+
+	subtotal, err := Subtotal(out.Lines)
+	if err != nil {
+		return Cart{}, err
+	}
+	if out.Subtotal, err = zero.Add(subtotal); err != nil {
+		return Cart{}, err
+	}
+	if out.Discount, err = out.Discount.Min(out.Subtotal); err != nil {
+		return Cart{}, err
+	}
+	if out.Shipping, err = zero.Add(convert(cart.Shipping)); err != nil {
+		return Cart{}, err
+	}
+	if err := out.total(); err != nil {
+		return Cart{}, err
+	}
+	return out, nil
+}
+
+// total works out what is left to pay from the other amounts.
+func (b *Breakdown) total() error {
+	total, err := b.Subtotal.Sub(b.Discount)
+	if err == nil && !b.TaxInclusive {
+		total, err = total.Add(b.Tax)
+	}
+	if err == nil {
+		total, err = total.Add(b.Shipping)
+	}
+	if err != nil {
+		return err
+	}
+	b.Total = total
+	return nil
+}
+
+// ChooseShipping ships the cart with option, its price is added to the
+// total.
+func (c *Cart) ChooseShipping(option types.ShippingOption) error {
+	c.ShippingMethod = option.MethodID
+	c.Shipping = option.Price
+	return c.total()
+}
+
+// Parcel returns the weight and volume of every unit in the cart together.
+func (c Cart) Parcel() (weight int, volume int64) {
+	for _, line := range c.Lines {
+		weight += line.Weight * line.Quantity
+		volume += line.Volume * int64(line.Quantity)
+	}
+	return weight, volume
+}
+
+// Subtotal is what the lines cost before any discount.
+func Subtotal(lines []Line) (types.Money, error) {
+	var total types.Money
+	for _, line := range lines {
+		var err error
+		if total, err = total.Add(line.Total()); err != nil {
+			return types.Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// Quote prices lines in currency and applies coupon when there is one.
+// used is how many times the customer already redeemed it. When the coupon
+// can not be applied the breakdown comes without discount, along with the
+// reason. The discount is spread over the lines it comes from, their
+// Discount is set. Tax is left to ApplyTax.
+//
+// Amounts in another currency than currency fail with
+// types.ErrCurrencyMismatch, the breakdown is then of no use.
+func Quote(lines []Line, currency string, coupon *types.Coupon, used int, now int64) (Breakdown, error) {
+	zero := types.Money{Currency: currency}
+	subtotal, err := Subtotal(lines)
+	if err == nil {
+		subtotal, err = zero.Add(subtotal)
+	}
+	if err != nil {
+		return Breakdown{}, err
+	}
+	b := Breakdown{Subtotal: subtotal, Discount: zero, Tax: zero, Shipping: zero}
+	for i := range lines {
+		lines[i].Discount, lines[i].Tax = zero, zero
+	}
+	b.Total = b.Subtotal
+	if coupon == nil {
+		return b, nil
+	}
+
+	discount, err := CouponDiscount(*coupon, lines, used, now)
+	if err != nil {
+		return b, err
+	}
+	total, err := b.Subtotal.Sub(discount)
+	if err != nil {
+		return b, err
+	}
+	if err := spreadDiscount(*coupon, lines, discount); err != nil {
+		return b, err
+	}
+	b.Discount = discount
+	b.Total = total
+	b.Coupon = coupon.Name
+	return b, nil
+}
@@ -0,0 +1,242 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+func inr(amount int64) types.Money {
+	return types.NewMoney(amount, "INR")
+}
+
+// testLines are a shirt in category "shirts" under "clothing", and a book.
+func testLines() []Line {
+	return []Line{
+		{ProductID: "shirt", CategoryIDs: []string{"shirts", "clothing"}, UnitPrice: inr(1000), Quantity: 3},
+		{ProductID: "book", CategoryIDs: []string{"books"}, UnitPrice: inr(500), Quantity: 1},
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name         string
+		coupon       *types.Coupon
+		used         int
+		wantDiscount int64
+		wantErr      error
+	}{
+		{name: "no coupon"},
+		{name: "percentage", coupon: &types.Coupon{Type: constant.CouponPercentage, Discount: 10}, wantDiscount: 350},
+		{name: "coupon from before types", coupon: &types.Coupon{Discount: 10}, wantDiscount: 350},
+		{name: "percentage capped", coupon: &types.Coupon{Type: constant.CouponPercentage, Discount: 50, MaxDiscount: inr(1000)}, wantDiscount: 1000},
+		{name: "fixed", coupon: &types.Coupon{Type: constant.CouponFixed, AmountOff: inr(700)}, wantDiscount: 700},
+		{name: "fixed above the cart", coupon: &types.Coupon{Type: constant.CouponFixed, AmountOff: inr(9000)}, wantDiscount: 3500},
+		{name: "scoped to a parent category", coupon: &types.Coupon{Discount: 10, CategoryIds: []string{"clothing"}}, wantDiscount: 300},
+		{name: "scoped to a product", coupon: &types.Coupon{Type: constant.CouponFixed, AmountOff: inr(900), ProductIds: []string{"book"}}, wantDiscount: 500},
+		{name: "scoped to nothing in the cart", coupon: &types.Coupon{Discount: 10, ProductIds: []string{"hat"}}, wantErr: ErrCouponNotApplicable},
+		{name: "cart too small", coupon: &types.Coupon{Discount: 10, MinCartValue: inr(3501)}, wantErr: ErrCouponMinCartValue},
+		{name: "cart just big enough", coupon: &types.Coupon{Discount: 10, MinCartValue: inr(3500)}, wantDiscount: 350},
+		{name: "not started", coupon: &types.Coupon{Discount: 10, StartsAt: 200}, wantErr: ErrCouponNotStarted},
+		{name: "expired", coupon: &types.Coupon{Discount: 10, EndsAt: 100}, wantErr: ErrCouponExpired},
+		{name: "used up", coupon: &types.Coupon{Discount: 10, UsageLimit: 5, UsedCount: 5}, wantErr: ErrCouponUsedUp},
+		{name: "used up by the customer", coupon: &types.Coupon{Discount: 10, PerUserLimit: 1}, used: 1, wantErr: ErrCouponUserLimit},
+		{name: "minimum in another currency", coupon: &types.Coupon{Discount: 10, MinCartValue: types.NewMoney(1, "USD")}, wantErr: types.ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := testLines()
+			b, err := Quote(lines, "INR", tt.coupon, tt.used, 100)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Quote() error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(err, types.ErrCurrencyMismatch) {
+				return
+			}
+			// turned down coupons leave the cart priced without them
+			if b.Subtotal != inr(3500) {
+				t.Errorf("subtotal = %v, want %v", b.Subtotal, inr(3500))
+			}
+			if err != nil {
+				return
+			}
+			if b.Discount != inr(tt.wantDiscount) || b.Total != inr(3500-tt.wantDiscount) {
+				t.Errorf("discount %v and total %v, want %d and %d", b.Discount, b.Total, tt.wantDiscount, 3500-tt.wantDiscount)
+			}
+
+			// the discount is spread over the lines it comes from
+			var spread int64
+			for _, line := range lines {
+				spread += line.Discount.Amount
+				if line.Discount.Amount > line.Total().Amount {
+					t.Errorf("line %s takes %v off %v", line.ProductID, line.Discount, line.Total())
+				}
+			}
+			if spread != tt.wantDiscount {
+				t.Errorf("lines share %d of the discount, want %d", spread, tt.wantDiscount)
+			}
+		})
+	}
+
+	t.Run("prices in another currency", func(t *testing.T) {
+		lines := testLines()
+		lines[1].UnitPrice = types.NewMoney(5, "USD")
+		if _, err := Quote(lines, "INR", nil, 0, 100); !errors.Is(err, types.ErrCurrencyMismatch) {
+			t.Errorf("Quote() error = %v, want %v", err, types.ErrCurrencyMismatch)
+		}
+	})
+}
+
+func TestSpreadDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		totals   []int64
+		discount int64
+		want     []int64
+	}{
+		{name: "even", totals: []int64{1000, 1000}, discount: 200, want: []int64{100, 100}},
+		{name: "proportional", totals: []int64{3000, 1000}, discount: 400, want: []int64{300, 100}},
+		// 100 over thirds leaves one unit, it goes to the line that lost
+		// the most to rounding
+		{name: "remainder", totals: []int64{100, 100, 100}, discount: 100, want: []int64{34, 33, 33}},
+		{name: "remainder to the largest fraction", totals: []int64{1, 2}, discount: 2, want: []int64{1, 1}},
+		{name: "everything", totals: []int64{700, 300}, discount: 1000, want: []int64{700, 300}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]Line, len(tt.totals))
+			for i, total := range tt.totals {
+				lines[i] = Line{UnitPrice: inr(total), Quantity: 1}
+			}
+			if err := spreadDiscount(types.Coupon{}, lines, inr(tt.discount)); err != nil {
+				t.Fatal(err)
+			}
+			for i := range lines {
+				if lines[i].Discount != inr(tt.want[i]) {
+					t.Errorf("line %d discount = %v, want %d", i, lines[i].Discount, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyTax(t *testing.T) {
+	tests := []struct {
+		name      string
+		inclusive bool
+		shipping  int64
+		wantTotal int64
+	}{
+		{name: "exclusive", wantTotal: 3500 + 450},
+		{name: "inclusive", inclusive: true, wantTotal: 3500},
+		{name: "with shipping", shipping: 200, wantTotal: 3500 + 450 + 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := Cart{Lines: testLines()}
+			b, err := Quote(cart.Lines, "INR", nil, 0, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cart.Breakdown = b
+			if err := cart.ApplyTax([]LineTax{{Rate: 1200, Tax: inr(360)}, {Rate: 1800, Tax: inr(90)}}, tt.inclusive); err != nil {
+				t.Fatal(err)
+			}
+			if tt.shipping > 0 {
+				if err := cart.ChooseShipping(types.ShippingOption{MethodID: "m", Price: inr(tt.shipping)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if cart.Tax != inr(450) || cart.Total != inr(tt.wantTotal) {
+				t.Errorf("tax %v and total %v, want 450 and %d", cart.Tax, cart.Total, tt.wantTotal)
+			}
+			if cart.Lines[0].TaxRate != 1200 || cart.Lines[1].Tax != inr(90) {
+				t.Errorf("line taxes not recorded: %+v", cart.Lines)
+			}
+		})
+	}
+
+	t.Run("line count mismatch", func(t *testing.T) {
+		cart := Cart{Lines: testLines()}
+		if err := cart.ApplyTax([]LineTax{{}}, false); err == nil {
+			t.Error("ApplyTax() with too few taxes succeeded")
+		}
+	})
+
+	t.Run("tax in another currency", func(t *testing.T) {
+		cart := Cart{Lines: testLines(), Breakdown: Breakdown{Subtotal: inr(3500)}}
+		err := cart.ApplyTax([]LineTax{{Tax: types.NewMoney(1, "USD")}, {}}, false)
+		if !errors.Is(err, types.ErrCurrencyMismatch) {
+			t.Errorf("ApplyTax() error = %v, want %v", err, types.ErrCurrencyMismatch)
+		}
+	})
+}
+
+func TestConvert(t *testing.T) {
+	// 1 INR buys 1/3 of a unit of XTS, so every amount rounds
+	convert := func(m types.Money) types.Money {
+		return types.Money{Amount: (2*m.Amount + 3) / 6, Currency: "XTS"}
+	}
+
+	cart := Cart{Lines: testLines()}
+	b, err := Quote(cart.Lines, "INR", &types.Coupon{Discount: 10}, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cart.Breakdown = b
+	if err := cart.ApplyTax([]LineTax{{Rate: 1000, Tax: inr(271)}, {Rate: 1000, Tax: inr(46)}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := cart.ChooseShipping(types.ShippingOption{Price: inr(100)}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Convert(cart, convert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the totals are summed up from the converted lines, so they add up
+	var subtotal, discount, tax int64
+	for _, line := range got.Lines {
+		if line.UnitPrice.Currency != "XTS" {
+			t.Errorf("line in %s, want XTS", line.UnitPrice.Currency)
+		}
+		subtotal += line.Total().Amount
+		discount += line.Discount.Amount
+		tax += line.Tax.Amount
+	}
+	if got.Subtotal.Amount != subtotal || got.Discount.Amount != discount || got.Tax.Amount != tax {
+		t.Errorf("converted totals %v %v %v, lines add up to %d %d %d", got.Subtotal, got.Discount, got.Tax, subtotal, discount, tax)
+	}
+	if want := subtotal - discount + tax + got.Shipping.Amount; got.Total.Amount != want {
+		t.Errorf("converted total %v, want %d", got.Total, want)
+	}
+	if cart.Total != inr(3500-350+317+100) {
+		t.Errorf("Convert() changed the cart it was given, total %v", cart.Total)
+	}
+}
+
+func TestCheckCoupon(t *testing.T) {
+	tests := []struct {
+		name   string
+		coupon types.Coupon
+		used   int
+		want   error
+	}{
+		{name: "usable", coupon: types.Coupon{StartsAt: 100, EndsAt: 101, UsageLimit: 2, UsedCount: 1, PerUserLimit: 2}, used: 1},
+		{name: "starts later", coupon: types.Coupon{StartsAt: 101}, want: ErrCouponNotStarted},
+		{name: "ends now", coupon: types.Coupon{EndsAt: 100}, want: ErrCouponExpired},
+		{name: "used up", coupon: types.Coupon{UsageLimit: 2, UsedCount: 2}, want: ErrCouponUsedUp},
+		{name: "used up by the customer", coupon: types.Coupon{PerUserLimit: 2}, used: 2, want: ErrCouponUserLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckCoupon(tt.coupon, tt.used, 100); err != tt.want {
+				t.Errorf("CheckCoupon() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// TaxCalculator works out the tax on the lines of a cart. Package tax
+// applies rules kept by the store, an external tax service can stand in
+// for it.
+type TaxCalculator interface {
+	// Tax returns one LineTax per line of req, in the same order.
+	Tax(ctx context.Context, req TaxRequest) ([]LineTax, error)
+}
+
+// TaxRequest asks for the tax on lines shipped to Region.
+type TaxRequest struct {
+	Region   types.TaxRegion
+	Currency string
+	// Inclusive is set when prices contain the tax already, it is then
+	// taken out of Taxable instead of added on top
+	Inclusive bool
+	Lines     []Line
+}
+
+// LineTax is the tax on one line.
+type LineTax struct {
+	// Rate is in hundredths of a percent
+	Rate int
+	Tax  types.Money
+}
+
+// ApplyTax records the tax of each line and adds it up, taxes comes from a
+// TaxCalculator asked about the lines of the cart.
+func (c *Cart) ApplyTax(taxes []LineTax, inclusive bool) error {
+	if len(taxes) != len(c.Lines) {
+		return fmt.Errorf("pricing: got tax for %d lines, the cart has %d", len(taxes), len(c.Lines))
+	}
+
+	currency := c.Subtotal.Currency
+	c.Tax = types.Money{Currency: currency}
+	c.TaxInclusive = inclusive
+	for i, tax := range taxes {
+		c.Lines[i].TaxRate = tax.Rate
+		c.Lines[i].Tax = tax.Tax.DefaultCurrency(currency)
+		var err error
+		if c.Tax, err = c.Tax.Add(tax.Tax); err != nil {
+			return err
+		}
+	}
+	return c.total()
+}
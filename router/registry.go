@@ -0,0 +1,89 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/gin-gonic/gin"
+)
+
+// routeGroup is a list of routes mounted under one path prefix.
+type routeGroup struct {
+	Name   string
+	Prefix string
+	// CORS adds the CORS headers, which also mark responses as JSON.
+	CORS   bool
+	Routes Routes
+}
+
+// RouteInfo describes a mounted route for operators.
+type RouteInfo struct {
+	Group      string `json:"group"`
+	Name       string `json:"name"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Permission string `json:"permission"`
+}
+
+// registry collects every route group before anything is mounted, so a
+// method and path claimed twice is reported at startup.
+type registry struct {
+	groups []routeGroup
+	seen   map[string]string
+	table  []RouteInfo
+}
+
+func newRegistry() *registry {
+	return &registry{seen: map[string]string{}}
+}
+
+func (reg *registry) add(group routeGroup) error {
+	for _, route := range group.Routes {
+		full := path.Join(group.Prefix, route.Pattern)
+		key := route.Method + " " + full
+		if other, ok := reg.seen[key]; ok {
+			return fmt.Errorf("router: %s is registered by both %q and %q", key, other, route.Name)
+		}
+		reg.seen[key] = route.Name
+		reg.table = append(reg.table, RouteInfo{
+			Group:      group.Name,
+			Name:       route.Name,
+			Method:     route.Method,
+			Path:       full,
+			Permission: route.Permission,
+		})
+	}
+	reg.groups = append(reg.groups, group)
+	return nil
+}
+
+func (reg *registry) mount(r routes) {
+	for _, group := range reg.groups {
+		rg := r.router.Group(group.Prefix)
+		if group.CORS {
+			rg.Use(CORSMiddleware())
+		}
+		r.mount(rg, group.Routes)
+	}
+}
+
+// @Summary List routes
+// @Description List every mounted route with the permission it needs
+// @Tags Admin
+// @Produce json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Token"
+// @Success 200 {object}  string
+// @Router /v1/ecommerce/routes [get]
+func (reg *registry) ListRoutes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"error": false, "message": "success", "data": reg.table})
+}
+
+// operatorRoutes are served from the registry itself.
+func operatorRoutes(reg *registry) Routes {
+	return Routes{
+		Route{"List Routes", http.MethodGet, constant.ListRoutesRoute, constant.PermissionViewRoutes, reg.ListRoutes},
+	}
+}
@@ -1,76 +1,61 @@
 package router
 
 import (
-	"log"
 	"net/http"
-	"os"
+	"time"
 
+	"github.com/PiehTVH/go-ecommerce/config"
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/controller"
+	"github.com/PiehTVH/go-ecommerce/database"
 	"github.com/PiehTVH/go-ecommerce/docs"
+	"github.com/PiehTVH/go-ecommerce/exchange"
+	"github.com/PiehTVH/go-ecommerce/helper"
+	"github.com/PiehTVH/go-ecommerce/mailer"
+	"github.com/PiehTVH/go-ecommerce/payment"
+	"github.com/PiehTVH/go-ecommerce/pricing"
 	"github.com/gin-gonic/gin"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 type Route struct {
-	Name        string
-	Method      string
-	Pattern     string
+	Name    string
+	Method  string
+	Pattern string
+	// Permission the caller needs, constant.PermissionPublic for none
+	Permission  string
 	HandlerFunc func(*gin.Context)
 }
 
 type routes struct {
-	router *gin.Engine
+	router  *gin.Engine
+	db      database.Manager
+	handler *controller.Handler
 }
 
 type Routes []Route
 
-/*
-*	Function for grouping user routes
- */
-func (r routes) EcommerceUser(rg *gin.RouterGroup) {
-	orderRouteGrouping := rg.Group("/ecommerce")
-	orderRouteGrouping.Use(CORSMiddleware())
-	for _, route := range userRoutes {
-		switch route.Method {
-		case "GET":
-			orderRouteGrouping.GET(route.Pattern, route.HandlerFunc)
-		case "POST":
-			orderRouteGrouping.POST(route.Pattern, route.HandlerFunc)
-		case "OPTIONS":
-			orderRouteGrouping.OPTIONS(route.Pattern, route.HandlerFunc)
-		case "PUT":
-			orderRouteGrouping.PUT(route.Pattern, route.HandlerFunc)
-		case "DELETE":
-			orderRouteGrouping.DELETE(route.Pattern, route.HandlerFunc)
-		default:
-			orderRouteGrouping.GET(route.Pattern, func(c *gin.Context) {
-				c.JSON(200, gin.H{
-
-					"result": "Specify a valid http method with this route.",
-				})
-			})
+// mount registers every route of the list on the given group.
+func (r routes) mount(rg *gin.RouterGroup, list Routes) {
+	for _, route := range list {
+		handlers := []gin.HandlerFunc{}
+		if route.Permission != constant.PermissionPublic {
+			handlers = append(handlers, AuthMiddleware(r.db), RequirePermission(route.Permission))
 		}
+		handlers = append(handlers, route.HandlerFunc)
 
-	}
-}
-
-func (r routes) EcommerceGlobalProductRoutes(rg *gin.RouterGroup) {
-	orderRouteGrouping := rg.Group("/ecommerce")
-	// orderRouteGrouping.Use(CORSMiddleware())
-	for _, route := range productGlobalRoutes {
 		switch route.Method {
 		case "GET":
-			orderRouteGrouping.GET(route.Pattern, route.HandlerFunc)
+			rg.GET(route.Pattern, handlers...)
 		case "POST":
-			orderRouteGrouping.POST(route.Pattern, route.HandlerFunc)
+			rg.POST(route.Pattern, handlers...)
 		case "OPTIONS":
-			orderRouteGrouping.OPTIONS(route.Pattern, route.HandlerFunc)
+			rg.OPTIONS(route.Pattern, handlers...)
 		case "PUT":
-			orderRouteGrouping.PUT(route.Pattern, route.HandlerFunc)
+			rg.PUT(route.Pattern, handlers...)
 		case "DELETE":
-			orderRouteGrouping.DELETE(route.Pattern, route.HandlerFunc)
+			rg.DELETE(route.Pattern, handlers...)
 		default:
-			orderRouteGrouping.GET(route.Pattern, func(c *gin.Context) {
+			rg.GET(route.Pattern, func(c *gin.Context) {
 				c.JSON(200, gin.H{
 					"result": "Specify a valid http method with this route.",
 				})
@@ -79,18 +64,24 @@ func (r routes) EcommerceGlobalProductRoutes(rg *gin.RouterGroup) {
 	}
 }
 
-func (r routes) Swagger(rg *gin.RouterGroup) {
-	orderRouteGrouping := rg.Group("/ecommerce")
-	orderRouteGrouping.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-}
-
-func ClientRoutes() {
+// New builds the HTTP handler of the API. It fails when two routes claim
+// the same method and path.
+func New(cfg config.Config, db database.Manager, mail mailer.Mailer, pay *payment.Gateway, rates exchange.RateProvider, taxes pricing.TaxCalculator) (*gin.Engine, error) {
 	r := routes{
-		router: gin.Default(),
+		router:  gin.Default(),
+		db:      db,
+		handler: controller.NewHandler(cfg, db, mail, pay, rates, taxes),
 	}
 
-	v1 := r.router.Group(os.Getenv("API_VERSION"))
-	r.EcommerceUser(v1)
+	api := "/" + cfg.APIVersion + "/ecommerce"
+	reg := newRegistry()
+	groups := append(r.groups(api), routeGroup{"operator", api, true, operatorRoutes(reg)})
+	for _, group := range groups {
+		if err := reg.add(group); err != nil {
+			return nil, err
+		}
+	}
+	reg.mount(r)
 
 	// Swagger docs
 	docs.SwaggerInfo.Title = "Elegance API"
@@ -99,10 +90,7 @@ func ClientRoutes() {
 	docs.SwaggerInfo.Host = "https://ethnicelegance.onrender.com"
 	docs.SwaggerInfo.BasePath = "/v1/ecommerce"
 
-	if err := r.router.Run(":" + os.Getenv("PORT")); err != nil {
-		log.Printf("Failed to run server: %v", err)
-	}
-
+	return r.router, nil
 }
 
 // Middlewares
@@ -116,3 +104,49 @@ func CORSMiddleware() gin.HandlerFunc {
 		}
 	}
 }
+
+// AuthMiddleware rejects requests without a valid access token, or whose
+// session has been revoked, and stores the verified caller on the context
+// for the handlers.
+func AuthMiddleware(db database.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Request.Header.Get("Authorization")
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.TokenRequiredError})
+			return
+		}
+
+		principal, err := helper.VerifyToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidTokenError})
+			return
+		}
+
+		session, err := db.Sessions().FindByID(c, principal.SessionID)
+		if err != nil || session.UserID != principal.UserID || !session.Active(time.Now().Unix()) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.InvalidTokenError})
+			return
+		}
+
+		helper.SetPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// RequirePermission lets the request through only when the role of the
+// authenticated caller grants permission. It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := helper.CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": true, "message": constant.TokenRequiredError})
+			return
+		}
+
+		if !helper.HasPermission(principal.Role, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": true, "message": constant.PermissionDeniedError})
+			return
+		}
+		c.Next()
+	}
+}
@@ -5,24 +5,173 @@ import (
 
 	"github.com/PiehTVH/go-ecommerce/constant"
 	"github.com/PiehTVH/go-ecommerce/controller"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// groups lists every route group of the API, api is the prefix of the
+// versioned routes.
+func (r routes) groups(api string) []routeGroup {
+	h := r.handler
+	return []routeGroup{
+		{"health", "/", false, healthCheckRoutes(h)},
+		{"swagger", api, false, swaggerRoutes()},
+		{"user", api, true, userRoutes(h)},
+		{"product", api, true, productGlobalRoutes(h)},
+		{"account", api, true, authenticatedUserRoutes(h)},
+		{"admin", api, true, adminRoutes(h)},
+		{"payment", api, true, paymentRoutes(h)},
+	}
+}
+
 // health check service
-var healthCheckRoutes = Routes{
-	Route{"Health check", http.MethodGet, constant.HealthCheckRoute, controller.HealthCheck},
+func healthCheckRoutes(h *controller.Handler) Routes {
+	return Routes{
+		Route{"Health check", http.MethodGet, constant.HealthCheckRoute, constant.PermissionPublic, controller.HealthCheck},
+	}
+}
+
+func userRoutes(h *controller.Handler) Routes {
+	return Routes{
+
+		// Register User
+		Route{"Register User", http.MethodPost, constant.UserRegisterRoute, constant.PermissionPublic, h.RegisterUser},
+		Route{"Login User", http.MethodPost, constant.UserLoginRoute, constant.PermissionPublic, h.UserLogin},
+		Route{"Sign Out", http.MethodPost, constant.UserLogoutRoute, constant.PermissionAuthenticated, h.SignOut},
+		Route{"Refresh Token", http.MethodPost, constant.RefreshTokenRoute, constant.PermissionPublic, h.RefreshToken},
+		Route{"Two Factor Login", http.MethodPost, constant.TwoFactorLoginRoute, constant.PermissionPublic, h.TwoFactorLogin},
+
+		// two factor authentication
+		Route{"Setup Two Factor", http.MethodPost, constant.TwoFactorSetupRoute, constant.PermissionAuthenticated, h.SetupTwoFactor},
+		Route{"Enable Two Factor", http.MethodPost, constant.TwoFactorEnableRoute, constant.PermissionAuthenticated, h.EnableTwoFactor},
+		Route{"Disable Two Factor", http.MethodPost, constant.TwoFactorDisableRoute, constant.PermissionAuthenticated, h.DisableTwoFactor},
+		Route{"Regenerate Recovery Codes", http.MethodPost, constant.RecoveryCodesRoute, constant.PermissionAuthenticated, h.RegenerateRecoveryCodes},
+
+		// email verification
+		Route{"Verify Email", http.MethodPost, constant.VerifyEmailRoute, constant.PermissionPublic, h.VerifyEmail},
+		Route{"Verify Otp", http.MethodPost, constant.VerifyOtpRoute, constant.PermissionPublic, h.VerifyOtp},
+		Route{"Resend Email", http.MethodPost, constant.ResendEmailRoute, constant.PermissionPublic, h.ResendEmail},
+
+		// password reset
+		Route{"Forgot Password", http.MethodPost, constant.ForgotPasswordRoute, constant.PermissionPublic, h.ForgotPassword},
+		Route{"Reset Password", http.MethodPost, constant.ResetPasswordRoute, constant.PermissionPublic, h.ResetPassword},
+	}
+}
+
+func swaggerRoutes() Routes {
+	return Routes{
+		Route{"Swagger", http.MethodGet, constant.SwaggerRoute, constant.PermissionPublic, ginSwagger.WrapHandler(swaggerFiles.Handler)},
+	}
+}
+
+func productGlobalRoutes(h *controller.Handler) Routes {
+	return Routes{
+		Route{"List Product", http.MethodGet, constant.ListProductRoute, constant.PermissionPublic, h.ListProductsController},
+		Route{"List Category", http.MethodGet, constant.ListCategoryRoute, constant.PermissionPublic, h.ListCategoryController},
+		Route{"List Single Product", http.MethodGet, constant.ListSingleProductRoute, constant.PermissionPublic, h.ListSingleProductController},
+		Route{"List Category Products", http.MethodGet, constant.CategoryProductsRoute, constant.PermissionPublic, h.ListCategoryProducts},
+		Route{"List Currencies", http.MethodGet, constant.ListCurrenciesRoute, constant.PermissionPublic, h.ListCurrencies},
+	}
 }
 
-var userRoutes = Routes{
+// payment providers call these, each request is verified by its signature
+func paymentRoutes(h *controller.Handler) Routes {
+	return Routes{
+		Route{"Payment Webhook", http.MethodPost, constant.PaymentWebhookRoute, constant.PermissionPublic, h.PaymentWebhook},
+	}
+}
+
+// routes for any signed in user, they act on the caller of the token
+func authenticatedUserRoutes(h *controller.Handler) Routes {
+	return Routes{
+		// account
+		Route{"Add Address", http.MethodPost, constant.AddAddressRoute, constant.PermissionAuthenticated, h.AddAddress},
+		Route{"Update Address", http.MethodPut, constant.UpdateAddressRoute, constant.PermissionAuthenticated, h.UpdateAddress},
+		Route{"Delete Address", http.MethodDelete, constant.DeleteAddressRoute, constant.PermissionAuthenticated, h.DeleteAddress},
+		Route{"List Addresses", http.MethodGet, constant.ListAddressesRoute, constant.PermissionAuthenticated, h.ListAddresses},
+		Route{"Edit Name", http.MethodPut, constant.EditNameRoute, constant.PermissionAuthenticated, h.EditName},
+		Route{"Update Password", http.MethodPut, constant.UpdateUser, constant.PermissionAuthenticated, h.UpdateUser},
+
+		// favorites
+		Route{"Add To Favorite", http.MethodPost, constant.AddToFavoriteRoute, constant.PermissionAuthenticated, h.AddToFavorite},
+		Route{"Remove From Favorite", http.MethodPost, constant.RemoveFromFavoriteRoute, constant.PermissionAuthenticated, h.RemoveFromFavorite},
+		Route{"List Favorite", http.MethodGet, constant.ListFavoriteRoute, constant.PermissionAuthenticated, h.ListFavorite},
 
-	// Register User
-	Route{"Register User", http.MethodPost, constant.UserRegisterRoute, controller.RegisterUser},
-	Route{"Login User", http.MethodPost, constant.UserLoginRoute, controller.UserLogin},
-	Route{"Sign Out", http.MethodPost, constant.UserLogoutRoute, controller.SignOut},
+		// cart
+		Route{"Add To Cart", http.MethodPost, constant.AddToCartRoute, constant.PermissionAuthenticated, h.AddToCart},
+		Route{"Update Cart", http.MethodPut, constant.UpdateCart, constant.PermissionAuthenticated, h.UpdateCart},
+		Route{"Remove From Cart", http.MethodPost, constant.RemoveFromCartRoute, constant.PermissionAuthenticated, h.RemoveFromCart},
+		Route{"List Cart", http.MethodGet, constant.ListCartRoute, constant.PermissionAuthenticated, h.ListCart},
+		Route{"Empty Cart", http.MethodDelete, constant.EmptyCartRoute, constant.PermissionAuthenticated, h.EmptyCart},
+		Route{"Apply Coupon", http.MethodPost, constant.ApplyCouponRoute, constant.PermissionAuthenticated, h.ApplyCoupon},
+		Route{"Remove Coupon", http.MethodDelete, constant.RemoveCouponRoute, constant.PermissionAuthenticated, h.RemoveCoupon},
+
+		// orders
+		Route{"Checkout", http.MethodPost, constant.CheckoutRoute, constant.PermissionAuthenticated, h.Checkout},
+		Route{"List My Orders", http.MethodGet, constant.MyOrdersRoute, constant.PermissionAuthenticated, h.ListMyOrders},
+		Route{"Get My Order", http.MethodGet, constant.MyOrderRoute, constant.PermissionAuthenticated, h.GetMyOrder},
+		Route{"Cancel Order", http.MethodPost, constant.CancelOrderRoute, constant.PermissionAuthenticated, h.CancelOrder},
+
+		// product actions
+		Route{"Search Product", http.MethodPost, constant.SearchProductRoute, constant.PermissionAuthenticated, h.SearchProductController},
+		Route{"Get Product Link", http.MethodGet, constant.GetProductLinkRoute, constant.PermissionAuthenticated, h.GetProductLink},
+		Route{"Give Rating", http.MethodPost, constant.GiveRatingRoute, constant.PermissionAuthenticated, h.GiveRating},
+		Route{"Comment On Product", http.MethodPost, constant.CommentOnProductRoute, constant.PermissionAuthenticated, h.CommentOnProduct},
+	}
 }
 
-var productGlobalRoutes = Routes{
-	Route{"List Product", http.MethodGet, constant.ListProductRoute, controller.ListProductsController},
-	Route{"Search Product", http.MethodPost, constant.SearchProductRoute, controller.SearchProductController},
-	Route{"List Category", http.MethodGet, constant.ListCategoryRoute, controller.ListCategoryController},
-	Route{"List Single Product", http.MethodGet, constant.ListSingleProductRoute, controller.ListSingleProductController},
+// back office routes, each guarded by the permission it needs
+func adminRoutes(h *controller.Handler) Routes {
+	return Routes{
+		// users
+		Route{"List Users", http.MethodGet, constant.GetAllUserRoute, constant.PermissionViewUsers, h.GetAllUsers},
+		Route{"Get User", http.MethodGet, constant.GetSingleUserRoute, constant.PermissionViewUsers, h.GetSingleUser},
+		Route{"Block User", http.MethodPut, constant.BlockUserRoute, constant.PermissionManageUsers, h.BlockUser},
+		Route{"Unblock User", http.MethodPut, constant.UnblockUserRoute, constant.PermissionManageUsers, h.UnblockUser},
+		Route{"Update User Role", http.MethodPut, constant.UpdateUserRoleRoute, constant.PermissionManageRoles, h.UpdateUserRole},
+		Route{"Unlock User", http.MethodPut, constant.UnlockUserRoute, constant.PermissionUnlockUsers, h.UnlockUser},
+		Route{"List Login Attempts", http.MethodGet, constant.LoginAttemptsRoute, constant.PermissionViewUsers, h.ListLoginAttempts},
+
+		// products
+		Route{"Register Product", http.MethodPost, constant.RegisterProductRoute, constant.PermissionManageCatalog, h.RegisterProduct},
+		Route{"Update Product", http.MethodPut, constant.UpdateProductRoute, constant.PermissionManageCatalog, h.UpdateProduct},
+		Route{"Update Stock", http.MethodPut, constant.UpdateStockRoute, constant.PermissionManageCatalog, h.UpdateStock},
+		Route{"Delete Product", http.MethodDelete, constant.DeleteProductRoute, constant.PermissionManageCatalog, h.DeleteProduct},
+		Route{"List Products Admin", http.MethodGet, constant.ListProductRouteAdmin, constant.PermissionManageCatalog, h.ListProductsAdmin},
+
+		// categories
+		Route{"Add Category", http.MethodPost, constant.AddCategoryRoute, constant.PermissionManageCatalog, h.AddCategory},
+		Route{"Update Category", http.MethodPut, constant.UpdateCategoryRoute, constant.PermissionManageCatalog, h.UpdateCategory},
+		Route{"Delete Category", http.MethodDelete, constant.DeleteCategoryRoute, constant.PermissionManageCatalog, h.DeleteCategory},
+
+		// coupons
+		Route{"Add Coupon", http.MethodPost, constant.AddCouponRoute, constant.PermissionManageCoupons, h.AddCoupon},
+		Route{"Delete Coupon", http.MethodDelete, constant.DeleteCouponRoute, constant.PermissionManageCoupons, h.DeleteCoupon},
+		Route{"List Coupons", http.MethodGet, constant.ListCouponRoute, constant.PermissionManageCoupons, h.ListCoupons},
+
+		// orders
+		Route{"List Orders", http.MethodGet, constant.ListOrders, constant.PermissionViewOrders, h.ListOrders},
+		Route{"Update Order Status", http.MethodPut, constant.UpdateOrder, constant.PermissionManageOrders, h.UpdateOrderStatus},
+
+		// offers
+		Route{"Add Offer", http.MethodPost, constant.AddOfferRoute, constant.PermissionManageCatalog, h.AddOffer},
+		Route{"Update Offer", http.MethodPut, constant.UpdateOfferRoute, constant.PermissionManageCatalog, h.UpdateOffer},
+		Route{"List Offers", http.MethodGet, constant.ListOffersRoute, constant.PermissionManageCatalog, h.ListOffers},
+
+		// taxes
+		Route{"Add Tax Rule", http.MethodPost, constant.AddTaxRuleRoute, constant.PermissionManageTaxes, h.AddTaxRule},
+		Route{"Update Tax Rule", http.MethodPut, constant.UpdateTaxRuleRoute, constant.PermissionManageTaxes, h.UpdateTaxRule},
+		Route{"Delete Tax Rule", http.MethodDelete, constant.DeleteTaxRuleRoute, constant.PermissionManageTaxes, h.DeleteTaxRule},
+		Route{"List Tax Rules", http.MethodGet, constant.ListTaxRulesRoute, constant.PermissionManageTaxes, h.ListTaxRules},
+
+		// shipping
+		Route{"Add Shipping Zone", http.MethodPost, constant.AddShippingZoneRoute, constant.PermissionManageShipping, h.AddShippingZone},
+		Route{"Update Shipping Zone", http.MethodPut, constant.UpdateShippingZoneRoute, constant.PermissionManageShipping, h.UpdateShippingZone},
+		Route{"Delete Shipping Zone", http.MethodDelete, constant.DeleteShippingZoneRoute, constant.PermissionManageShipping, h.DeleteShippingZone},
+		Route{"List Shipping Zones", http.MethodGet, constant.ListShippingZonesRoute, constant.PermissionManageShipping, h.ListShippingZones},
+		Route{"Add Shipping Method", http.MethodPost, constant.AddShippingMethodRoute, constant.PermissionManageShipping, h.AddShippingMethod},
+		Route{"Update Shipping Method", http.MethodPut, constant.UpdateShippingMethodRoute, constant.PermissionManageShipping, h.UpdateShippingMethod},
+		Route{"Delete Shipping Method", http.MethodDelete, constant.DeleteShippingMethodRoute, constant.PermissionManageShipping, h.DeleteShippingMethod},
+		Route{"List Shipping Methods", http.MethodGet, constant.ListShippingMethodsRoute, constant.PermissionManageShipping, h.ListShippingMethods},
+	}
 }
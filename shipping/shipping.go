@@ -0,0 +1,117 @@
+// Package shipping works out which shipping methods can take an order and
+// what they cost.
+package shipping
+
+import (
+	"sort"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// Parcel is an order as the shipping rates see it.
+type Parcel struct {
+	// Country is where it goes, an ISO 3166-1 alpha-2 code
+	Country string
+	// Weight is in grams and Volume in cubic millimetres, both for every
+	// unit together
+	Weight int
+	Volume int64
+	// Value is what the goods cost after discounts, free over threshold
+	// methods compare it to their threshold
+	Value types.Money
+}
+
+// Zone returns the zone serving country: the one listing it, or else the
+// one listing no country.
+func Zone(zones []types.ShippingZone, country string) (types.ShippingZone, bool) {
+	var rest types.ShippingZone
+	found := false
+	for _, zone := range zones {
+		if len(zone.Countries) == 0 && !found {
+			rest, found = zone, true
+		}
+		for _, c := range zone.Countries {
+			if c == country {
+				return zone, true
+			}
+		}
+	}
+	return rest, found
+}
+
+// ChargedWeight is the weight method charges parcel for, the volumetric
+// weight when that is greater than the real one.
+func ChargedWeight(method types.ShippingMethod, parcel Parcel) int {
+	if method.VolumetricDivisor <= 0 {
+		return parcel.Weight
+	}
+	// mm³ per gram is the same number as cm³ per kg
+	if volumetric := parcel.Volume / int64(method.VolumetricDivisor); volumetric > int64(parcel.Weight) {
+		return int(volumetric)
+	}
+	return parcel.Weight
+}
+
+// Rate returns what method charges for parcel, false when it can't take it.
+func Rate(method types.ShippingMethod, parcel Parcel) (types.Money, bool, error) {
+	switch method.Type {
+	case constant.ShippingFlat:
+		return method.Price, true, nil
+	case constant.ShippingFreeOver:
+		below, err := parcel.Value.Less(method.FreeOver)
+		if err != nil {
+			return types.Money{}, false, err
+		}
+		if below {
+			return method.Price, true, nil
+		}
+		return types.Money{Currency: method.Price.Currency}, true, nil
+	case constant.ShippingWeight:
+		weight := ChargedWeight(method, parcel)
+		for _, rate := range method.Rates {
+			if weight <= rate.UpTo {
+				return rate.Price, true, nil
+			}
+		}
+	}
+	return types.Money{}, false, nil
+}
+
+// Options lists the methods able to take parcel, cheapest first. Methods
+// of zones other than the one serving the parcel are left out.
+func Options(zones []types.ShippingZone, methods []types.ShippingMethod, parcel Parcel) ([]types.ShippingOption, error) {
+	out := []types.ShippingOption{}
+	zone, ok := Zone(zones, parcel.Country)
+	if !ok || parcel.Country == "" {
+		return out, nil
+	}
+
+	for _, method := range methods {
+		if method.ZoneId != zone.ID.Hex() {
+			continue
+		}
+		price, ok, err := Rate(method, parcel)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		out = append(out, types.ShippingOption{MethodID: method.ID.Hex(), Name: method.Name, Zone: zone.Name, Price: price})
+	}
+
+	// equally priced methods stay in the order they were created
+	var err error
+	sort.SliceStable(out, func(i, j int) bool {
+		less, lessErr := out[i].Price.Less(out[j].Price)
+		if err == nil {
+			err = lessErr
+		}
+		return less
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
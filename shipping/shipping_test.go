@@ -0,0 +1,150 @@
+package shipping
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/constant"
+	"github.com/PiehTVH/go-ecommerce/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func inr(amount int64) types.Money {
+	return types.NewMoney(amount, "INR")
+}
+
+func TestZone(t *testing.T) {
+	zones := []types.ShippingZone{
+		{Name: "rest of the world"},
+		{Name: "india", Countries: []string{"IN"}},
+		{Name: "neighbours", Countries: []string{"NP", "BT"}},
+		{Name: "another rest"},
+	}
+	tests := []struct {
+		country string
+		want    string
+	}{
+		{country: "IN", want: "india"},
+		{country: "BT", want: "neighbours"},
+		{country: "FR", want: "rest of the world"},
+	}
+	for _, tt := range tests {
+		got, ok := Zone(zones, tt.country)
+		if !ok || got.Name != tt.want {
+			t.Errorf("Zone(%s) = %q, %v, want %q", tt.country, got.Name, ok, tt.want)
+		}
+	}
+
+	if _, ok := Zone(zones[1:3], "FR"); ok {
+		t.Error("Zone() found a zone without a catch all one")
+	}
+}
+
+func TestChargedWeight(t *testing.T) {
+	tests := []struct {
+		name    string
+		divisor int
+		weight  int
+		volume  int64
+		want    int
+	}{
+		{name: "no divisor", weight: 500, volume: 50_000_000, want: 500},
+		// 30x30x30 cm at 5000 cm³/kg weighs 5.4 kg
+		{name: "bulky", divisor: 5000, weight: 1000, volume: 27_000_000, want: 5400},
+		{name: "heavy", divisor: 5000, weight: 8000, volume: 27_000_000, want: 8000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChargedWeight(types.ShippingMethod{VolumetricDivisor: tt.divisor}, Parcel{Weight: tt.weight, Volume: tt.volume})
+			if got != tt.want {
+				t.Errorf("ChargedWeight() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRate(t *testing.T) {
+	flat := types.ShippingMethod{Type: constant.ShippingFlat, Price: inr(500)}
+	freeOver := types.ShippingMethod{Type: constant.ShippingFreeOver, Price: inr(500), FreeOver: inr(100000)}
+	byWeight := types.ShippingMethod{Type: constant.ShippingWeight, Rates: []types.WeightRate{
+		{UpTo: 1000, Price: inr(400)},
+		{UpTo: 5000, Price: inr(900)},
+	}}
+	tests := []struct {
+		name    string
+		method  types.ShippingMethod
+		parcel  Parcel
+		want    types.Money
+		wantOK  bool
+		wantErr error
+	}{
+		{name: "flat", method: flat, parcel: Parcel{Weight: 90000}, want: inr(500), wantOK: true},
+		{name: "below the threshold", method: freeOver, parcel: Parcel{Value: inr(99999)}, want: inr(500), wantOK: true},
+		{name: "at the threshold", method: freeOver, parcel: Parcel{Value: inr(100000)}, want: inr(0), wantOK: true},
+		{name: "threshold in another currency", method: freeOver, parcel: Parcel{Value: types.NewMoney(100, "USD")}, wantErr: types.ErrCurrencyMismatch},
+		{name: "light", method: byWeight, parcel: Parcel{Weight: 1000}, want: inr(400), wantOK: true},
+		{name: "heavier", method: byWeight, parcel: Parcel{Weight: 1001}, want: inr(900), wantOK: true},
+		{name: "too heavy", method: byWeight, parcel: Parcel{Weight: 5001}},
+		{name: "unknown type", method: types.ShippingMethod{Type: "pigeon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := Rate(tt.method, tt.parcel)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rate() error = %v, want %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Rate() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	india := types.ShippingZone{ID: primitive.NewObjectID(), Name: "india", Countries: []string{"IN"}}
+	world := types.ShippingZone{ID: primitive.NewObjectID(), Name: "world"}
+	zones := []types.ShippingZone{india, world}
+	method := func(name string, zone types.ShippingZone, price int64) types.ShippingMethod {
+		return types.ShippingMethod{ID: primitive.NewObjectID(), ZoneId: zone.ID.Hex(), Name: name, Type: constant.ShippingFlat, Price: inr(price)}
+	}
+	methods := []types.ShippingMethod{
+		method("express", india, 900),
+		method("standard", india, 300),
+		method("courier", india, 300),
+		method("air mail", world, 2500),
+		{ID: primitive.NewObjectID(), ZoneId: india.ID.Hex(), Name: "truck", Type: constant.ShippingWeight, Rates: []types.WeightRate{{UpTo: 100, Price: inr(100)}}},
+	}
+	tests := []struct {
+		name    string
+		country string
+		want    []string
+	}{
+		{name: "cheapest first, ties in order", country: "IN", want: []string{"standard", "courier", "express"}},
+		{name: "catch all zone", country: "FR", want: []string{"air mail"}},
+		{name: "no country", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Options(zones, methods, Parcel{Country: tt.country, Weight: 1000, Value: inr(5000)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Options() = %v, want %v", got, tt.want)
+			}
+			for i, option := range got {
+				if option.Name != tt.want[i] {
+					t.Errorf("option %d = %s, want %s", i, option.Name, tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("prices in several currencies", func(t *testing.T) {
+		mixed := append([]types.ShippingMethod{}, methods...)
+		mixed[0].Price = types.NewMoney(10, "USD")
+		if _, err := Options(zones, mixed, Parcel{Country: "IN", Weight: 1000}); !errors.Is(err, types.ErrCurrencyMismatch) {
+			t.Errorf("Options() error = %v, want %v", err, types.ErrCurrencyMismatch)
+		}
+	})
+}
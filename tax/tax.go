@@ -0,0 +1,112 @@
+// Package tax works out the tax on cart lines from rules the store keeps
+// per region and category.
+package tax
+
+import (
+	"context"
+	"math/bits"
+
+	"github.com/PiehTVH/go-ecommerce/pricing"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// RuleSource lists the tax rules, database.TaxRuleRepository is one.
+type RuleSource interface {
+	List(ctx context.Context) ([]types.TaxRule, error)
+}
+
+// Rules is a pricing.TaxCalculator applying the rules of a RuleSource.
+type Rules struct {
+	source RuleSource
+}
+
+func NewRules(source RuleSource) *Rules {
+	return &Rules{source: source}
+}
+
+func (r *Rules) Tax(ctx context.Context, req pricing.TaxRequest) ([]pricing.LineTax, error) {
+	out := make([]pricing.LineTax, len(req.Lines))
+	for i := range out {
+		out[i].Tax = types.Money{Currency: req.Currency}
+	}
+	if req.Region.Country == "" {
+		return out, nil
+	}
+
+	rules, err := r.source.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range req.Lines {
+		rule, ok := Match(rules, req.Region, line.CategoryIDs)
+		if !ok {
+			continue
+		}
+		taxable, err := line.Taxable()
+		if err != nil {
+			return nil, err
+		}
+		out[i] = pricing.LineTax{Rate: rule.Rate, Tax: Amount(taxable, rule.Rate, req.Inclusive)}
+	}
+	return out, nil
+}
+
+// Match returns the rule for a product in categoryIDs, its category first
+// and then the ancestors, shipped to region. The most specific rule wins:
+// a rule on a category beats one on its parent, which beats one on every
+// product, and between those a rule on the region beats one on the whole
+// country. Among equally specific rules the first listed wins.
+func Match(rules []types.TaxRule, region types.TaxRegion, categoryIDs []string) (types.TaxRule, bool) {
+	var best types.TaxRule
+	bestScore := -1
+	for _, rule := range rules {
+		if rule.Country != region.Country || (rule.Region != "" && rule.Region != region.Region) {
+			continue
+		}
+
+		// categories nearer to the product score higher
+		depth := -1
+		if rule.CategoryId == "" {
+			depth = len(categoryIDs)
+		}
+		for i, id := range categoryIDs {
+			if id == rule.CategoryId {
+				depth = i
+				break
+			}
+		}
+		if depth < 0 {
+			continue
+		}
+
+		score := 2 * (len(categoryIDs) - depth)
+		if rule.Region != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rule, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// Amount returns the tax at rate, in hundredths of a percent, on amount.
+// When inclusive amount contains the tax already and it is taken out of
+// it. The tax is rounded half up to the minor unit.
+func Amount(amount types.Money, rate int, inclusive bool) types.Money {
+	if amount.Amount <= 0 || rate <= 0 {
+		return types.Money{Currency: amount.Currency}
+	}
+
+	// amount * rate / 10000, or amount * rate / (10000 + rate) when
+	// inclusive, in 128 bits so large amounts do not overflow
+	div := uint64(10000)
+	if inclusive {
+		div += uint64(rate)
+	}
+	hi, lo := bits.Mul64(uint64(amount.Amount), uint64(rate))
+	lo, carry := bits.Add64(lo, div/2, 0)
+	hi += carry
+	tax, _ := bits.Div64(hi, lo, div)
+	return types.Money{Amount: int64(tax), Currency: amount.Currency}
+}
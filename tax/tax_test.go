@@ -0,0 +1,132 @@
+package tax
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PiehTVH/go-ecommerce/pricing"
+	"github.com/PiehTVH/go-ecommerce/types"
+)
+
+// ruleList is a RuleSource over fixed rules.
+type ruleList []types.TaxRule
+
+func (l ruleList) List(ctx context.Context) ([]types.TaxRule, error) {
+	return l, nil
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    int64
+		rate      int
+		inclusive bool
+		want      int64
+	}{
+		{name: "exclusive", amount: 10000, rate: 1800, want: 1800},
+		{name: "inclusive", amount: 11800, rate: 1800, inclusive: true, want: 1800},
+		// 7.25% of 9.99 is 0.724275
+		{name: "rounds down", amount: 999, rate: 725, want: 72},
+		// 18% of 0.25 is 0.045
+		{name: "rounds half up", amount: 25, rate: 1800, want: 5},
+		{name: "exempt", amount: 10000, rate: 0, want: 0},
+		{name: "nothing to tax", amount: 0, rate: 1800, want: 0},
+		{name: "negative amount", amount: -100, rate: 1800, want: 0},
+		{name: "no overflow", amount: 1 << 60, rate: 10000, want: 1 << 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Amount(types.NewMoney(tt.amount, "INR"), tt.rate, tt.inclusive)
+			if got.Amount != tt.want || got.Currency != "INR" {
+				t.Errorf("Amount() = %v, want %d INR", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	rules := []types.TaxRule{
+		{Name: "india", Country: "IN", Rate: 1800},
+		{Name: "karnataka", Country: "IN", Region: "KA", Rate: 1900},
+		{Name: "clothing", Country: "IN", CategoryId: "clothing", Rate: 500},
+		{Name: "shirts", Country: "IN", CategoryId: "shirts", Rate: 1200},
+		{Name: "karnataka clothing", Country: "IN", Region: "KA", CategoryId: "clothing", Rate: 600},
+		{Name: "india again", Country: "IN", Rate: 2800},
+	}
+	tests := []struct {
+		name        string
+		region      types.TaxRegion
+		categoryIDs []string
+		want        string
+	}{
+		{name: "country", region: types.TaxRegion{Country: "IN", Region: "MH"}, categoryIDs: []string{"books"}, want: "india"},
+		{name: "region beats country", region: types.TaxRegion{Country: "IN", Region: "KA"}, categoryIDs: []string{"books"}, want: "karnataka"},
+		{name: "category beats region", region: types.TaxRegion{Country: "IN", Region: "MH"}, categoryIDs: []string{"jeans", "clothing"}, want: "clothing"},
+		{name: "subcategory beats parent", region: types.TaxRegion{Country: "IN", Region: "KA"}, categoryIDs: []string{"shirts", "clothing"}, want: "shirts"},
+		{name: "region breaks category tie", region: types.TaxRegion{Country: "IN", Region: "KA"}, categoryIDs: []string{"jeans", "clothing"}, want: "karnataka clothing"},
+		{name: "other country", region: types.TaxRegion{Country: "US"}, categoryIDs: []string{"shirts"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Match(rules, tt.region, tt.categoryIDs)
+			if ok != (tt.want != "") || got.Name != tt.want {
+				t.Errorf("Match() = %q, %v, want %q", got.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesTax(t *testing.T) {
+	rules := NewRules(ruleList{
+		{Country: "IN", Rate: 1800},
+		{Country: "IN", CategoryId: "books", Rate: 0},
+	})
+	lines := []pricing.Line{
+		{CategoryIDs: []string{"shirts"}, UnitPrice: types.NewMoney(10000, "INR"), Quantity: 2, Discount: types.NewMoney(2000, "INR")},
+		{CategoryIDs: []string{"books"}, UnitPrice: types.NewMoney(5000, "INR"), Quantity: 1},
+	}
+	tests := []struct {
+		name      string
+		region    types.TaxRegion
+		inclusive bool
+		want      []pricing.LineTax
+	}{
+		{
+			name:   "taxed on the discounted total",
+			region: types.TaxRegion{Country: "IN"},
+			want:   []pricing.LineTax{{Rate: 1800, Tax: types.NewMoney(3240, "INR")}, {Rate: 0, Tax: types.NewMoney(0, "INR")}},
+		},
+		{
+			name:      "inclusive",
+			region:    types.TaxRegion{Country: "IN"},
+			inclusive: true,
+			want:      []pricing.LineTax{{Rate: 1800, Tax: types.NewMoney(2746, "INR")}, {Rate: 0, Tax: types.NewMoney(0, "INR")}},
+		},
+		{
+			name:   "no country",
+			region: types.TaxRegion{},
+			want:   []pricing.LineTax{{Tax: types.NewMoney(0, "INR")}, {Tax: types.NewMoney(0, "INR")}},
+		},
+		{
+			name:   "no rule",
+			region: types.TaxRegion{Country: "US"},
+			want:   []pricing.LineTax{{Tax: types.NewMoney(0, "INR")}, {Tax: types.NewMoney(0, "INR")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rules.Tax(context.Background(), pricing.TaxRequest{Region: tt.region, Currency: "INR", Inclusive: tt.inclusive, Lines: lines})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Tax() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d tax = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// PostalAddress is where a parcel or an invoice goes. Country is an ISO
+// 3166-1 alpha-2 code and Region the code of a state or province, they
+// decide the tax and the shipping of orders.
+type PostalAddress struct {
+	Name       string   `json:"name" bson:"name"`
+	Lines      []string `json:"lines" bson:"lines"`
+	City       string   `json:"city" bson:"city"`
+	Region     string   `json:"region" bson:"region"`
+	PostalCode string   `json:"postal_code" bson:"postal_code"`
+	Country    string   `json:"country" bson:"country"`
+	Phone      string   `json:"phone" bson:"phone"`
+}
+
+// TaxRegion is where orders shipped to the address are taxed.
+func (a PostalAddress) TaxRegion() TaxRegion {
+	return TaxRegion{Country: a.Country, Region: a.Region}
+}
+
+// Address is an entry of the address book of a customer. A book with
+// addresses has exactly one default shipping and one default billing
+// address, they may be the same.
+type Address struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Email           string             `json:"-" bson:"email"`
+	PostalAddress   `bson:",inline"`
+	DefaultShipping bool  `json:"default_shipping" bson:"default_shipping"`
+	DefaultBilling  bool  `json:"default_billing" bson:"default_billing"`
+	CreatedAt       int64 `json:"created_at" bson:"created_at"`
+	UpdatedAt       int64 `json:"updated_at" bson:"updated_at"`
+}
+
+// AddressData is what a customer sends to add or edit an address. Setting
+// a default flag moves it from the address holding it.
+type AddressData struct {
+	PostalAddress   `bson:",inline"`
+	DefaultShipping bool `json:"default_shipping" bson:"default_shipping"`
+	DefaultBilling  bool `json:"default_billing" bson:"default_billing"`
+}
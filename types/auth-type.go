@@ -0,0 +1,107 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Principal is the verified identity of the caller, taken from the access
+// token by the router's auth middleware.
+type Principal struct {
+	UserID    primitive.ObjectID `json:"user_id"`
+	Email     string             `json:"email"`
+	Role      string             `json:"role"`
+	SessionID primitive.ObjectID `json:"session_id"`
+}
+
+// Session is a server side login. Access tokens name the session they were
+// issued for, so revoking it invalidates them before they expire.
+type Session struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	// RefreshHash is the sha256 of the only refresh token still accepted.
+	RefreshHash string `json:"-" bson:"refresh_hash"`
+	// PreviousHashes are the hashes most recently rotated away, newest
+	// last. Presenting one of them again is a replay.
+	PreviousHashes []string `json:"-" bson:"previous_hashes"`
+	UserAgent      string   `json:"user_agent" bson:"user_agent"`
+	IP             string   `json:"ip" bson:"ip"`
+	CreatedAt      int64    `json:"created_at" bson:"created_at"`
+	LastUsedAt     int64    `json:"last_used_at" bson:"last_used_at"`
+	ExpiresAt      int64    `json:"expires_at" bson:"expires_at"`
+	RevokedAt      int64    `json:"revoked_at" bson:"revoked_at"`
+	RevokedReason  string   `json:"revoked_reason" bson:"revoked_reason"`
+}
+
+// Rotated reports whether hash belongs to a refresh token the session
+// already rotated away.
+func (s Session) Rotated(hash string) bool {
+	for _, previous := range s.PreviousHashes {
+		if previous == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the session can still be used at the given time.
+func (s Session) Active(now int64) bool {
+	return s.RevokedAt == 0 && now < s.ExpiresAt
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token" bson:"refresh_token"`
+}
+
+// PasswordReset is a single use password reset token. Only its hash is
+// stored.
+type PasswordReset struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	CreatedAt int64              `json:"created_at" bson:"created_at"`
+	ExpiresAt int64              `json:"expires_at" bson:"expires_at"`
+	UsedAt    int64              `json:"used_at" bson:"used_at"`
+}
+
+type ResetPassword struct {
+	Token    string `json:"token" bson:"token"`
+	Password string `json:"password" bson:"password"`
+}
+
+// LoginAttempt records a failed sign in, for the back office.
+type LoginAttempt struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Email     string             `json:"email" bson:"email"`
+	IP        string             `json:"ip" bson:"ip"`
+	UserAgent string             `json:"user_agent" bson:"user_agent"`
+	Reason    string             `json:"reason" bson:"reason"`
+	CreatedAt int64              `json:"created_at" bson:"created_at"`
+}
+
+// LoginThrottle counts consecutive failed sign ins for one key, an email
+// or a client IP.
+type LoginThrottle struct {
+	Key           string `json:"key" bson:"_id"`
+	Failures      int    `json:"failures" bson:"failures"`
+	LastFailureAt int64  `json:"last_failure_at" bson:"last_failure_at"`
+}
+
+type TwoFactorLogin struct {
+	ChallengeToken string `json:"challenge_token" bson:"challenge_token"`
+	// Code is a code from the authenticator app or a recovery code.
+	Code string `json:"code" bson:"code"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code" bson:"code"`
+}
+
+type DisableTwoFactor struct {
+	Password string `json:"password" bson:"password"`
+	Code     string `json:"code" bson:"code"`
+}
@@ -0,0 +1,165 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in the minor unit of its currency, such as paise for
+// INR or cents for USD. Amounts are whole numbers, so sums and differences
+// are exact. Rounding only happens where a percentage is taken, see
+// Percent.
+//
+// A zero amount without currency stands for nothing yet and combines with
+// any currency. Combining two different currencies fails with
+// ErrCurrencyMismatch.
+type Money struct {
+	Amount int64 `json:"amount" bson:"amount"`
+	// Currency is an ISO 4217 code such as "INR"
+	Currency string `json:"currency" bson:"currency"`
+}
+
+// ErrCurrencyMismatch is returned when amounts in different currencies are
+// combined.
+var ErrCurrencyMismatch = errors.New("money: currencies differ")
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// minorDigits lists the currencies whose minor unit is not a hundredth of
+// the major one.
+var minorDigits = map[string]int{
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "UGX": 0, "VND": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// MinorDigits is the number of decimals of currency.
+func MinorDigits(currency string) int {
+	if digits, ok := minorDigits[currency]; ok {
+		return digits
+	}
+	return 2
+}
+
+// MinorFactor is how many minor units make one major unit of currency.
+func MinorFactor(currency string) int64 {
+	factor := int64(1)
+	for i := 0; i < MinorDigits(currency); i++ {
+		factor *= 10
+	}
+	return factor
+}
+
+// ValidCurrency reports whether code has the form of an ISO 4217 code,
+// three upper case letters.
+func ValidCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultCurrency returns m in currency when it has none.
+func (m Money) DefaultCurrency(currency string) Money {
+	if m.Currency == "" {
+		m.Currency = currency
+	}
+	return m
+}
+
+// currency returns the currency m and o have in common.
+func (m Money) currency(o Money) (string, error) {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency, nil
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency, nil
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency, nil
+	}
+	return "", fmt.Errorf("%w: can not combine %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := m.currency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	currency, err := m.currency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount - o.Amount, Currency: currency}, nil
+}
+
+// Mul returns m times n, for a quantity of units.
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Percent returns p percent of m, rounded half away from zero to the
+// minor unit: 15% of 3.33 is 0.4995 and becomes 0.50.
+func (m Money) Percent(p int) Money {
+	n := m.Amount * int64(p)
+	q := n / 100
+	if r := n % 100; r >= 50 {
+		q++
+	} else if r <= -50 {
+		q--
+	}
+	return Money{Amount: q, Currency: m.Currency}
+}
+
+// Less reports whether m is less than o.
+func (m Money) Less(o Money) (bool, error) {
+	if _, err := m.currency(o); err != nil {
+		return false, err
+	}
+	return m.Amount < o.Amount, nil
+}
+
+// Min returns the lesser of m and o.
+func (m Money) Min(o Money) (Money, error) {
+	less, err := o.Less(m)
+	switch {
+	case err != nil:
+		return Money{}, err
+	case less:
+		return o, nil
+	}
+	return m, nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// String formats m in major units, such as "12.50 INR".
+func (m Money) String() string {
+	digits := MinorDigits(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	s := strconv.FormatInt(amount, 10)
+	if digits > 0 {
+		if len(s) <= digits {
+			s = strings.Repeat("0", digits-len(s)+1) + s
+		}
+		s = s[:len(s)-digits] + "." + s[len(s)-digits:]
+	}
+	return strings.TrimSpace(sign + s + " " + m.Currency)
+}
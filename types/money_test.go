@@ -0,0 +1,119 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMoneyArithmetic(t *testing.T) {
+	inr := func(amount int64) Money { return NewMoney(amount, "INR") }
+	tests := []struct {
+		name    string
+		op      func(a, b Money) (Money, error)
+		a, b    Money
+		want    Money
+		wantErr error
+	}{
+		{name: "add", op: Money.Add, a: inr(150), b: inr(275), want: inr(425)},
+		{name: "sub", op: Money.Sub, a: inr(150), b: inr(275), want: inr(-125)},
+		{name: "add to nothing yet", op: Money.Add, a: Money{}, b: inr(100), want: inr(100)},
+		{name: "sub nothing yet", op: Money.Sub, a: inr(100), b: Money{}, want: inr(100)},
+		{name: "min", op: Money.Min, a: inr(300), b: inr(200), want: inr(200)},
+		{name: "min keeps equal left", op: Money.Min, a: inr(200), b: inr(200), want: inr(200)},
+		{name: "add other currency", op: Money.Add, a: inr(100), b: NewMoney(100, "USD"), wantErr: ErrCurrencyMismatch},
+		{name: "sub other currency", op: Money.Sub, a: inr(100), b: NewMoney(100, "USD"), wantErr: ErrCurrencyMismatch},
+		{name: "min other currency", op: Money.Min, a: inr(100), b: NewMoney(100, "USD"), wantErr: ErrCurrencyMismatch},
+		// only a zero amount goes without currency
+		{name: "add amount without currency", op: Money.Add, a: inr(100), b: Money{Amount: 5}, wantErr: ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op(tt.a, tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyLess(t *testing.T) {
+	tests := []struct {
+		a, b    Money
+		want    bool
+		wantErr error
+	}{
+		{a: NewMoney(1, "INR"), b: NewMoney(2, "INR"), want: true},
+		{a: NewMoney(2, "INR"), b: NewMoney(2, "INR")},
+		{a: Money{}, b: NewMoney(2, "INR"), want: true},
+		{a: NewMoney(1, "INR"), b: NewMoney(2, "USD"), wantErr: ErrCurrencyMismatch},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Less(tt.b)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%v.Less(%v) = %v, %v, want %v, %v", tt.a, tt.b, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneyPercent(t *testing.T) {
+	tests := []struct {
+		amount  int64
+		percent int
+		want    int64
+	}{
+		{amount: 1000, percent: 10, want: 100},
+		// 15% of 3.33 is 0.4995
+		{amount: 333, percent: 15, want: 50},
+		{amount: 333, percent: 10, want: 33},
+		{amount: 5, percent: 10, want: 1},
+		{amount: 4, percent: 10, want: 0},
+		{amount: -333, percent: 15, want: -50},
+		{amount: 1000, percent: 0, want: 0},
+	}
+	for _, tt := range tests {
+		got := NewMoney(tt.amount, "INR").Percent(tt.percent)
+		if got.Amount != tt.want || got.Currency != "INR" {
+			t.Errorf("Percent(%d) of %d = %v, want %d INR", tt.percent, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{m: NewMoney(1250, "INR"), want: "12.50 INR"},
+		{m: NewMoney(5, "USD"), want: "0.05 USD"},
+		{m: NewMoney(-5, "USD"), want: "-0.05 USD"},
+		{m: NewMoney(1250, "JPY"), want: "1250 JPY"},
+		{m: NewMoney(1250, "KWD"), want: "1.250 KWD"},
+		{m: NewMoney(0, "INR"), want: "0.00 INR"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestValidCurrency(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{code: "INR", want: true},
+		{code: "inr"},
+		{code: "IN"},
+		{code: "INRS"},
+		{code: "I1R"},
+	}
+	for _, tt := range tests {
+		if got := ValidCurrency(tt.code); got != tt.want {
+			t.Errorf("ValidCurrency(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ShippingZone groups the countries served by the same shipping methods.
+// A country is in at most one zone, a zone without countries serves every
+// country no other zone lists.
+type ShippingZone struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	Countries []string           `json:"countries" bson:"countries"`
+	CreatedAt int64              `json:"created_at" bson:"created_at"`
+	UpdatedAt int64              `json:"updated_at" bson:"updated_at"`
+}
+
+// ShippingZoneData is what the back office sends to create or edit a
+// shipping zone.
+type ShippingZoneData struct {
+	Name      string   `json:"name" bson:"name"`
+	Countries []string `json:"countries" bson:"countries"`
+}
+
+// ShippingMethod is a way to ship to a zone and what it costs, Type is one
+// of the constant.Shipping* rules.
+type ShippingMethod struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ZoneId string             `json:"zone_id" bson:"zone_id"`
+	Name   string             `json:"name" bson:"name"`
+	Type   string             `json:"type" bson:"type"`
+	// Price is what a flat rate method costs, and a free over threshold
+	// one below FreeOver
+	Price    Money `json:"price" bson:"price"`
+	FreeOver Money `json:"free_over" bson:"free_over"`
+	// Rates of a weight based method, the first covering the weight of
+	// the order applies. Heavier orders can't use the method.
+	Rates []WeightRate `json:"rates" bson:"rates"`
+	// VolumetricDivisor turns the volume of bulky products into weight,
+	// in cubic centimetres per kilogram such as 5000. The greater of the
+	// real and the volumetric weight is charged, 0 charges the real one.
+	VolumetricDivisor int   `json:"volumetric_divisor" bson:"volumetric_divisor"`
+	CreatedAt         int64 `json:"created_at" bson:"created_at"`
+	UpdatedAt         int64 `json:"updated_at" bson:"updated_at"`
+}
+
+// WeightRate prices orders weighing up to UpTo grams.
+type WeightRate struct {
+	UpTo  int   `json:"up_to" bson:"up_to"`
+	Price Money `json:"price" bson:"price"`
+}
+
+// ShippingMethodData is what the back office sends to create or edit a
+// shipping method.
+type ShippingMethodData struct {
+	ZoneId            string       `json:"zone_id" bson:"zone_id"`
+	Name              string       `json:"name" bson:"name"`
+	Type              string       `json:"type" bson:"type"`
+	Price             Money        `json:"price" bson:"price"`
+	FreeOver          Money        `json:"free_over" bson:"free_over"`
+	Rates             []WeightRate `json:"rates" bson:"rates"`
+	VolumetricDivisor int          `json:"volumetric_divisor" bson:"volumetric_divisor"`
+}
+
+// ShippingOption is a shipping method able to take an order, with what it
+// costs for it.
+type ShippingOption struct {
+	MethodID string `json:"method_id" bson:"method_id"`
+	Name     string `json:"name" bson:"name"`
+	Zone     string `json:"zone" bson:"zone"`
+	Price    Money  `json:"price" bson:"price"`
+}
+
+// OrderShipping is the shipping method chosen at checkout. It is copied
+// into the order, later changes to the method do not alter it.
+type OrderShipping struct {
+	ShippingOption `bson:",inline"`
+	// Weight is the weight of the order in grams
+	Weight int `json:"weight" bson:"weight"`
+}
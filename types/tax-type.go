@@ -0,0 +1,38 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// TaxRegion is where an order is taxed, taken from its shipping address.
+type TaxRegion struct {
+	// Country is an ISO 3166-1 alpha-2 code, empty when unknown
+	Country string `json:"country" bson:"country"`
+	// Region is a subdivision of the country such as a state, empty for
+	// none
+	Region string `json:"region" bson:"region"`
+}
+
+// TaxRule sets the tax rate of a country or one of its regions, for every
+// product or for a category and its subcategories.
+type TaxRule struct {
+	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name    string             `json:"name" bson:"name"`
+	Country string             `json:"country" bson:"country"`
+	// Region is empty for a rule covering the whole country
+	Region string `json:"region" bson:"region"`
+	// CategoryId is empty for a rule covering every product
+	CategoryId string `json:"category_id" bson:"category_id"`
+	// Rate is in hundredths of a percent, 1850 is 18.5%. 0 exempts what
+	// the rule covers.
+	Rate      int   `json:"rate" bson:"rate"`
+	CreatedAt int64 `json:"created_at" bson:"created_at"`
+	UpdatedAt int64 `json:"updated_at" bson:"updated_at"`
+}
+
+// TaxRuleData is what the back office sends to create or edit a tax rule.
+type TaxRuleData struct {
+	Name       string `json:"name" bson:"name"`
+	Country    string `json:"country" bson:"country"`
+	Region     string `json:"region" bson:"region"`
+	CategoryId string `json:"category_id" bson:"category_id"`
+	Rate       int    `json:"rate" bson:"rate"`
+}
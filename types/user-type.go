@@ -13,7 +13,13 @@ type User struct {
 	UpdatedAt int64              `json:"updated_at" bson:"updated_at"`
 	Favourite []string           `json:"favourite" bson:"favourite"`
 	IsBlocked bool               `json:"is_blocked" bson:"is_blocked"`
-	Address   string             `json:"address" bson:"address"`
+	Verified  bool               `json:"verified" bson:"verified"`
+	// two factor authentication, the secrets never leave the server
+	TotpEnabled       bool     `json:"totp_enabled" bson:"totp_enabled"`
+	TotpSecret        string   `json:"-" bson:"totp_secret"`
+	TotpPendingSecret string   `json:"-" bson:"totp_pending_secret"`
+	TotpLastStep      int64    `json:"-" bson:"totp_last_step"`
+	RecoveryCodes     []string `json:"-" bson:"recovery_codes"`
 }
 
 type UserClient struct {
@@ -23,12 +29,29 @@ type UserClient struct {
 	Password string `json:"password" bson:"password"`
 }
 
+// Verification tracks the email verification of one address. Only the
+// hash of the current otp is kept.
 type Verification struct {
-	ID        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
-	Email     string             `json:"email" bson:"email"`
-	Otp       int64              `json:"otp" bson:"otp"`
-	Status    bool               `json:"status" bson:"status"`
-	CreatedAt int64              `json:"created_at" bson:"created_at"`
+	ID      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Email   string             `json:"email" bson:"email"`
+	OtpHash string             `json:"-" bson:"otp_hash"`
+	Status  bool               `json:"status" bson:"status"`
+	// Attempts and Sends count the guesses and the otps sent since
+	// WindowStart, resending does not give more guesses
+	Attempts    int   `json:"attempts" bson:"attempts"`
+	Sends       int   `json:"sends" bson:"sends"`
+	WindowStart int64 `json:"window_start" bson:"window_start"`
+	CreatedAt   int64 `json:"created_at" bson:"created_at"`
+	ExpiresAt   int64 `json:"expires_at" bson:"expires_at"`
+}
+
+type EmailData struct {
+	Email string `json:"email" bson:"email"`
+}
+
+type VerifyOtp struct {
+	Email string `json:"email" bson:"email"`
+	Otp   string `json:"otp" bson:"otp"`
 }
 
 type Login struct {
@@ -36,75 +59,229 @@ type Login struct {
 	Password string `json:"password" bson:"password"`
 }
 
+// Coupon is a discount code customers apply to their cart.
 type Coupon struct {
-	ID       primitive.ObjectID `json:"id"`
-	Name     string             `json:"name"`
-	Discount int                `json:"discount"`
-	Expiry   string             `json:"expiry"`
+	ID primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	// Name is the code customers enter, kept upper case
+	Name string `json:"name" bson:"name"`
+	// Type is constant.CouponPercentage or constant.CouponFixed
+	Type string `json:"type" bson:"type"`
+	// Discount is the percentage off of a percentage coupon, AmountOff
+	// the amount off of a fixed one
+	Discount     int   `json:"discount" bson:"discount"`
+	AmountOff    Money `json:"amount_off" bson:"amount_off"`
+	MinCartValue Money `json:"min_cart_value" bson:"min_cart_value"`
+	// MaxDiscount caps the amount taken off, zero for no cap
+	MaxDiscount Money `json:"max_discount" bson:"max_discount"`
+	StartsAt    int64 `json:"starts_at" bson:"starts_at"`
+	// EndsAt is 0 for a coupon that does not expire
+	EndsAt int64 `json:"ends_at" bson:"ends_at"`
+	// UsageLimit and PerUserLimit are 0 when unlimited
+	UsageLimit   int `json:"usage_limit" bson:"usage_limit"`
+	PerUserLimit int `json:"per_user_limit" bson:"per_user_limit"`
+	UsedCount    int `json:"used_count" bson:"used_count"`
+	// CategoryIds and ProductIds restrict the coupon to those products, it
+	// applies to the whole cart when both are empty. A category includes
+	// its subcategories.
+	CategoryIds []string `json:"category_ids" bson:"category_ids"`
+	ProductIds  []string `json:"product_ids" bson:"product_ids"`
+	CreatedAt   int64    `json:"created_at" bson:"created_at"`
+	UpdatedAt   int64    `json:"updated_at" bson:"updated_at"`
+}
+
+// CouponData is what the back office sends to create a coupon.
+type CouponData struct {
+	Name         string   `json:"name" bson:"name"`
+	Type         string   `json:"type" bson:"type"`
+	Discount     int      `json:"discount" bson:"discount"`
+	AmountOff    Money    `json:"amount_off" bson:"amount_off"`
+	MinCartValue Money    `json:"min_cart_value" bson:"min_cart_value"`
+	MaxDiscount  Money    `json:"max_discount" bson:"max_discount"`
+	StartsAt     int64    `json:"starts_at" bson:"starts_at"`
+	EndsAt       int64    `json:"ends_at" bson:"ends_at"`
+	UsageLimit   int      `json:"usage_limit" bson:"usage_limit"`
+	PerUserLimit int      `json:"per_user_limit" bson:"per_user_limit"`
+	CategoryIds  []string `json:"category_ids" bson:"category_ids"`
+	ProductIds   []string `json:"product_ids" bson:"product_ids"`
+}
+
+// CouponUsage counts the redemptions of one coupon by one customer.
+type CouponUsage struct {
+	// ID joins the coupon id and the email
+	ID       string `json:"id" bson:"_id"`
+	CouponID string `json:"coupon_id" bson:"coupon_id"`
+	Email    string `json:"email" bson:"email"`
+	Count    int    `json:"count" bson:"count"`
+}
+
+type ApplyCoupon struct {
+	Coupon string `json:"coupon" bson:"coupon"`
 }
 
 type Product struct {
-	ID          primitive.ObjectID `json:"id"`
-	Name        string             `json:"name"`
-	Price       int                `json:"price"`
-	Description string             `json:"description"`
-	Images      string             `json:"images"`
-	Rating      float64            `json:"rating"`
-	Stock       int                `json:"stock"`
-	Keywords    []string           `json:"keywords"`
-	NumRating   int                `json:"num_rating"`
-	Comments    []Comment          `json:"comments"`
-	CategoryId  string             `json:"category_id"`
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	Price       Money              `json:"price" bson:"price"`
+	Description string             `json:"description" bson:"description"`
+	Images      string             `json:"images" bson:"images"`
+	Rating      float64            `json:"rating" bson:"rating"`
+	Stock       int                `json:"stock" bson:"stock"`
+	Keywords    []string           `json:"keywords" bson:"keywords"`
+	NumRating   int                `json:"num_rating" bson:"num_rating"`
+	Comments    []Comment          `json:"comments" bson:"comments"`
+	CategoryId  string             `json:"category_id" bson:"category_id"`
+	// Weight is in grams, it prices weight based shipping
+	Weight     int        `json:"weight" bson:"weight"`
+	Dimensions Dimensions `json:"dimensions" bson:"dimensions"`
+	// Hidden keeps the product out of the storefront
+	Hidden bool `json:"hidden" bson:"hidden"`
+	// DeletedAt is set once the product is deleted. The record stays so
+	// orders can still refer to it.
+	DeletedAt int64 `json:"deleted_at" bson:"deleted_at"`
+	CreatedAt int64 `json:"created_at" bson:"created_at"`
+	UpdatedAt int64 `json:"updated_at" bson:"updated_at"`
+}
+
+// Dimensions is the size of a packed product in millimetres.
+type Dimensions struct {
+	Length int `json:"length" bson:"length"`
+	Width  int `json:"width" bson:"width"`
+	Height int `json:"height" bson:"height"`
+}
+
+// Volume is in cubic millimetres.
+func (d Dimensions) Volume() int64 {
+	return int64(d.Length) * int64(d.Width) * int64(d.Height)
+}
+
+// Visible reports whether customers may see and buy the product.
+func (p Product) Visible() bool {
+	return !p.Hidden && p.DeletedAt == 0
+}
+
+// PricedProduct is a product as the storefront shows it, with the price
+// customers pay next to the list price.
+type PricedProduct struct {
+	Product        `bson:",inline"`
+	EffectivePrice Money `json:"effective_price" bson:"effective_price"`
+	// OfferId is the offer the effective price comes from, if any
+	OfferId string `json:"offer_id,omitempty" bson:"offer_id,omitempty"`
+}
+
+// ProductData is what the back office sends to create or edit a product.
+type ProductData struct {
+	Name        string   `json:"name" bson:"name"`
+	Price       Money    `json:"price" bson:"price"`
+	Description string   `json:"description" bson:"description"`
+	Images      string   `json:"images" bson:"images"`
+	Stock       int      `json:"stock" bson:"stock"`
+	Keywords    []string `json:"keywords" bson:"keywords"`
+	CategoryId  string   `json:"category_id" bson:"category_id"`
+	// Weight is in grams, Dimensions in millimetres
+	Weight     int        `json:"weight" bson:"weight"`
+	Dimensions Dimensions `json:"dimensions" bson:"dimensions"`
+	Hidden     bool       `json:"hidden" bson:"hidden"`
+}
+
+type UpdateStock struct {
+	// Quantity is added to the stock, a negative one removes stock.
+	Quantity int `json:"quantity" bson:"quantity"`
 }
 
 type Comment struct {
-	Email   string `json:"email"`
-	Comment string `json:"comment"`
+	Email   string `json:"email" bson:"email"`
+	Comment string `json:"comment" bson:"comment"`
 }
 
 type Category struct {
-	ID       primitive.ObjectID `json:"id"`
-	Category string             `json:"category"`
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Category string             `json:"category" bson:"category"`
+	Slug     string             `json:"slug" bson:"slug"`
+	// ParentId is empty for a top level category
+	ParentId string `json:"parent_id" bson:"parent_id"`
+	// Position orders siblings, lowest first
+	Position  int   `json:"position" bson:"position"`
+	CreatedAt int64 `json:"created_at" bson:"created_at"`
+	UpdatedAt int64 `json:"updated_at" bson:"updated_at"`
 }
 
+// CategoryNode is a category with its subcategories, for the storefront
+// menu.
+type CategoryNode struct {
+	Category
+	Children []CategoryNode `json:"children"`
+}
+
+// CategoryData is what the back office sends to create or edit a
+// category. Without a slug one is made from the name.
+type CategoryData struct {
+	Category string `json:"category" bson:"category"`
+	Slug     string `json:"slug" bson:"slug"`
+	ParentId string `json:"parent_id" bson:"parent_id"`
+	Position int    `json:"position" bson:"position"`
+}
+
+// Offer lowers the price of a category, its subcategories included, or of
+// a single product for a while.
 type Offer struct {
-	CategoryId int  `json:"category_id"`
-	Discount   int  `json:"discount"`
-	Expiry     bool `json:"expiry"`
+	ID   primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name string             `json:"name" bson:"name"`
+	// exactly one of CategoryId and ProductId is set
+	CategoryId string `json:"category_id" bson:"category_id"`
+	ProductId  string `json:"product_id" bson:"product_id"`
+	// Discount is a percentage off the list price
+	Discount int   `json:"discount" bson:"discount"`
+	StartsAt int64 `json:"starts_at" bson:"starts_at"`
+	// EndsAt is 0 for an offer that runs until it is ended
+	EndsAt    int64 `json:"ends_at" bson:"ends_at"`
+	CreatedAt int64 `json:"created_at" bson:"created_at"`
+	UpdatedAt int64 `json:"updated_at" bson:"updated_at"`
 }
 
-type AddressData struct {
-	Address string `json:"address" bson:"address"`
-	Email   string `json:"email" bson:"email"`
+// Running reports whether the offer applies at now.
+func (o Offer) Running(now int64) bool {
+	return o.StartsAt <= now && (o.EndsAt == 0 || now < o.EndsAt)
+}
+
+// OfferData is what the back office sends to create or edit an offer.
+type OfferData struct {
+	Name       string `json:"name" bson:"name"`
+	CategoryId string `json:"category_id" bson:"category_id"`
+	ProductId  string `json:"product_id" bson:"product_id"`
+	Discount   int    `json:"discount" bson:"discount"`
+	StartsAt   int64  `json:"starts_at" bson:"starts_at"`
+	EndsAt     int64  `json:"ends_at" bson:"ends_at"`
 }
 
 type UpdatePassword struct {
-	Email       string `json:"email" bson:"email"`
 	OldPassword string `json:"oldPassword" bson:"oldPassword"`
 	NewPassword string `json:"newPassword" bson:"newPassword"`
 }
 
 type NameData struct {
-	Name  string `json:"name" bson:"name"`
-	Email string `json:"email" bson:"email"`
+	Name string `json:"name" bson:"name"`
 }
 
 type Rating struct {
 	Rating float64 `json:"rating" bson:"rating"`
 }
 
+// AddToCart adds Quantity to the cart line of the product. It also
+// updates a line, then Quantity replaces the current one and 0 removes it.
 type AddToCart struct {
-	Email     string `json:"email" bson:"email"`
 	ProductID string `json:"product_id" bson:"product_id"`
 	Quantity  int    `json:"quantity" bson:"quantity"`
 }
 
 type CartItem struct {
-	Email     string `json:"email" bson:"email"`
-	Products  []ProductInCart
-	ChekedOut bool    `json:"checked_out" bson:"checked_out"`
-	Total     float64 `json:"total" bson:"total"`
-	NumItems  int     `json:"num_items" bson:"num_items"`
+	Email     string          `json:"email" bson:"email"`
+	Products  []ProductInCart `json:"products" bson:"products"`
+	ChekedOut bool            `json:"checked_out" bson:"checked_out"`
+	// Coupon is the code applied to the cart, empty for none
+	Coupon string `json:"coupon" bson:"coupon"`
+	// NumItems counts units, the totals are worked out from the current
+	// prices whenever the cart is read
+	NumItems int `json:"num_items" bson:"num_items"`
 }
 
 type ProductInCart struct {
@@ -112,12 +289,108 @@ type ProductInCart struct {
 	Quantity  int    `json:"quantity" bson:"quantity"`
 }
 
+// Order is a checked out cart. Prices and the addresses are copied at
+// checkout, later catalog or account changes do not alter it.
 type Order struct {
-	Email     string          `json:"email" bson:"email"`
-	NumItems  int             `json:"num_items" bson:"num_items"`
-	Total     float64         `json:"total" bson:"total"`
-	Products  []ProductInCart `json:"products" bson:"products"`
-	CreatedAt int64           `json:"created_at" bson:"created_at"`
-	UpdatedAt int64           `json:"updated_at" bson:"updated_at"`
-	Deliverd  bool            `json:"deliverd" bson:"deliverd"`
+	ID    primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Email string             `json:"email" bson:"email"`
+	// IdempotencyKey is the Idempotency-Key header of the checkout that
+	// placed the order
+	IdempotencyKey string      `json:"-" bson:"idempotency_key,omitempty"`
+	Items          []OrderItem `json:"items" bson:"items"`
+	NumItems       int         `json:"num_items" bson:"num_items"`
+	Subtotal       Money       `json:"subtotal" bson:"subtotal"`
+	Discount       Money       `json:"discount" bson:"discount"`
+	// Tax is included in Total, and in the prices too when TaxInclusive
+	// is set
+	Tax          Money     `json:"tax" bson:"tax"`
+	TaxInclusive bool      `json:"tax_inclusive" bson:"tax_inclusive"`
+	TaxRegion    TaxRegion `json:"tax_region" bson:"tax_region"`
+	// Shipping is empty for orders placed while the store had no
+	// shipping methods
+	Shipping OrderShipping `json:"shipping" bson:"shipping"`
+	Total    Money         `json:"total" bson:"total"`
+	// Coupon is the code redeemed by the order, empty for none
+	Coupon          string        `json:"coupon" bson:"coupon"`
+	ShippingAddress PostalAddress `json:"shipping_address" bson:"shipping_address"`
+	BillingAddress  PostalAddress `json:"billing_address" bson:"billing_address"`
+	// Status is one of the constant.Order* states, History lists every
+	// change, oldest first
+	Status  string       `json:"status" bson:"status"`
+	History []OrderEvent `json:"history" bson:"history"`
+	Payment Payment      `json:"payment" bson:"payment"`
+	// Conversion is the currency the customer shopped in
+	Conversion Conversion `json:"conversion" bson:"conversion"`
+	CreatedAt  int64      `json:"created_at" bson:"created_at"`
+	UpdatedAt  int64      `json:"updated_at" bson:"updated_at"`
+}
+
+// Conversion records the currency prices were shown in at checkout. The
+// amounts of the order are in SettlementCurrency, the store currency, and
+// are what gets charged.
+type Conversion struct {
+	Currency           string `json:"currency" bson:"currency"`
+	SettlementCurrency string `json:"settlement_currency" bson:"settlement_currency"`
+	// Rate is how many units of Currency one unit of SettlementCurrency
+	// bought, as a decimal
+	Rate      string `json:"rate" bson:"rate"`
+	RatesAsOf int64  `json:"rates_as_of" bson:"rates_as_of"`
+	// Total is the total of the order as the customer saw it
+	Total Money `json:"total" bson:"total"`
+}
+
+// Payment is the payment intent of an order at its provider.
+type Payment struct {
+	Provider string `json:"provider" bson:"provider"`
+	IntentID string `json:"intent_id" bson:"intent_id"`
+	// Status is one of the payment.Status* states
+	Status string `json:"status" bson:"status"`
+	Amount Money  `json:"amount" bson:"amount"`
+	// Error is why the last capture or refund failed, empty when it went
+	// through
+	Error string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// Checkout picks how the order is paid, the default provider when empty,
+// and how it is shipped.
+type Checkout struct {
+	PaymentMethod  string `json:"payment_method" bson:"payment_method"`
+	ShippingMethod string `json:"shipping_method" bson:"shipping_method"`
+	// AddressId and BillingAddressId pick entries of the address book,
+	// the default ones are used when they are empty
+	AddressId        string `json:"address_id" bson:"address_id"`
+	BillingAddressId string `json:"billing_address_id" bson:"billing_address_id"`
+}
+
+// OrderEvent records an order entering a status.
+type OrderEvent struct {
+	Status string `json:"status" bson:"status"`
+	At     int64  `json:"at" bson:"at"`
+	// By is the email of who made the change, empty for the system
+	By   string `json:"by,omitempty" bson:"by,omitempty"`
+	Note string `json:"note,omitempty" bson:"note,omitempty"`
+}
+
+type UpdateOrderStatus struct {
+	Status string `json:"status" bson:"status"`
+	Note   string `json:"note" bson:"note"`
+}
+
+// OrderItem is one product of an order at the price it was sold.
+type OrderItem struct {
+	ProductID string `json:"product_id" bson:"product_id"`
+	Name      string `json:"name" bson:"name"`
+	ListPrice Money  `json:"list_price" bson:"list_price"`
+	UnitPrice Money  `json:"unit_price" bson:"unit_price"`
+	Quantity  int    `json:"quantity" bson:"quantity"`
+	// Discount is the share of the coupon discount taken off the line
+	Discount Money `json:"discount" bson:"discount"`
+	// TaxRate is in hundredths of a percent
+	TaxRate int   `json:"tax_rate" bson:"tax_rate"`
+	Tax     Money `json:"tax" bson:"tax"`
+}
+
+type UpdateRole struct {
+	Email string `json:"email" bson:"email"`
+	Role  string `json:"role" bson:"role"`
 }